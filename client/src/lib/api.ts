@@ -40,7 +40,39 @@ class ApiClient {
 		return tokenErrorPatterns.some((pattern) => errorLower.includes(pattern));
 	}
 
-	private async request<T>(endpoint: string, options: RequestInit = {}): Promise<ApiResponse<T>> {
+	// Exchange the stored refresh token for new tokens, once per expired access token
+	private refreshing: Promise<boolean> | null = null;
+
+	private refreshSession(): Promise<boolean> {
+		const refreshToken = localStorage.getItem('refreshToken');
+		if (!refreshToken) return Promise.resolve(false);
+
+		if (!this.refreshing) {
+			this.refreshing = fetch(`${API_BASE_URL}/refresh`, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ refresh_token: refreshToken })
+			})
+				.then(async (response) => {
+					if (!response.ok) return false;
+					const data = await response.json();
+					localStorage.setItem('token', data.token);
+					localStorage.setItem('refreshToken', data.refresh_token);
+					return true;
+				})
+				.catch(() => false)
+				.finally(() => {
+					this.refreshing = null;
+				});
+		}
+		return this.refreshing;
+	}
+
+	private async request<T>(
+		endpoint: string,
+		options: RequestInit = {},
+		retry = true
+	): Promise<ApiResponse<T>> {
 		try {
 			console.log(`${API_BASE_URL}${endpoint}`);
 			const response = await fetch(`${API_BASE_URL}${endpoint}`, {
@@ -70,6 +102,11 @@ class ApiClient {
 			if (!response.ok) {
 				const errorMessage = data.error || `HTTP error! status: ${response.status}`;
 
+				// Access tokens are short-lived: refresh once and try again
+				if (response.status === 401 && retry && (await this.refreshSession())) {
+					return this.request(endpoint, options, false);
+				}
+
 				// Check if this is a token-related error
 				if (response.status === 401 || this.isTokenExpiredError(errorMessage)) {
 					// Token is invalid or expired, logout user
@@ -107,6 +144,62 @@ class ApiClient {
 		});
 	}
 
+	async logout() {
+		return this.request('/logout', { method: 'POST' }, false);
+	}
+
+	async forgotPassword(email: string) {
+		return this.request('/password/forgot', {
+			method: 'POST',
+			body: JSON.stringify({ email })
+		});
+	}
+
+	async resetPassword(token: string, password: string) {
+		return this.request('/password/reset', {
+			method: 'POST',
+			body: JSON.stringify({ token, password })
+		});
+	}
+
+	async changePassword(currentPassword: string, newPassword: string) {
+		return this.request('/me/password', {
+			method: 'PUT',
+			body: JSON.stringify({ current_password: currentPassword, new_password: newPassword })
+		});
+	}
+
+	async verifyEmail(token: string) {
+		return this.request('/email/verify', {
+			method: 'POST',
+			body: JSON.stringify({ token })
+		});
+	}
+
+	async resendVerification(email: string) {
+		return this.request('/email/verify/resend', {
+			method: 'POST',
+			body: JSON.stringify({ email })
+		});
+	}
+
+	// Single sign-on: the login buttons go straight to the API, which redirects to the
+	// identity provider and back to /sso/callback with a one-time ticket
+	ssoLoginURL(provider: 'oidc' | 'saml') {
+		return `${API_BASE_URL}/sso/${provider}/login`;
+	}
+
+	async getSSOProviders() {
+		return this.request('/sso/providers', {}, false);
+	}
+
+	async exchangeSSOTicket(ticket: string) {
+		return this.request('/sso/exchange', {
+			method: 'POST',
+			body: JSON.stringify({ ticket })
+		});
+	}
+
 	async register(userData: any) {
 		return this.request('/register', {
 			method: 'POST',
@@ -123,10 +216,17 @@ class ApiClient {
 		return this.request('/student/surveys');
 	}
 
-	async submitResponse(response: any) {
-		return this.request('/student/responses', {
+	async submitSurvey(surveyId: string | number, answers: { question_id: number; answer: string }[]) {
+		return this.request(`/student/surveys/${surveyId}/submission`, {
 			method: 'POST',
-			body: JSON.stringify(response)
+			body: JSON.stringify({ answers })
+		});
+	}
+
+	async updateSubmission(surveyId: string | number, answers: { question_id: number; answer: string }[]) {
+		return this.request(`/student/surveys/${surveyId}/submission`, {
+			method: 'PUT',
+			body: JSON.stringify({ answers })
 		});
 	}
 
@@ -152,6 +252,10 @@ class ApiClient {
 		return this.request('/professor/subjects');
 	}
 
+	async getProfessorSubjectTrends(subjectId: string) {
+		return this.request(`/professor/subjects/${subjectId}/trends`);
+	}
+
 	async getProfessorSurveys() {
 		return this.request('/professor/surveys');
 	}
@@ -163,6 +267,48 @@ class ApiClient {
 		});
 	}
 
+	async createSurveyFromTemplate(request: any) {
+		return this.request('/professor/surveys/from-template', {
+			method: 'POST',
+			body: JSON.stringify(request)
+		});
+	}
+
+	async cloneSurvey(surveyId: string, request: any) {
+		return this.request(`/professor/surveys/${surveyId}/clone`, {
+			method: 'POST',
+			body: JSON.stringify(request)
+		});
+	}
+
+	async getTemplates(role: 'admin' | 'professor' = 'professor') {
+		return this.request(`/${role}/templates`);
+	}
+
+	async getTemplate(templateId: string, role: 'admin' | 'professor' = 'professor') {
+		return this.request(`/${role}/templates/${templateId}`);
+	}
+
+	async createTemplate(template: any, role: 'admin' | 'professor' = 'professor') {
+		return this.request(`/${role}/templates`, {
+			method: 'POST',
+			body: JSON.stringify(template)
+		});
+	}
+
+	async updateTemplate(templateId: string, template: any, role: 'admin' | 'professor' = 'professor') {
+		return this.request(`/${role}/templates/${templateId}`, {
+			method: 'PUT',
+			body: JSON.stringify(template)
+		});
+	}
+
+	async deleteTemplate(templateId: string, role: 'admin' | 'professor' = 'professor') {
+		return this.request(`/${role}/templates/${templateId}`, {
+			method: 'DELETE'
+		});
+	}
+
 	async addQuestionToSurvey(surveyId: string, question: any) {
 		return this.request(`/professor/surveys/${surveyId}/questions`, {
 			method: 'POST',
@@ -183,6 +329,93 @@ class ApiClient {
 		});
 	}
 
+	async getProfessorSurvey(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}`);
+	}
+
+	async updateSurvey(surveyId: string, changes: any) {
+		return this.request(`/professor/surveys/${surveyId}`, {
+			method: 'PUT',
+			body: JSON.stringify(changes)
+		});
+	}
+
+	async deleteSurvey(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async updateSurveyStatus(surveyId: string, status: string) {
+		return this.request(`/professor/surveys/${surveyId}/status`, {
+			method: 'PUT',
+			body: JSON.stringify({ status })
+		});
+	}
+
+	async archiveSurvey(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}/archive`, {
+			method: 'POST'
+		});
+	}
+
+	async restoreSurvey(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}/restore`, {
+			method: 'POST'
+		});
+	}
+
+	async getArchivedSurveys() {
+		return this.request('/professor/surveys/archived');
+	}
+
+	async getDeletedQuestions(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}/questions/deleted`);
+	}
+
+	async restoreQuestion(surveyId: string, questionId: string) {
+		return this.request(`/professor/surveys/${surveyId}/questions/${questionId}/restore`, {
+			method: 'POST'
+		});
+	}
+
+	async getAdminArchivedSurveys() {
+		return this.request('/admin/surveys/archived');
+	}
+
+	async adminArchiveSurvey(surveyId: string) {
+		return this.request(`/admin/surveys/${surveyId}/archive`, {
+			method: 'POST'
+		});
+	}
+
+	async adminRestoreSurvey(surveyId: string) {
+		return this.request(`/admin/surveys/${surveyId}/restore`, {
+			method: 'POST'
+		});
+	}
+
+	async purgeSurvey(surveyId: string) {
+		return this.request(`/admin/surveys/${surveyId}/purge`, {
+			method: 'DELETE'
+		});
+	}
+
+	async getProfessorSections(semesterId?: string) {
+		return this.request(`/professor/sections${semesterId ? `?semester_id=${semesterId}` : ''}`);
+	}
+
+	async getSurveyStaff(surveyId: string) {
+		return this.request(`/professor/surveys/${surveyId}/staff`);
+	}
+
+	async createStaffQuestions(surveyId: string, question: any) {
+		return this.request(`/professor/surveys/${surveyId}/staff-questions`, {
+			method: 'POST',
+			body: JSON.stringify(question)
+		});
+	}
+
 	async getProfessorResponses() {
 		return this.request('/professor/responses');
 	}
@@ -203,6 +436,19 @@ class ApiClient {
 		return this.request('/admin/semesters');
 	}
 
+	async updateSemester(semesterId: string, semester: any) {
+		return this.request(`/admin/semesters/${semesterId}`, {
+			method: 'PUT',
+			body: JSON.stringify(semester)
+		});
+	}
+
+	async deleteSemester(semesterId: string) {
+		return this.request(`/admin/semesters/${semesterId}`, {
+			method: 'DELETE'
+		});
+	}
+
 	async activateSemester(semesterId: string) {
 		return this.request(`/admin/semesters/${semesterId}/activate`, {
 			method: 'PUT'
@@ -220,6 +466,141 @@ class ApiClient {
 		return this.request('/admin/subjects');
 	}
 
+	async updateSubject(subjectId: string, subject: any) {
+		return this.request(`/admin/subjects/${subjectId}`, {
+			method: 'PUT',
+			body: JSON.stringify(subject)
+		});
+	}
+
+	async deleteSubject(subjectId: string) {
+		return this.request(`/admin/subjects/${subjectId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async getSubjectTrends(subjectId: string) {
+		return this.request(`/admin/subjects/${subjectId}/trends`);
+	}
+
+	async setSubjectUnit(subjectId: string, unit: { department_id: number; program_id: number }) {
+		return this.request(`/admin/subjects/${subjectId}/unit`, {
+			method: 'PUT',
+			body: JSON.stringify(unit)
+		});
+	}
+
+	async createDepartment(department: any) {
+		return this.request('/admin/departments', {
+			method: 'POST',
+			body: JSON.stringify(department)
+		});
+	}
+
+	async getDepartments() {
+		return this.request('/admin/departments');
+	}
+
+	async createProgram(program: any) {
+		return this.request('/admin/programs', {
+			method: 'POST',
+			body: JSON.stringify(program)
+		});
+	}
+
+	async getPrograms() {
+		return this.request('/admin/programs');
+	}
+
+	async importUsers(csv: string, dryRun = false) {
+		return this.request(`/admin/import/users${dryRun ? '?dry_run=true' : ''}`, {
+			method: 'POST',
+			headers: { 'Content-Type': 'text/csv' },
+			body: csv
+		});
+	}
+
+	async importEnrollments(csv: string, dryRun = false) {
+		return this.request(`/admin/import/enrollments${dryRun ? '?dry_run=true' : ''}`, {
+			method: 'POST',
+			headers: { 'Content-Type': 'text/csv' },
+			body: csv
+		});
+	}
+
+	async createSection(section: any) {
+		return this.request('/admin/sections', {
+			method: 'POST',
+			body: JSON.stringify(section)
+		});
+	}
+
+	async getSections(subjectId?: string, semesterId?: string) {
+		const params = new URLSearchParams();
+		if (subjectId) params.set('subject_id', subjectId);
+		if (semesterId) params.set('semester_id', semesterId);
+		const query = params.toString();
+		return this.request(`/admin/sections${query ? `?${query}` : ''}`);
+	}
+
+	async updateSection(sectionId: string, section: any) {
+		return this.request(`/admin/sections/${sectionId}`, {
+			method: 'PUT',
+			body: JSON.stringify(section)
+		});
+	}
+
+	async deleteSection(sectionId: string) {
+		return this.request(`/admin/sections/${sectionId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async assignStaff(assignment: any) {
+		return this.request('/admin/staff', {
+			method: 'POST',
+			body: JSON.stringify(assignment)
+		});
+	}
+
+	async getStaff(subjectId?: string, semesterId?: string) {
+		const params = new URLSearchParams();
+		if (subjectId) params.set('subject_id', subjectId);
+		if (semesterId) params.set('semester_id', semesterId);
+		const query = params.toString();
+		return this.request(`/admin/staff${query ? `?${query}` : ''}`);
+	}
+
+	async updateStaffRole(assignmentId: string, role: 'lead' | 'co_professor' | 'ta') {
+		return this.request(`/admin/staff/${assignmentId}`, {
+			method: 'PUT',
+			body: JSON.stringify({ role })
+		});
+	}
+
+	async removeStaff(assignmentId: string) {
+		return this.request(`/admin/staff/${assignmentId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async assignCoordinator(assignment: any) {
+		return this.request('/admin/coordinators', {
+			method: 'POST',
+			body: JSON.stringify(assignment)
+		});
+	}
+
+	async getCoordinators() {
+		return this.request('/admin/coordinators');
+	}
+
+	async removeCoordinator(assignmentId: string) {
+		return this.request(`/admin/coordinators/${assignmentId}`, {
+			method: 'DELETE'
+		});
+	}
+
 	async createEnrollment(enrollment: any) {
 		return this.request('/admin/enrollments', {
 			method: 'POST',
@@ -231,6 +612,34 @@ class ApiClient {
 		return this.request('/admin/enrollments');
 	}
 
+	async updateEnrollment(enrollmentId: string, enrollment: any) {
+		return this.request(`/admin/enrollments/${enrollmentId}`, {
+			method: 'PUT',
+			body: JSON.stringify(enrollment)
+		});
+	}
+
+	async deleteEnrollment(enrollmentId: string) {
+		return this.request(`/admin/enrollments/${enrollmentId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async createCampaign(campaign: any) {
+		return this.request('/admin/campaigns', {
+			method: 'POST',
+			body: JSON.stringify(campaign)
+		});
+	}
+
+	async getCampaigns() {
+		return this.request('/admin/campaigns');
+	}
+
+	async getCampaign(campaignId: string) {
+		return this.request(`/admin/campaigns/${campaignId}`);
+	}
+
 	async getAllResponses() {
 		return this.request('/admin/responses');
 	}
@@ -244,12 +653,76 @@ class ApiClient {
 		return this.request('/admin/role-requests');
 	}
 
-	async updateUserRole(userId: number, role: 'student' | 'professor' | 'admin') {
+	async updateUserRole(userId: number, role: 'student' | 'professor' | 'coordinator' | 'admin') {
 		return this.request(`/admin/users/${userId}/role`, {
 			method: 'PUT',
 			body: JSON.stringify({ role })
 		});
 	}
+
+	async updateUser(userId: number, user: { first_name?: string; last_name?: string; email?: string }) {
+		return this.request(`/admin/users/${userId}`, {
+			method: 'PUT',
+			body: JSON.stringify(user)
+		});
+	}
+
+	async disableUser(userId: number) {
+		return this.request(`/admin/users/${userId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async enableUser(userId: number) {
+		return this.request(`/admin/users/${userId}/enable`, {
+			method: 'PUT'
+		});
+	}
+
+	async verifyUser(userId: number) {
+		return this.request(`/admin/users/${userId}/verify`, {
+			method: 'PUT'
+		});
+	}
+
+	// Allowed email domains for registration (admin)
+	async getAllowedDomains() {
+		return this.request('/admin/allowed-domains');
+	}
+
+	async addAllowedDomain(domain: string) {
+		return this.request('/admin/allowed-domains', {
+			method: 'POST',
+			body: JSON.stringify({ domain })
+		});
+	}
+
+	async removeAllowedDomain(domainId: number) {
+		return this.request(`/admin/allowed-domains/${domainId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	// Coordinator endpoints
+	async getCoordinatorSubjects() {
+		return this.request('/coordinator/subjects');
+	}
+
+	async getCoordinatorSurveys() {
+		return this.request('/coordinator/surveys');
+	}
+
+	async getCoordinatorSurveyAnalytics(surveyId: string) {
+		return this.request(`/coordinator/surveys/${surveyId}/analytics`);
+	}
+
+	async getCoordinatorResponseRates(semesterId: string) {
+		return this.request(`/coordinator/semesters/${semesterId}/response-rates`);
+	}
+
+	async getCoordinatorSubjectTrends(subjectId: string) {
+		return this.request(`/coordinator/subjects/${subjectId}/trends`);
+	}
 }
 
 export const api = new ApiClient();
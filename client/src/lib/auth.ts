@@ -8,6 +8,7 @@ export function logout() {
 		localStorage.removeItem('user');
 		localStorage.removeItem('userId');
 		localStorage.removeItem('token');
+		localStorage.removeItem('refreshToken');
 		window.location.href = '/login';
 	}
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Answer scale constants
+const (
+	NPSMin    = 0
+	NPSMax    = 10
+	RatingMin = 1
+	RatingMax = 5
+
+	// NPS buckets: 9-10 are promoters, 7-8 passives and 0-6 detractors
+	NPSPromoterMin  = 9
+	NPSDetractorMax = 6
+)
+
+// NPSSummary aggregates the answers to an NPS question
+type NPSSummary struct {
+	Score      float64 `json:"score"` // % promoters - % detractors, from -100 to 100
+	Average    float64 `json:"average"`
+	Promoters  int     `json:"promoters"`
+	Passives   int     `json:"passives"`
+	Detractors int     `json:"detractors"`
+}
+
+// RatingSummary aggregates the answers to a rating question
+type RatingSummary struct {
+	Mean      float64     `json:"mean"`
+	Median    float64     `json:"median"`
+	Histogram map[int]int `json:"histogram"` // rating value -> number of answers
+}
+
+// OptionCount is the number of answers that picked a multiple choice option
+type OptionCount struct {
+	Option string `json:"option"`
+	Count  int    `json:"count"`
+}
+
+// ChoiceSummary aggregates the answers to a multiple choice question
+type ChoiceSummary struct {
+	Options []OptionCount `json:"options"`
+	Other   int           `json:"other"` // answers that match none of the question's options
+}
+
+// QuestionAnalytics is the aggregate view of the answers to a single question.
+// It never carries individual answers or student identity.
+type QuestionAnalytics struct {
+	QuestionID    uint           `json:"question_id"`
+	Text          string         `json:"text"`
+	Type          string         `json:"type"`
+	Order         int            `json:"order"`
+	StaffID       uint           `json:"staff_id,omitempty"`   // set on questions evaluating a staff member
+	StaffName     string         `json:"staff_name,omitempty"` // when the question's Staff is loaded
+	ResponseCount int            `json:"response_count"`
+	NPS           *NPSSummary    `json:"nps,omitempty"`
+	Rating        *RatingSummary `json:"rating,omitempty"`
+	Choice        *ChoiceSummary `json:"choice,omitempty"`
+}
+
+// SurveyAnalytics is the aggregate view of every question in a survey
+type SurveyAnalytics struct {
+	SurveyID        uint                `json:"survey_id"`
+	Title           string              `json:"title"`
+	RespondentCount int                 `json:"respondent_count"`
+	Questions       []QuestionAnalytics `json:"questions"`
+}
+
+// BuildSurveyAnalytics aggregates the responses of a survey per question.
+// Questions are reported in their survey order; answers that cannot be parsed
+// for the question type are counted in ResponseCount but left out of the aggregates.
+func BuildSurveyAnalytics(survey Survey, responses []Response) SurveyAnalytics {
+	answersByQuestion := make(map[uint][]string)
+	respondents := make(map[string]struct{})
+	for _, r := range responses {
+		answersByQuestion[r.QuestionID] = append(answersByQuestion[r.QuestionID], r.Answer)
+		respondents[submissionKey(r)] = struct{}{}
+	}
+
+	questions := make([]Question, len(survey.Questions))
+	copy(questions, survey.Questions)
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].Order < questions[j].Order })
+
+	result := SurveyAnalytics{
+		SurveyID:        survey.ID,
+		Title:           survey.Title,
+		RespondentCount: len(respondents),
+		Questions:       make([]QuestionAnalytics, 0, len(questions)),
+	}
+	for _, q := range questions {
+		result.Questions = append(result.Questions, BuildQuestionAnalytics(q, answersByQuestion[q.ID]))
+	}
+	return result
+}
+
+// BuildQuestionAnalytics aggregates the raw answers given to a question
+func BuildQuestionAnalytics(question Question, answers []string) QuestionAnalytics {
+	qa := QuestionAnalytics{
+		QuestionID:    question.ID,
+		Text:          question.Text,
+		Type:          question.Type,
+		Order:         question.Order,
+		StaffID:       question.StaffID,
+		ResponseCount: len(answers),
+	}
+	if question.Staff != nil {
+		qa.StaffName = question.Staff.FirstName + " " + question.Staff.LastName
+	}
+
+	switch question.Type {
+	case QuestionTypeNPS:
+		qa.NPS = summarizeNPS(parseScores(answers, NPSMin, NPSMax))
+	case QuestionTypeRating:
+		qa.Rating = summarizeRating(parseScores(answers, RatingMin, RatingMax))
+	case QuestionTypeChoice:
+		qa.Choice = summarizeChoice(question.OptionList(), answers)
+	}
+	return qa
+}
+
+// parseScores keeps the answers that are integers within [min, max]
+func parseScores(answers []string, min, max int) []int {
+	scores := make([]int, 0, len(answers))
+	for _, a := range answers {
+		v, err := strconv.Atoi(strings.TrimSpace(a))
+		if err != nil || v < min || v > max {
+			continue
+		}
+		scores = append(scores, v)
+	}
+	return scores
+}
+
+func summarizeNPS(scores []int) *NPSSummary {
+	summary := &NPSSummary{}
+	if len(scores) == 0 {
+		return summary
+	}
+
+	total := 0
+	for _, s := range scores {
+		total += s
+		switch {
+		case s >= NPSPromoterMin:
+			summary.Promoters++
+		case s <= NPSDetractorMax:
+			summary.Detractors++
+		default:
+			summary.Passives++
+		}
+	}
+
+	n := float64(len(scores))
+	summary.Average = float64(total) / n
+	summary.Score = (float64(summary.Promoters) - float64(summary.Detractors)) / n * 100
+	return summary
+}
+
+func summarizeRating(scores []int) *RatingSummary {
+	summary := &RatingSummary{Histogram: make(map[int]int)}
+	for v := RatingMin; v <= RatingMax; v++ {
+		summary.Histogram[v] = 0
+	}
+	if len(scores) == 0 {
+		return summary
+	}
+
+	total := 0
+	for _, s := range scores {
+		total += s
+		summary.Histogram[s]++
+	}
+	summary.Mean = float64(total) / float64(len(scores))
+
+	sorted := make([]int, len(scores))
+	copy(sorted, scores)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		summary.Median = float64(sorted[mid-1]+sorted[mid]) / 2
+	} else {
+		summary.Median = float64(sorted[mid])
+	}
+	return summary
+}
+
+func summarizeChoice(options []string, answers []string) *ChoiceSummary {
+	summary := &ChoiceSummary{Options: make([]OptionCount, len(options))}
+	index := make(map[string]int, len(options))
+	for i, opt := range options {
+		summary.Options[i] = OptionCount{Option: opt}
+		index[opt] = i
+	}
+
+	for _, a := range answers {
+		if i, ok := index[strings.TrimSpace(a)]; ok {
+			summary.Options[i].Count++
+		} else {
+			summary.Other++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSurveyAnalytics(t *testing.T) {
+	survey := Survey{
+		ID:    1,
+		Title: "Course Feedback",
+		Questions: []Question{
+			{ID: 3, Type: QuestionTypeChoice, Text: "Favorite part", Order: 3, Options: `["Labs", "Lectures"]`},
+			{ID: 1, Type: QuestionTypeNPS, Text: "Would you recommend?", Order: 1},
+			{ID: 2, Type: QuestionTypeRating, Text: "Rate the professor", Order: 2},
+			{ID: 4, Type: QuestionTypeFreeText, Text: "Comments", Order: 4},
+		},
+	}
+
+	responses := []Response{
+		{SurveyID: 1, StudentID: 10, QuestionID: 1, Answer: "10"},
+		{SurveyID: 1, StudentID: 11, QuestionID: 1, Answer: "9"},
+		{SurveyID: 1, StudentID: 12, QuestionID: 1, Answer: "7"},
+		{SurveyID: 1, StudentID: 13, QuestionID: 1, Answer: "3"},
+		{SurveyID: 1, StudentID: 10, QuestionID: 2, Answer: "5"},
+		{SurveyID: 1, StudentID: 11, QuestionID: 2, Answer: "4"},
+		{SurveyID: 1, StudentID: 12, QuestionID: 2, Answer: "2"},
+		{SurveyID: 1, StudentID: 13, QuestionID: 2, Answer: "4"},
+		{SurveyID: 1, StudentID: 10, QuestionID: 3, Answer: "Labs"},
+		{SurveyID: 1, StudentID: 11, QuestionID: 3, Answer: "Labs"},
+		{SurveyID: 1, StudentID: 12, QuestionID: 3, Answer: "Homework"},
+		{SurveyID: 1, StudentID: 10, QuestionID: 4, Answer: "Great course"},
+	}
+
+	analytics := BuildSurveyAnalytics(survey, responses)
+
+	t.Run("Questions Ordered By Survey Order", func(t *testing.T) {
+		assert.Equal(t, uint(1), analytics.SurveyID)
+		assert.Equal(t, 4, analytics.RespondentCount)
+		assert.Len(t, analytics.Questions, 4)
+		for i, q := range analytics.Questions {
+			assert.Equal(t, i+1, q.Order)
+		}
+	})
+
+	t.Run("NPS Aggregates", func(t *testing.T) {
+		nps := analytics.Questions[0].NPS
+		assert.NotNil(t, nps)
+		assert.Equal(t, 2, nps.Promoters)
+		assert.Equal(t, 1, nps.Passives)
+		assert.Equal(t, 1, nps.Detractors)
+		assert.InDelta(t, 25.0, nps.Score, 0.001)
+		assert.InDelta(t, 7.25, nps.Average, 0.001)
+	})
+
+	t.Run("Rating Aggregates", func(t *testing.T) {
+		rating := analytics.Questions[1].Rating
+		assert.NotNil(t, rating)
+		assert.InDelta(t, 3.75, rating.Mean, 0.001)
+		assert.InDelta(t, 4.0, rating.Median, 0.001)
+		assert.Equal(t, 2, rating.Histogram[4])
+		assert.Equal(t, 0, rating.Histogram[1])
+		assert.Len(t, rating.Histogram, RatingMax-RatingMin+1)
+	})
+
+	t.Run("Choice Aggregates", func(t *testing.T) {
+		choice := analytics.Questions[2].Choice
+		assert.NotNil(t, choice)
+		assert.Equal(t, []OptionCount{{Option: "Labs", Count: 2}, {Option: "Lectures", Count: 0}}, choice.Options)
+		assert.Equal(t, 1, choice.Other)
+	})
+
+	t.Run("Free Text Only Counted", func(t *testing.T) {
+		freeText := analytics.Questions[3]
+		assert.Equal(t, 1, freeText.ResponseCount)
+		assert.Nil(t, freeText.NPS)
+		assert.Nil(t, freeText.Rating)
+		assert.Nil(t, freeText.Choice)
+	})
+
+	t.Run("JSON Excludes Student Identity And Answers", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(analytics)
+		assert.NoError(t, err)
+
+		jsonStr := string(jsonBytes)
+		assert.NotContains(t, jsonStr, "student")
+		assert.NotContains(t, jsonStr, "Great course")
+	})
+}
+
+func TestBuildQuestionAnalyticsIgnoresInvalidAnswers(t *testing.T) {
+	question := Question{ID: 1, Type: QuestionTypeNPS, Text: "Recommend?", Order: 1}
+
+	qa := BuildQuestionAnalytics(question, []string{"banana", "11", "-1", " 10 "})
+
+	assert.Equal(t, 4, qa.ResponseCount)
+	assert.Equal(t, 1, qa.NPS.Promoters)
+	assert.Equal(t, 0, qa.NPS.Detractors)
+	assert.InDelta(t, 100.0, qa.NPS.Score, 0.001)
+}
+
+func TestBuildQuestionAnalyticsWithoutAnswers(t *testing.T) {
+	rating := BuildQuestionAnalytics(Question{ID: 1, Type: QuestionTypeRating}, nil)
+	assert.Equal(t, 0, rating.ResponseCount)
+	assert.Zero(t, rating.Rating.Mean)
+	assert.Zero(t, rating.Rating.Median)
+
+	nps := BuildQuestionAnalytics(Question{ID: 2, Type: QuestionTypeNPS}, nil)
+	assert.Zero(t, nps.NPS.Score)
+}
+
+func TestQuestionOptionList(t *testing.T) {
+	q := Question{Options: `["Java", "Python"]`}
+	assert.Equal(t, []string{"Java", "Python"}, q.OptionList())
+
+	q = Question{Options: "not json"}
+	assert.Empty(t, q.OptionList())
+
+	q = Question{}
+	assert.Empty(t, q.OptionList())
+}
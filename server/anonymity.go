@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCodeAnonymousSurvey is returned when an action would need to link a student to their answers
+const ErrCodeAnonymousSurvey = "anonymous_survey"
+
+// ErrAnonymousEdit is returned when editing answers to an anonymous survey,
+// since the stored answers cannot be traced back to the student
+var ErrAnonymousEdit = errors.New("answers to anonymous surveys cannot be edited")
+
+// newSubmissionToken returns a random token that groups the answers of one anonymous submission
+func newSubmissionToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newAnonymousResponseID returns a random primary key for an anonymous answer, drawn from
+// [2^52, 2^53) so it stays exact in JSON and clear of the sequential IDs. Sequential keys
+// would follow submission order and could be lined up with the order of SurveyParticipation rows.
+func newAnonymousResponseID() (uint, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return 0, err
+	}
+	return uint(binary.BigEndian.Uint64(b)>>12 | 1<<52), nil
+}
+
+// anonymousTimestamp coarsens a timestamp to its UTC day, so the time an anonymous
+// answer was stored cannot be matched against other activity of the student
+func anonymousTimestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// submissionKey identifies the submission a response belongs to without exposing
+// who made it: the student for identified surveys, the submission token otherwise
+func submissionKey(r Response) string {
+	if r.SubmissionToken != "" {
+		return "token:" + r.SubmissionToken
+	}
+	return "student:" + strconv.FormatUint(uint64(r.StudentID), 10)
+}
+
+// HasSubmitted reports whether the student already submitted the survey
+func HasSubmitted(db *gorm.DB, survey Survey, studentID uint) (bool, error) {
+	var count int64
+	var err error
+	if survey.Anonymous {
+		err = db.Model(&SurveyParticipation{}).Where("survey_id = ? AND student_id = ?", survey.ID, studentID).Count(&count).Error
+	} else {
+		err = db.Model(&Response{}).Where("survey_id = ? AND student_id = ?", survey.ID, studentID).Count(&count).Error
+	}
+	return count > 0, err
+}
+
+// SubmittedSurveyIDs returns which of the given surveys the student already submitted
+func SubmittedSurveyIDs(db *gorm.DB, studentID uint, surveyIDs []uint) (map[uint]bool, error) {
+	submitted := make(map[uint]bool)
+	if len(surveyIDs) == 0 {
+		return submitted, nil
+	}
+
+	var ids []uint
+	if err := db.Model(&Response{}).Distinct("survey_id").
+		Where("student_id = ? AND survey_id IN ?", studentID, surveyIDs).
+		Pluck("survey_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	var anonymousIDs []uint
+	if err := db.Model(&SurveyParticipation{}).
+		Where("student_id = ? AND survey_id IN ?", studentID, surveyIDs).
+		Pluck("survey_id", &anonymousIDs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range append(ids, anonymousIDs...) {
+		submitted[id] = true
+	}
+	return submitted, nil
+}
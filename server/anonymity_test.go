@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymousSubmission(t *testing.T) {
+	db := setupTestDB()
+
+	student := User{FirstName: "Student", LastName: "Test", Email: "student@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	other := User{FirstName: "Other", LastName: "Student", Email: "other@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&other)
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: subject.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: other.ID, SubjectID: subject.ID, SemesterID: semester.ID})
+
+	survey := Survey{Title: "Anonymous Survey", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true, Anonymous: true}
+	db.Create(&survey)
+	q1 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true, Order: 1}
+	db.Create(&q1)
+	q2 := Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 2}
+	db.Create(&q2)
+
+	answers := []SubmittedAnswer{{QuestionID: q1.ID, Answer: "9"}, {QuestionID: q2.ID, Answer: "Honest feedback"}}
+	responses, err := SubmitSurvey(db, survey, student.ID, answers)
+	assert.NoError(t, err)
+	assert.Len(t, responses, 2)
+
+	t.Run("Answers Carry No Student Identity", func(t *testing.T) {
+		var nullCount int64
+		db.Model(&Response{}).Where("survey_id = ? AND student_id IS NULL", survey.ID).Count(&nullCount)
+		assert.Equal(t, int64(2), nullCount)
+
+		var stored []Response
+		db.Where("survey_id = ?", survey.ID).Find(&stored)
+		for _, r := range stored {
+			assert.Zero(t, r.StudentID)
+			assert.Len(t, r.SubmissionToken, 32)
+			assert.Equal(t, stored[0].SubmissionToken, r.SubmissionToken)
+			assert.Equal(t, anonymousTimestamp(r.SubmittedAt), r.SubmittedAt.UTC())
+			assert.Equal(t, anonymousTimestamp(r.CreatedAt), r.CreatedAt.UTC())
+		}
+	})
+
+	t.Run("Participation Recorded", func(t *testing.T) {
+		submitted, err := HasSubmitted(db, survey, student.ID)
+		assert.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = HasSubmitted(db, survey, other.ID)
+		assert.NoError(t, err)
+		assert.False(t, submitted)
+
+		ids, err := SubmittedSurveyIDs(db, student.ID, []uint{survey.ID})
+		assert.NoError(t, err)
+		assert.True(t, ids[survey.ID])
+	})
+
+	t.Run("Double Voting Rejected", func(t *testing.T) {
+		_, err := SubmitSurvey(db, survey, student.ID, answers)
+		assert.Error(t, err)
+		assert.True(t, isUniqueViolation(err))
+
+		var count int64
+		db.Model(&Response{}).Where("survey_id = ?", survey.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Editing Rejected", func(t *testing.T) {
+		_, err := ResubmitSurvey(db, survey, student.ID, answers)
+		assert.ErrorIs(t, err, ErrAnonymousEdit)
+	})
+
+	t.Run("Submissions Counted Separately", func(t *testing.T) {
+		_, err := SubmitSurvey(db, survey, other.ID, []SubmittedAnswer{{QuestionID: q1.ID, Answer: "4"}})
+		assert.NoError(t, err)
+
+		var stored []Response
+		db.Preload("Question").Where("survey_id = ?", survey.ID).Find(&stored)
+		survey.Questions = []Question{q1, q2}
+		analytics := BuildSurveyAnalytics(survey, stored)
+		assert.Equal(t, 2, analytics.RespondentCount)
+
+		table := BuildResponseTable(survey.Questions, stored)
+		assert.Len(t, table.Rows, 2)
+
+		rates, err := ComputeResponseRates(db, []Survey{survey})
+		assert.NoError(t, err)
+		assert.Equal(t, ResponseRate{Enrolled: 2, Respondents: 2, Rate: 1}, rates[survey.ID])
+	})
+}
+
+func TestSubmissionKey(t *testing.T) {
+	assert.Equal(t, "student:42", submissionKey(Response{StudentID: 42}))
+	assert.Equal(t, "token:abc", submissionKey(Response{SubmissionToken: "abc"}))
+}
+
+func TestAnonymousSubmissionOrderUnlinkable(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	survey := Survey{Title: "Anonymous Survey", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true, Anonymous: true}
+	db.Create(&survey)
+	question := Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 1}
+	db.Create(&question)
+
+	// Each answer names its author only so the test can tell whether the orders line up
+	var submitted []string
+	for i := 0; i < 20; i++ {
+		student := User{FirstName: "Student", LastName: strconv.Itoa(i), Email: fmt.Sprintf("student%d@example.com", i), Password: "password123", Role: RoleStudent}
+		db.Create(&student)
+		author := strconv.FormatUint(uint64(student.ID), 10)
+		_, err := SubmitSurvey(db, survey, student.ID, []SubmittedAnswer{{QuestionID: question.ID, Answer: author}})
+		assert.NoError(t, err)
+		submitted = append(submitted, author)
+	}
+
+	var participants []string
+	db.Raw("SELECT student_id FROM survey_participations WHERE survey_id = ? ORDER BY rowid", survey.ID).Scan(&participants)
+	assert.Equal(t, submitted, participants, "participations are stored in submission order")
+
+	var byID []Response
+	db.Where("survey_id = ?", survey.ID).Order("id ASC").Find(&byID)
+	assert.Len(t, byID, len(submitted))
+	authors := make([]string, len(byID))
+	for i, r := range byID {
+		assert.GreaterOrEqual(t, r.ID, uint(1<<52))
+		assert.Less(t, r.ID, uint(1<<53))
+		authors[i] = r.Answer
+	}
+	assert.NotEqual(t, participants, authors, "ordering answers by ID must not reproduce the participation order")
+	assert.ElementsMatch(t, participants, authors)
+}
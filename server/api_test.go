@@ -25,7 +25,7 @@ func setupTestRouter() (*gin.Engine, *gorm.DB) {
 	}
 
 	// Auto-migrate all models
-	testDB.AutoMigrate(&User{}, &Subject{}, &Semester{}, &StudentEnrollment{}, &Survey{}, &Question{}, &Response{})
+	testDB.AutoMigrate(migrationModels()...)
 
 	// Set global db variable for handlers
 	db = testDB
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSurveyNotArchived is returned when purging a survey that was not archived first
+var ErrSurveyNotArchived = errors.New("Only archived surveys can be purged")
+
+// ArchiveSurvey soft-deletes a survey together with its questions and responses, which
+// hides them from every listing and report until the survey is restored
+func ArchiveSurvey(db *gorm.DB, survey *Survey, now time.Time) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Rows removed earlier keep their own timestamp, so restoring leaves them removed
+		if err := tx.Model(&Response{}).Where("survey_id = ?", survey.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Question{}).Where("survey_id = ?", survey.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(survey).Updates(map[string]interface{}{"status": SurveyStatusArchived, "deleted_at": now}).Error
+	})
+	if err != nil {
+		return err
+	}
+	survey.Status = SurveyStatusArchived
+	survey.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	return nil
+}
+
+// RestoreSurvey brings back an archived survey with the questions and responses that
+// were archived with it. It comes back closed if it has responses, as a draft otherwise.
+func RestoreSurvey(db *gorm.DB, survey *Survey) error {
+	if !survey.DeletedAt.Valid {
+		return nil
+	}
+	status := SurveyStatusDraft
+	err := db.Transaction(func(tx *gorm.DB) error {
+		archivedAt := survey.DeletedAt.Time
+		if err := tx.Unscoped().Model(&Question{}).Where("survey_id = ? AND deleted_at >= ?", survey.ID, archivedAt).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Response{}).Where("survey_id = ? AND deleted_at >= ?", survey.ID, archivedAt).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		answered, err := SurveyHasResponses(tx, survey.ID)
+		if err != nil {
+			return err
+		}
+		if answered {
+			status = SurveyStatusClosed
+		}
+		return tx.Unscoped().Model(survey).Updates(map[string]interface{}{"status": status, "deleted_at": nil}).Error
+	})
+	if err != nil {
+		return err
+	}
+	survey.Status = status
+	survey.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// PurgeSurvey permanently deletes an archived survey with its questions, responses and
+// participation records
+func PurgeSurvey(db *gorm.DB, survey Survey) error {
+	if !survey.DeletedAt.Valid {
+		return ErrSurveyNotArchived
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("survey_id = ?", survey.ID).Delete(&Response{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("survey_id = ?", survey.ID).Delete(&SurveyParticipation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("survey_id = ?", survey.ID).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&survey).Error
+	})
+}
+
+// ArchivedSurveys limits a survey query to archived surveys
+func ArchivedSurveys(query *gorm.DB) *gorm.DB {
+	return query.Unscoped().Where("surveys.deleted_at IS NOT NULL")
+}
+
+// DeleteQuestion soft-deletes a question and the answers given to it, so removing a
+// question that was already answered doesn't lose data
+func DeleteQuestion(db *gorm.DB, question *Question, now time.Time) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Response{}).Where("question_id = ?", question.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(question).Update("deleted_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	question.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	return nil
+}
+
+// RestoreQuestion brings back a deleted question with the answers deleted with it
+func RestoreQuestion(db *gorm.DB, question *Question) error {
+	if !question.DeletedAt.Valid {
+		return nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&Response{}).Where("question_id = ? AND deleted_at >= ?", question.ID, question.DeletedAt.Time).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(question).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return err
+	}
+	question.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
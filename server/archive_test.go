@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyArchive(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	student := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	subject := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+
+	survey := Survey{Title: "Feedback", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&survey)
+	clarity := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+	db.Create(&clarity)
+	pace := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Pace", Order: 2}
+	db.Create(&pace)
+	db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: clarity.ID, Answer: "5"})
+	db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: pace.ID, Answer: "3"})
+
+	countResponses := func() int64 {
+		var count int64
+		db.Model(&Response{}).Where("survey_id = ?", survey.ID).Count(&count)
+		return count
+	}
+
+	t.Run("Deleting An Answered Question Keeps Its Answers", func(t *testing.T) {
+		assert.NoError(t, DeleteQuestion(db, &pace, time.Now()))
+		assert.True(t, pace.DeletedAt.Valid)
+
+		var loaded Survey
+		db.Preload("Questions").First(&loaded, survey.ID)
+		assert.Len(t, loaded.Questions, 1)
+		assert.Equal(t, int64(1), countResponses())
+
+		var stored int64
+		db.Unscoped().Model(&Response{}).Where("question_id = ?", pace.ID).Count(&stored)
+		assert.Equal(t, int64(1), stored)
+	})
+
+	t.Run("Archive Hides The Survey", func(t *testing.T) {
+		assert.NoError(t, ArchiveSurvey(db, &survey, time.Now().Add(time.Second)))
+
+		assert.Error(t, db.First(&Survey{}, survey.ID).Error)
+		assert.Zero(t, countResponses())
+
+		var archived []Survey
+		ArchivedSurveys(ReadableSurveys(db, professor)).Find(&archived)
+		assert.Len(t, archived, 1)
+	})
+
+	t.Run("Purge Requires Archiving", func(t *testing.T) {
+		live := Survey{Title: "Live", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+		db.Create(&live)
+		assert.ErrorIs(t, PurgeSurvey(db, live), ErrSurveyNotArchived)
+	})
+
+	t.Run("Restore Brings Back What Was Archived With It", func(t *testing.T) {
+		var archived Survey
+		db.Unscoped().First(&archived, survey.ID)
+		assert.NoError(t, RestoreSurvey(db, &archived))
+
+		var loaded Survey
+		assert.NoError(t, db.Preload("Questions").First(&loaded, survey.ID).Error)
+		// The question deleted before archiving stays deleted
+		assert.Len(t, loaded.Questions, 1)
+		assert.Equal(t, clarity.ID, loaded.Questions[0].ID)
+		assert.Equal(t, int64(1), countResponses())
+	})
+
+	t.Run("Restore A Question", func(t *testing.T) {
+		assert.NoError(t, RestoreQuestion(db, &pace))
+
+		var loaded Survey
+		db.Preload("Questions").First(&loaded, survey.ID)
+		assert.Len(t, loaded.Questions, 2)
+		assert.Equal(t, int64(2), countResponses())
+	})
+
+	t.Run("Resubmitting Clears Answers For Good", func(t *testing.T) {
+		_, err := ResubmitSurvey(db, survey, student.ID, []SubmittedAnswer{{QuestionID: clarity.ID, Answer: "4"}})
+		assert.NoError(t, err)
+
+		// The cleared answer can be given again without hitting the unique index
+		_, err = ResubmitSurvey(db, survey, student.ID, []SubmittedAnswer{{QuestionID: clarity.ID, Answer: "4"}, {QuestionID: pace.ID, Answer: "2"}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), countResponses())
+	})
+
+	t.Run("Purge Deletes Everything", func(t *testing.T) {
+		db.Create(&SurveyParticipation{SurveyID: survey.ID, StudentID: student.ID})
+		assert.NoError(t, ArchiveSurvey(db, &survey, time.Now()))
+		assert.NoError(t, PurgeSurvey(db, survey))
+
+		var surveys, questions, responses, participations int64
+		db.Unscoped().Model(&Survey{}).Where("id = ?", survey.ID).Count(&surveys)
+		db.Unscoped().Model(&Question{}).Where("survey_id = ?", survey.ID).Count(&questions)
+		db.Unscoped().Model(&Response{}).Where("survey_id = ?", survey.ID).Count(&responses)
+		db.Model(&SurveyParticipation{}).Where("survey_id = ?", survey.ID).Count(&participations)
+		assert.Zero(t, surveys+questions+responses+participations)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoOfferedSubjects is returned when a campaign would not generate any survey
+var ErrNoOfferedSubjects = errors.New("No subjects with enrollments in this semester")
+
+// ErrEnrollmentsWithoutSection is returned when a subject with sections has students
+// enrolled in none of them, who would get no survey from the campaign
+var ErrEnrollmentsWithoutSection = errors.New("Students enrolled in no section of a subject with sections")
+
+// CampaignRequest is the body of POST /admin/campaigns. The questions come from a
+// template; SubjectIDs optionally restricts the campaign to some of the offered subjects.
+type CampaignRequest struct {
+	Name           string    `json:"name" binding:"required"`
+	Description    string    `json:"description"`
+	SemesterID     uint      `json:"semester_id" binding:"required"`
+	TemplateID     uint      `json:"template_id" binding:"required"`
+	OpenDate       time.Time `json:"open_date"`
+	CloseDate      time.Time `json:"close_date"`
+	Anonymous      bool      `json:"anonymous"`
+	ResultsEmbargo string    `json:"results_embargo"`
+	SubjectIDs     []uint    `json:"subject_ids"`
+}
+
+// Campaign returns the campaign described by the request
+func (req CampaignRequest) Campaign(createdBy User) Campaign {
+	return Campaign{
+		Name:           req.Name,
+		Description:    req.Description,
+		SemesterID:     req.SemesterID,
+		TemplateID:     req.TemplateID,
+		OpenDate:       req.OpenDate,
+		CloseDate:      req.CloseDate,
+		Anonymous:      req.Anonymous,
+		ResultsEmbargo: req.ResultsEmbargo,
+		CreatedByID:    createdBy.ID,
+	}
+}
+
+// OfferedSubjects returns the subjects with student enrollments in a semester,
+// optionally limited to the given subject IDs, ordered by code
+func OfferedSubjects(db *gorm.DB, semesterID uint, subjectIDs []uint) ([]Subject, error) {
+	query := db.Where("id IN (?)", db.Model(&StudentEnrollment{}).Select("subject_id").Where("semester_id = ?", semesterID))
+	if len(subjectIDs) > 0 {
+		query = query.Where("id IN ?", subjectIDs)
+	}
+
+	var subjects []Subject
+	if err := query.Order("code ASC").Find(&subjects).Error; err != nil {
+		return nil, err
+	}
+	return subjects, nil
+}
+
+// CreateCampaign stores a campaign and generates one survey per subject, owned by the
+// subject's professor, with the template's questions and the campaign's window. Subjects
+// with sections in the semester get one survey per section instead, owned by the section's
+// professor when it has one, and are refused while any of their students is in no section.
+// Everything is created in one transaction.
+func CreateCampaign(db *gorm.DB, campaign *Campaign, template SurveyTemplate, subjects []Subject) error {
+	if len(subjects) == 0 {
+		return ErrNoOfferedSubjects
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		campaign.Surveys = nil
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+
+		for _, subject := range subjects {
+			var sections []Section
+			if err := tx.Where("subject_id = ? AND semester_id = ?", subject.ID, campaign.SemesterID).Order("code ASC").Find(&sections).Error; err != nil {
+				return err
+			}
+			if len(sections) == 0 {
+				// Surveys every student of the subject
+				sections = []Section{{}}
+			} else {
+				var withoutSection int64
+				if err := tx.Model(&StudentEnrollment{}).
+					Where("subject_id = ? AND semester_id = ? AND (section_id IS NULL OR section_id = 0)", subject.ID, campaign.SemesterID).
+					Count(&withoutSection).Error; err != nil {
+					return err
+				}
+				if withoutSection > 0 {
+					return fmt.Errorf("%w: %s", ErrEnrollmentsWithoutSection, subject.Code)
+				}
+			}
+
+			for _, section := range sections {
+				survey := Survey{
+					Title:          campaign.Name,
+					Description:    campaign.Description,
+					SubjectID:      subject.ID,
+					SemesterID:     campaign.SemesterID,
+					SectionID:      section.ID,
+					ProfessorID:    subject.ProfessorID,
+					CampaignID:     campaign.ID,
+					IsActive:       true,
+					Status:         SurveyStatusOpen,
+					Anonymous:      campaign.Anonymous,
+					ResultsEmbargo: campaign.ResultsEmbargo,
+					OpenDate:       campaign.OpenDate,
+					CloseDate:      campaign.CloseDate,
+				}
+				if section.ProfessorID != 0 {
+					survey.ProfessorID = section.ProfessorID
+				}
+				questions := QuestionsFromTemplate(template)
+				for i := range questions {
+					questions[i].CampaignQuestion = true
+				}
+				if err := CreateSurveyWithQuestions(tx, &survey, questions); err != nil {
+					return err
+				}
+				campaign.Surveys = append(campaign.Surveys, survey)
+			}
+		}
+		return nil
+	})
+}
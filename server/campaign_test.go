@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaigns(t *testing.T) {
+	db := setupTestDB()
+
+	admin := User{FirstName: "Admin", LastName: "Test", Email: "admin@example.com", Password: "password123", Role: RoleAdmin}
+	db.Create(&admin)
+	maria := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&maria)
+	joao := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&joao)
+	student := User{FirstName: "Student", LastName: "Test", Email: "student@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	other := Semester{Name: "2024.2", Year: 2024, Period: 2, StartDate: time.Now().AddDate(0, 6, 0), EndDate: time.Now().AddDate(0, 10, 0)}
+	db.Create(&other)
+
+	calculus := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: maria.ID}
+	db.Create(&calculus)
+	algorithms := Subject{Name: "Algorithms", Code: "MAC201", ProfessorID: joao.ID}
+	db.Create(&algorithms)
+	notOffered := Subject{Name: "Compilers", Code: "MAC411", ProfessorID: joao.ID}
+	db.Create(&notOffered)
+
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: calculus.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: algorithms.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: notOffered.ID, SemesterID: other.ID})
+
+	template := SurveyTemplate{Name: "Course evaluation", Questions: []TemplateQuestion{
+		{Type: QuestionTypeNPS, Text: "Recommend?", Required: true, Order: 1},
+		{Type: QuestionTypeFreeText, Text: "Comments", Order: 2},
+	}}
+	assert.NoError(t, CreateTemplate(db, admin, &template))
+
+	openDate := time.Now()
+	closeDate := openDate.AddDate(0, 0, 14)
+	req := CampaignRequest{Name: "End of term 2024.1", SemesterID: semester.ID, TemplateID: template.ID, OpenDate: openDate, CloseDate: closeDate, Anonymous: true}
+
+	t.Run("Offered Subjects", func(t *testing.T) {
+		subjects, err := OfferedSubjects(db, semester.ID, nil)
+		assert.NoError(t, err)
+		assert.Len(t, subjects, 2)
+		assert.Equal(t, "MAC201", subjects[0].Code)
+		assert.Equal(t, "MAT101", subjects[1].Code)
+
+		subjects, err = OfferedSubjects(db, semester.ID, []uint{calculus.ID, notOffered.ID})
+		assert.NoError(t, err)
+		assert.Len(t, subjects, 1)
+		assert.Equal(t, calculus.ID, subjects[0].ID)
+	})
+
+	t.Run("Generates One Survey Per Subject", func(t *testing.T) {
+		subjects, _ := OfferedSubjects(db, semester.ID, nil)
+		campaign := req.Campaign(admin)
+		assert.NoError(t, CreateCampaign(db, &campaign, template, subjects))
+		assert.Len(t, campaign.Surveys, 2)
+
+		var surveys []Survey
+		db.Preload("Questions").Where("campaign_id = ?", campaign.ID).Order("subject_id ASC").Find(&surveys)
+		assert.Len(t, surveys, 2)
+		assert.Equal(t, calculus.ID, surveys[0].SubjectID)
+		assert.Equal(t, maria.ID, surveys[0].ProfessorID)
+		assert.Equal(t, algorithms.ID, surveys[1].SubjectID)
+		assert.Equal(t, joao.ID, surveys[1].ProfessorID)
+		for _, s := range surveys {
+			assert.Equal(t, "End of term 2024.1", s.Title)
+			assert.Equal(t, semester.ID, s.SemesterID)
+			assert.True(t, s.IsActive)
+			assert.True(t, s.Anonymous)
+			assert.WithinDuration(t, openDate, s.OpenDate, time.Second)
+			assert.WithinDuration(t, closeDate, s.CloseDate, time.Second)
+			assert.Len(t, s.Questions, 2)
+			for _, q := range s.Questions {
+				assert.True(t, q.CampaignQuestion)
+			}
+		}
+	})
+
+	t.Run("No Offered Subjects", func(t *testing.T) {
+		campaign := req.Campaign(admin)
+		err := CreateCampaign(db, &campaign, template, nil)
+		assert.ErrorIs(t, err, ErrNoOfferedSubjects)
+	})
+
+	t.Run("Subject Surveyed Once Per Campaign", func(t *testing.T) {
+		campaign := req.Campaign(admin)
+		err := CreateCampaign(db, &campaign, template, []Subject{calculus, calculus})
+		assert.Error(t, err)
+		assert.True(t, isUniqueViolation(err))
+
+		// The transaction leaves nothing behind
+		var count int64
+		db.Model(&Campaign{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+		db.Model(&Survey{}).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Students Without A Section Are Refused", func(t *testing.T) {
+		sectioned := Subject{Name: "Linear Algebra", Code: "MAT201", ProfessorID: maria.ID}
+		db.Create(&sectioned)
+		section := Section{SubjectID: sectioned.ID, SemesterID: semester.ID, Code: "A", ProfessorID: joao.ID}
+		db.Create(&section)
+		placed := User{FirstName: "Placed", LastName: "Student", Email: "placed@example.com", Password: "password123", Role: RoleStudent}
+		db.Create(&placed)
+		db.Create(&StudentEnrollment{StudentID: placed.ID, SubjectID: sectioned.ID, SemesterID: semester.ID, SectionID: section.ID})
+		db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: sectioned.ID, SemesterID: semester.ID})
+
+		campaign := req.Campaign(admin)
+		err := CreateCampaign(db, &campaign, template, []Subject{calculus, sectioned})
+		assert.ErrorIs(t, err, ErrEnrollmentsWithoutSection)
+		assert.Contains(t, err.Error(), "MAT201")
+
+		// The transaction leaves nothing behind
+		var count int64
+		db.Model(&Survey{}).Where("subject_id = ?", sectioned.ID).Count(&count)
+		assert.Zero(t, count)
+
+		// Once every student is in a section the campaign goes through
+		db.Model(&StudentEnrollment{}).Where("student_id = ? AND subject_id = ?", student.ID, sectioned.ID).Update("section_id", section.ID)
+		campaign = req.Campaign(admin)
+		assert.NoError(t, CreateCampaign(db, &campaign, template, []Subject{sectioned}))
+		assert.Len(t, campaign.Surveys, 1)
+		assert.Equal(t, section.ID, campaign.Surveys[0].SectionID)
+	})
+}
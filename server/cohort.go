@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// DefaultMinCohortSize is the number of submissions a survey needs before its
+// results are shown, unless MIN_RESPONSE_COHORT says otherwise
+const DefaultMinCohortSize = 5
+
+// Cohort statuses returned alongside survey results
+const (
+	CohortStatusOK                 = "ok"
+	CohortStatusNotEnoughResponses = "not_enough_responses"
+)
+
+// Cohort tells whether a survey has enough submissions for its results to be
+// shown without making them attributable to individual students
+type Cohort struct {
+	Status       string `json:"status"`
+	Respondents  int64  `json:"respondents"`
+	MinResponses int    `json:"min_responses"`
+}
+
+// Met reports whether the survey reached the minimum cohort size
+func (c Cohort) Met() bool {
+	return c.Status == CohortStatusOK
+}
+
+func newCohort(respondents int64, minSize int) Cohort {
+	cohort := Cohort{Status: CohortStatusOK, Respondents: respondents, MinResponses: minSize}
+	if respondents < int64(minSize) {
+		cohort.Status = CohortStatusNotEnoughResponses
+	}
+	return cohort
+}
+
+// getMinCohortSize returns the configured minimum cohort size. A value of 0 or 1
+// disables the threshold.
+func getMinCohortSize() int {
+	value := os.Getenv("MIN_RESPONSE_COHORT")
+	if value == "" {
+		return DefaultMinCohortSize
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		log.Printf("⚠️  Invalid MIN_RESPONSE_COHORT %q, using %d", value, DefaultMinCohortSize)
+		return DefaultMinCohortSize
+	}
+	return size
+}
+
+// SurveyCohorts returns the cohort of each given survey, keyed by survey ID.
+// Submissions are counted per student, or per submission token for anonymous surveys.
+func SurveyCohorts(db *gorm.DB, surveyIDs []uint, minSize int) (map[uint]Cohort, error) {
+	cohorts := make(map[uint]Cohort, len(surveyIDs))
+	if len(surveyIDs) == 0 {
+		return cohorts, nil
+	}
+
+	var rows []struct {
+		SurveyID uint
+		Total    int64
+	}
+	if err := db.Model(&Response{}).
+		Select("survey_id, COUNT(DISTINCT student_id) + COUNT(DISTINCT NULLIF(submission_token, '')) AS total").
+		Where("survey_id IN ?", surveyIDs).
+		Group("survey_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	submissions := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		submissions[row.SurveyID] = row.Total
+	}
+
+	for _, id := range surveyIDs {
+		cohorts[id] = newCohort(submissions[id], minSize)
+	}
+	return cohorts, nil
+}
+
+// SurveyCohort returns the cohort of a single survey
+func SurveyCohort(db *gorm.DB, surveyID uint, minSize int) (Cohort, error) {
+	cohorts, err := SurveyCohorts(db, []uint{surveyID}, minSize)
+	if err != nil {
+		return Cohort{}, err
+	}
+	return cohorts[surveyID], nil
+}
+
+// FilterByCohort drops the responses of surveys below the minimum cohort size and
+// returns the cohort of every survey the responses belonged to
+func FilterByCohort(db *gorm.DB, responses []Response, minSize int) ([]Response, map[uint]Cohort, error) {
+	seen := make(map[uint]bool)
+	var surveyIDs []uint
+	for _, r := range responses {
+		if !seen[r.SurveyID] {
+			seen[r.SurveyID] = true
+			surveyIDs = append(surveyIDs, r.SurveyID)
+		}
+	}
+	cohorts, err := SurveyCohorts(db, surveyIDs, minSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filtered := make([]Response, 0, len(responses))
+	for _, r := range responses {
+		if cohorts[r.SurveyID].Met() {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, cohorts, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinCohortSize(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		t.Setenv("MIN_RESPONSE_COHORT", "")
+		assert.Equal(t, DefaultMinCohortSize, getMinCohortSize())
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		t.Setenv("MIN_RESPONSE_COHORT", "3")
+		assert.Equal(t, 3, getMinCohortSize())
+	})
+
+	t.Run("Invalid Falls Back To Default", func(t *testing.T) {
+		t.Setenv("MIN_RESPONSE_COHORT", "-1")
+		assert.Equal(t, DefaultMinCohortSize, getMinCohortSize())
+		t.Setenv("MIN_RESPONSE_COHORT", "five")
+		assert.Equal(t, DefaultMinCohortSize, getMinCohortSize())
+	})
+}
+
+func TestSurveyCohorts(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+
+	var students []User
+	for i, email := range []string{"s1@example.com", "s2@example.com", "s3@example.com"} {
+		student := User{FirstName: "Student", LastName: string(rune('A' + i)), Email: email, Password: "password123", Role: RoleStudent}
+		db.Create(&student)
+		db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: subject.ID, SemesterID: semester.ID})
+		students = append(students, student)
+	}
+
+	identified := Survey{Title: "Identified", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&identified)
+	anonymous := Survey{Title: "Anonymous", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true, Anonymous: true}
+	db.Create(&anonymous)
+	empty := Survey{Title: "Empty", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&empty)
+
+	for _, survey := range []*Survey{&identified, &anonymous} {
+		q1 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Order: 1}
+		db.Create(&q1)
+		q2 := Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 2}
+		db.Create(&q2)
+		for _, student := range students {
+			_, err := SubmitSurvey(db, *survey, student.ID, []SubmittedAnswer{{QuestionID: q1.ID, Answer: "8"}, {QuestionID: q2.ID, Answer: "Fine"}})
+			assert.NoError(t, err)
+		}
+	}
+
+	t.Run("Counts Submissions Not Answers", func(t *testing.T) {
+		cohorts, err := SurveyCohorts(db, []uint{identified.ID, anonymous.ID, empty.ID}, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, Cohort{Status: CohortStatusOK, Respondents: 3, MinResponses: 3}, cohorts[identified.ID])
+		assert.Equal(t, Cohort{Status: CohortStatusOK, Respondents: 3, MinResponses: 3}, cohorts[anonymous.ID])
+		assert.Equal(t, Cohort{Status: CohortStatusNotEnoughResponses, Respondents: 0, MinResponses: 3}, cohorts[empty.ID])
+	})
+
+	t.Run("Below Threshold", func(t *testing.T) {
+		cohort, err := SurveyCohort(db, anonymous.ID, 5)
+		assert.NoError(t, err)
+		assert.False(t, cohort.Met())
+		assert.Equal(t, CohortStatusNotEnoughResponses, cohort.Status)
+		assert.Equal(t, int64(3), cohort.Respondents)
+	})
+
+	t.Run("Threshold Disabled", func(t *testing.T) {
+		cohort, err := SurveyCohort(db, empty.ID, 0)
+		assert.NoError(t, err)
+		assert.True(t, cohort.Met())
+	})
+
+	t.Run("Filter Withholds Small Cohorts", func(t *testing.T) {
+		// A fourth student only answers the identified survey
+		extra := User{FirstName: "Student", LastName: "D", Email: "s4@example.com", Password: "password123", Role: RoleStudent}
+		db.Create(&extra)
+		var question Question
+		db.Where("survey_id = ?", identified.ID).First(&question)
+		db.Create(&Response{SurveyID: identified.ID, StudentID: extra.ID, QuestionID: question.ID, Answer: "10"})
+
+		var responses []Response
+		db.Find(&responses)
+		filtered, cohorts, err := FilterByCohort(db, responses, 4)
+		assert.NoError(t, err)
+		assert.Len(t, filtered, 7)
+		for _, r := range filtered {
+			assert.Equal(t, identified.ID, r.SurveyID)
+		}
+		assert.True(t, cohorts[identified.ID].Met())
+		assert.False(t, cohorts[anonymous.ID].Met())
+		assert.Equal(t, int64(3), cohorts[anonymous.ID].Respondents)
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Results embargo policies. A survey with an empty policy follows the
+// institution-wide policy set in RESULTS_EMBARGO.
+const (
+	EmbargoNone            = "none"
+	EmbargoUntilClose      = "until_close"
+	EmbargoUntilGradesDone = "until_grades_finalized"
+)
+
+// ErrCodeResultsEmbargoed is returned when results are requested before the embargo lifts
+const ErrCodeResultsEmbargoed = "results_embargoed"
+
+// Embargo tells whether the results of a survey are still hidden from its professor
+type Embargo struct {
+	Policy string     `json:"policy"`
+	Active bool       `json:"active"`
+	Until  *time.Time `json:"until,omitempty"` // only known for until_close
+}
+
+func isEmbargoPolicy(policy string) bool {
+	return policy == EmbargoNone || policy == EmbargoUntilClose || policy == EmbargoUntilGradesDone
+}
+
+// ValidateEmbargoPolicy checks the policy chosen for a survey; empty means the institution default
+func ValidateEmbargoPolicy(policy string) error {
+	if policy != "" && !isEmbargoPolicy(policy) {
+		return fmt.Errorf("Invalid results embargo, use %s, %s or %s", EmbargoNone, EmbargoUntilClose, EmbargoUntilGradesDone)
+	}
+	return nil
+}
+
+// getDefaultEmbargoPolicy returns the institution-wide results embargo policy
+func getDefaultEmbargoPolicy() string {
+	policy := os.Getenv("RESULTS_EMBARGO")
+	if policy == "" {
+		return EmbargoNone
+	}
+	if !isEmbargoPolicy(policy) {
+		log.Printf("⚠️  Invalid RESULTS_EMBARGO %q, using %s", policy, EmbargoNone)
+		return EmbargoNone
+	}
+	return policy
+}
+
+// SurveyEmbargo returns the embargo on a survey's results at the given time. The
+// institution-wide policy is a minimum: a survey's own policy can hide results for
+// longer but never show them sooner. survey.Semester must be loaded for the
+// until_grades_finalized policy.
+func SurveyEmbargo(survey Survey, defaultPolicy string, now time.Time) Embargo {
+	institution := policyEmbargo(survey, defaultPolicy, now)
+	if survey.ResultsEmbargo == "" || survey.ResultsEmbargo == defaultPolicy {
+		return institution
+	}
+	own := policyEmbargo(survey, survey.ResultsEmbargo, now)
+	if institution.Active && !own.Active {
+		return institution
+	}
+	return own
+}
+
+// policyEmbargo returns the embargo a single policy puts on a survey's results
+func policyEmbargo(survey Survey, policy string, now time.Time) Embargo {
+	embargo := Embargo{Policy: policy}
+	switch policy {
+	case EmbargoUntilClose:
+		closeDate := survey.CloseDate
+		embargo.Until = &closeDate
+		embargo.Active = !now.After(closeDate)
+	case EmbargoUntilGradesDone:
+		embargo.Active = !survey.Semester.GradesFinalized
+	}
+	return embargo
+}
+
+// FilterByEmbargo drops the responses of surveys whose results are still embargoed.
+// Each response's Survey.Semester must be loaded.
+func FilterByEmbargo(responses []Response, defaultPolicy string, now time.Time) []Response {
+	filtered := make([]Response, 0, len(responses))
+	for _, r := range responses {
+		if !SurveyEmbargo(r.Survey, defaultPolicy, now).Active {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyEmbargo(t *testing.T) {
+	closeDate := time.Date(2024, 6, 30, 23, 59, 0, 0, time.UTC)
+	before := closeDate.Add(-time.Hour)
+	after := closeDate.Add(time.Hour)
+
+	t.Run("No Embargo", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate, ResultsEmbargo: EmbargoNone}
+		embargo := SurveyEmbargo(survey, EmbargoNone, before)
+		assert.False(t, embargo.Active)
+		assert.Equal(t, EmbargoNone, embargo.Policy)
+	})
+
+	t.Run("Surveys Cannot Loosen The Institution Policy", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate, ResultsEmbargo: EmbargoNone}
+		embargo := SurveyEmbargo(survey, EmbargoUntilClose, before)
+		assert.True(t, embargo.Active)
+		assert.Equal(t, EmbargoUntilClose, embargo.Policy)
+		assert.False(t, SurveyEmbargo(survey, EmbargoUntilClose, after).Active)
+
+		survey.ResultsEmbargo = EmbargoUntilClose
+		assert.True(t, SurveyEmbargo(survey, EmbargoUntilGradesDone, after).Active)
+	})
+
+	t.Run("Surveys Can Tighten The Institution Policy", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate, ResultsEmbargo: EmbargoUntilGradesDone}
+		embargo := SurveyEmbargo(survey, EmbargoUntilClose, after)
+		assert.True(t, embargo.Active)
+		assert.Equal(t, EmbargoUntilGradesDone, embargo.Policy)
+
+		survey.Semester.GradesFinalized = true
+		assert.True(t, SurveyEmbargo(survey, EmbargoUntilClose, before).Active)
+		assert.False(t, SurveyEmbargo(survey, EmbargoUntilClose, after).Active)
+	})
+
+	t.Run("Until Close", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate, ResultsEmbargo: EmbargoUntilClose}
+		embargo := SurveyEmbargo(survey, EmbargoNone, before)
+		assert.True(t, embargo.Active)
+		assert.Equal(t, closeDate, *embargo.Until)
+		assert.True(t, SurveyEmbargo(survey, EmbargoNone, closeDate).Active)
+		assert.False(t, SurveyEmbargo(survey, EmbargoNone, after).Active)
+	})
+
+	t.Run("Until Grades Finalized", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate, ResultsEmbargo: EmbargoUntilGradesDone}
+		embargo := SurveyEmbargo(survey, EmbargoNone, after)
+		assert.True(t, embargo.Active)
+		assert.Nil(t, embargo.Until)
+
+		survey.Semester.GradesFinalized = true
+		assert.False(t, SurveyEmbargo(survey, EmbargoNone, before).Active)
+	})
+
+	t.Run("Institution Default", func(t *testing.T) {
+		survey := Survey{CloseDate: closeDate}
+		embargo := SurveyEmbargo(survey, EmbargoUntilClose, before)
+		assert.True(t, embargo.Active)
+		assert.Equal(t, EmbargoUntilClose, embargo.Policy)
+		assert.False(t, SurveyEmbargo(survey, EmbargoNone, before).Active)
+	})
+}
+
+func TestEmbargoPolicy(t *testing.T) {
+	t.Run("Validation", func(t *testing.T) {
+		assert.NoError(t, ValidateEmbargoPolicy(""))
+		assert.NoError(t, ValidateEmbargoPolicy(EmbargoNone))
+		assert.NoError(t, ValidateEmbargoPolicy(EmbargoUntilClose))
+		assert.NoError(t, ValidateEmbargoPolicy(EmbargoUntilGradesDone))
+		assert.Error(t, ValidateEmbargoPolicy("forever"))
+	})
+
+	t.Run("Institution Default From Environment", func(t *testing.T) {
+		t.Setenv("RESULTS_EMBARGO", "")
+		assert.Equal(t, EmbargoNone, getDefaultEmbargoPolicy())
+		t.Setenv("RESULTS_EMBARGO", EmbargoUntilGradesDone)
+		assert.Equal(t, EmbargoUntilGradesDone, getDefaultEmbargoPolicy())
+		t.Setenv("RESULTS_EMBARGO", "forever")
+		assert.Equal(t, EmbargoNone, getDefaultEmbargoPolicy())
+	})
+}
+
+func TestFilterByEmbargo(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	open := Survey{ID: 1, CloseDate: now.AddDate(0, 0, 7), ResultsEmbargo: EmbargoUntilClose}
+	closed := Survey{ID: 2, CloseDate: now.AddDate(0, 0, -7), ResultsEmbargo: EmbargoUntilClose}
+	finalized := Survey{ID: 3, ResultsEmbargo: EmbargoUntilGradesDone, Semester: Semester{GradesFinalized: true}}
+
+	responses := []Response{
+		{ID: 1, SurveyID: open.ID, Survey: open},
+		{ID: 2, SurveyID: closed.ID, Survey: closed},
+		{ID: 3, SurveyID: finalized.ID, Survey: finalized},
+	}
+	filtered := FilterByEmbargo(responses, EmbargoNone, now)
+	assert.Len(t, filtered, 2)
+	assert.Equal(t, uint(2), filtered[0].ID)
+	assert.Equal(t, uint(3), filtered[1].ID)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported response export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// Export column headers that precede the question columns
+const (
+	ExportColumnSubmission  = "Submission"
+	ExportColumnSubmittedAt = "Submitted At"
+)
+
+// ResponseTable is a spreadsheet view of a survey's responses: one row per
+// submission and one column per question. Rows carry no student identity.
+type ResponseTable struct {
+	Questions []Question // column order, sorted by Question.Order
+	Header    []string
+	Rows      [][]string
+}
+
+// BuildResponseTable groups the responses of a survey into one row per submission.
+// Rows are numbered in submission order and blank cells are questions left unanswered.
+func BuildResponseTable(questions []Question, responses []Response) ResponseTable {
+	sorted := make([]Question, len(questions))
+	copy(sorted, questions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	column := make(map[uint]int, len(sorted))
+	header := []string{ExportColumnSubmission, ExportColumnSubmittedAt}
+	for i, q := range sorted {
+		column[q.ID] = i
+		header = append(header, q.Label())
+	}
+
+	// Submission keys are only used to group answers and never leave this function
+	type submission struct {
+		submittedAt time.Time
+		answers     []string
+	}
+	byKey := make(map[string]*submission)
+	var submissions []*submission
+	for _, r := range responses {
+		col, ok := column[r.QuestionID]
+		if !ok {
+			continue
+		}
+		key := submissionKey(r)
+		s, ok := byKey[key]
+		if !ok {
+			s = &submission{submittedAt: r.SubmittedAt, answers: make([]string, len(sorted))}
+			byKey[key] = s
+			submissions = append(submissions, s)
+		}
+		if r.SubmittedAt.Before(s.submittedAt) {
+			s.submittedAt = r.SubmittedAt
+		}
+		s.answers[col] = r.Answer
+	}
+	sort.SliceStable(submissions, func(i, j int) bool { return submissions[i].submittedAt.Before(submissions[j].submittedAt) })
+
+	rows := make([][]string, len(submissions))
+	for i, s := range submissions {
+		row := []string{strconv.Itoa(i + 1), s.submittedAt.UTC().Format(time.RFC3339)}
+		rows[i] = append(row, s.answers...)
+	}
+
+	return ResponseTable{Questions: sorted, Header: header, Rows: rows}
+}
+
+// escapeCSVCell prefixes cells that spreadsheet software would run as a formula with a
+// single quote, so free-text answers are always shown as text (CSV injection)
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func escapeCSVRecord(record []string) []string {
+	escaped := make([]string, len(record))
+	for i, value := range record {
+		escaped[i] = escapeCSVCell(value)
+	}
+	return escaped
+}
+
+// WriteCSV writes the table as UTF-8 CSV with a byte order mark, so spreadsheet
+// software picks up accented characters correctly. Cells that would start a formula are escaped.
+func (t ResponseTable) WriteCSV(w io.Writer) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(escapeCSVRecord(t.Header)); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		if err := writer.Write(escapeCSVRecord(row)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteXLSX writes the table as a single-sheet Excel workbook. NPS and rating
+// answers are stored as numbers so they can be aggregated in the spreadsheet.
+func (t ResponseTable) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Responses"
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+
+	fixedColumns := len(t.Header) - len(t.Questions)
+	for i, row := range t.Rows {
+		cells := make([]interface{}, len(row))
+		for j, value := range row {
+			cells[j] = value
+			if j == 0 {
+				cells[j], _ = strconv.Atoi(value)
+				continue
+			}
+			if j < fixedColumns || value == "" {
+				continue
+			}
+			if qType := t.Questions[j-fixedColumns].Type; qType == QuestionTypeNPS || qType == QuestionTypeRating {
+				if n, err := strconv.Atoi(value); err == nil {
+					cells[j] = n
+				}
+			}
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &cells); err != nil {
+			return err
+		}
+	}
+
+	_, err := f.WriteTo(w)
+	return err
+}
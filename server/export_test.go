@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func exportFixture() ([]Question, []Response) {
+	questions := []Question{
+		{ID: 2, Type: QuestionTypeFreeText, Text: "Comentários", Order: 2},
+		{ID: 1, Type: QuestionTypeNPS, Text: "Recommend?", Order: 1},
+	}
+
+	first := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	responses := []Response{
+		{SurveyID: 1, StudentID: 42, QuestionID: 1, Answer: "7", SubmittedAt: second},
+		{SurveyID: 1, StudentID: 7, QuestionID: 1, Answer: "10", SubmittedAt: first},
+		{SurveyID: 1, StudentID: 7, QuestionID: 2, Answer: "Ótimo, \"muito\" bom", SubmittedAt: first},
+		{SurveyID: 1, StudentID: 7, QuestionID: 99, Answer: "deleted question", SubmittedAt: first},
+	}
+	return questions, responses
+}
+
+func TestBuildResponseTable(t *testing.T) {
+	questions, responses := exportFixture()
+
+	table := BuildResponseTable(questions, responses)
+
+	assert.Equal(t, []string{ExportColumnSubmission, ExportColumnSubmittedAt, "Recommend?", "Comentários"}, table.Header)
+	assert.Equal(t, [][]string{
+		{"1", "2024-05-01T10:00:00Z", "10", "Ótimo, \"muito\" bom"},
+		{"2", "2024-05-01T11:00:00Z", "7", ""},
+	}, table.Rows)
+
+	for _, row := range table.Rows {
+		assert.NotContains(t, row, "42")
+	}
+}
+
+func TestResponseTableWriteCSV(t *testing.T) {
+	questions, responses := exportFixture()
+	table := BuildResponseTable(questions, responses)
+
+	var buf bytes.Buffer
+	assert.NoError(t, table.WriteCSV(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "\xEF\xBB\xBF"))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, table.Header, records[0])
+	assert.Equal(t, "Ótimo, \"muito\" bom", records[1][3])
+}
+
+func TestResponseTableWriteCSVEscapesFormulas(t *testing.T) {
+	questions := []Question{{ID: 1, Type: QuestionTypeFreeText, Text: "Comentários", Order: 1}}
+	submittedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	answers := []string{`=HYPERLINK("http://evil.example","clique")`, "+1", "-1", "@SUM(A1)", "\tx", "sem fórmula"}
+	var responses []Response
+	for i, answer := range answers {
+		responses = append(responses, Response{SurveyID: 1, StudentID: uint(i + 1), QuestionID: 1, Answer: answer, SubmittedAt: submittedAt.Add(time.Duration(i) * time.Minute)})
+	}
+	table := BuildResponseTable(questions, responses)
+
+	var buf bytes.Buffer
+	assert.NoError(t, table.WriteCSV(&buf))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, len(answers)+1)
+	assert.Equal(t, `'=HYPERLINK("http://evil.example","clique")`, records[1][2])
+	assert.Equal(t, "'+1", records[2][2])
+	assert.Equal(t, "'-1", records[3][2])
+	assert.Equal(t, "'@SUM(A1)", records[4][2])
+	assert.Equal(t, "'\tx", records[5][2])
+	assert.Equal(t, "sem fórmula", records[6][2])
+
+	// The table itself keeps the raw answers, escaping only applies to the CSV output
+	assert.Equal(t, answers[0], table.Rows[0][2])
+}
+
+func TestResponseTableWriteXLSX(t *testing.T) {
+	questions, responses := exportFixture()
+	table := BuildResponseTable(questions, responses)
+
+	var buf bytes.Buffer
+	assert.NoError(t, table.WriteXLSX(&buf))
+
+	f, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Responses")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, table.Header, rows[0])
+	assert.Equal(t, "10", rows[1][2])
+
+	// NPS answers are stored as numbers
+	cellType, err := f.GetCellType("Responses", "C2")
+	assert.NoError(t, err)
+	assert.NotEqual(t, excelize.CellTypeSharedString, cellType)
+	assert.NotEqual(t, excelize.CellTypeInlineString, cellType)
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCSV is returned when an import file is not a CSV with the expected header
+var ErrInvalidCSV = errors.New("Invalid CSV file")
+
+// Columns of the import files. Optional columns may be left out of the header.
+var (
+	userImportColumns       = []string{"first_name", "last_name", "email"}  // optional: role, password
+	enrollmentImportColumns = []string{"email", "subject_code", "semester"} // optional: section
+)
+
+// ImportRowError describes why a row of an import file was rejected. Rows are numbered
+// as lines of the file, so the first data row is row 2.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedUser is a user created by an import, with the temporary password generated
+// when the file didn't set one
+type ImportedUser struct {
+	Row               int    `json:"row"`
+	ID                uint   `json:"id"`
+	Email             string `json:"email"`
+	Role              string `json:"role"`
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+}
+
+// ImportResult reports the outcome of an import. Nothing is created when any row has
+// an error or in a dry run.
+type ImportResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Rows    int              `json:"rows"`
+	Created int              `json:"created"`
+	Errors  []ImportRowError `json:"errors"`
+	Users   []ImportedUser   `json:"users,omitempty"`
+}
+
+// csvTable is a parsed import file with its columns looked up by name
+type csvTable struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+func (t csvTable) value(row []string, column string) string {
+	i, ok := t.columns[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// readCSV parses an import file whose header must contain the required columns.
+// Column names are case-insensitive and blank lines are skipped.
+func readCSV(r io.Reader, required []string) (csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return csvTable{}, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	if len(records) == 0 {
+		return csvTable{}, fmt.Errorf("%w: missing header", ErrInvalidCSV)
+	}
+
+	table := csvTable{columns: make(map[string]int)}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		table.columns[name] = i
+	}
+	for _, column := range required {
+		if _, ok := table.columns[column]; !ok {
+			return csvTable{}, fmt.Errorf("%w: missing column %s", ErrInvalidCSV, column)
+		}
+	}
+	table.rows = records[1:]
+	return table, nil
+}
+
+func isBlankRecord(row []string) bool {
+	for _, field := range row {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newTemporaryPassword returns a random password for imported users without one
+func newTemporaryPassword() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ImportUsers creates the users listed in a CSV file with the columns first_name,
+// last_name, email and optionally role (student by default) and password (generated
+// when empty). Every row is validated first; users are only created, in one transaction,
+// when no row has an error and dryRun is false.
+func ImportUsers(db *gorm.DB, r io.Reader, dryRun bool) (ImportResult, error) {
+	result := ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+	table, err := readCSV(r, userImportColumns)
+	if err != nil {
+		return result, err
+	}
+
+	type pendingUser struct {
+		row  int
+		user User
+	}
+	var pending []pendingUser
+	seen := make(map[string]int)
+	var emails []string
+	for i, record := range table.rows {
+		row := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+		result.Rows++
+
+		user := User{
+			FirstName: table.value(record, "first_name"),
+			LastName:  table.value(record, "last_name"),
+			Email:     normalizeEmail(table.value(record, "email")),
+			Role:      strings.ToLower(table.value(record, "role")),
+			Password:  table.value(record, "password"),
+		}
+		if user.Role == "" {
+			user.Role = RoleStudent
+		}
+		user.RequestedRole = user.Role
+
+		rowErrors := len(result.Errors)
+		if user.FirstName == "" {
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "first_name", Message: "First name is required"})
+		}
+		if user.LastName == "" {
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "last_name", Message: "Last name is required"})
+		}
+		switch {
+		case user.Email == "":
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "email", Message: "Email is required"})
+		case !strings.Contains(user.Email, "@"):
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "email", Message: "Invalid email"})
+		case seen[user.Email] != 0:
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "email", Message: fmt.Sprintf("Duplicate email, already on row %d", seen[user.Email])})
+		default:
+			seen[user.Email] = row
+			emails = append(emails, user.Email)
+		}
+		if !isRole(user.Role) {
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "role", Message: "Invalid role"})
+		}
+		if len(result.Errors) == rowErrors {
+			pending = append(pending, pendingUser{row: row, user: user})
+		}
+	}
+
+	// Emails already registered
+	if len(emails) > 0 {
+		var existing []string
+		if err := db.Model(&User{}).Where("LOWER(email) IN ?", emails).Pluck("LOWER(email)", &existing).Error; err != nil {
+			return result, err
+		}
+		taken := make(map[string]bool, len(existing))
+		for _, email := range existing {
+			taken[email] = true
+		}
+		kept := pending[:0]
+		for _, p := range pending {
+			if taken[p.user.Email] {
+				result.Errors = append(result.Errors, ImportRowError{Row: p.row, Field: "email", Message: "Email already exists"})
+				continue
+			}
+			kept = append(kept, p)
+		}
+		pending = kept
+	}
+	sortImportErrors(result.Errors)
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range pending {
+			imported := ImportedUser{Row: p.row, Email: p.user.Email, Role: p.user.Role}
+			password := p.user.Password
+			if password == "" {
+				if password, err = newTemporaryPassword(); err != nil {
+					return err
+				}
+				imported.TemporaryPassword = password
+			}
+			user := p.user
+			if user.Password, err = HashPassword(password); err != nil {
+				return err
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			imported.ID = user.ID
+			result.Users = append(result.Users, imported)
+		}
+		return nil
+	})
+	if err != nil {
+		result.Users = nil
+		return result, err
+	}
+	result.Created = len(result.Users)
+	return result, nil
+}
+
+// ImportEnrollments enrolls students listed in a CSV file with the columns email,
+// subject_code, semester (the semester name, e.g. 2024.1) and optionally section (the
+// section code). Every row is validated first; enrollments are only created, in one
+// transaction, when no row has an error and dryRun is false.
+func ImportEnrollments(db *gorm.DB, r io.Reader, dryRun bool) (ImportResult, error) {
+	result := ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+	table, err := readCSV(r, enrollmentImportColumns)
+	if err != nil {
+		return result, err
+	}
+
+	// Everything rows refer to is loaded up front instead of once per row
+	var students []User
+	if err := db.Where("role = ?", RoleStudent).Find(&students).Error; err != nil {
+		return result, err
+	}
+	studentsByEmail := make(map[string]User, len(students))
+	for _, s := range students {
+		studentsByEmail[normalizeEmail(s.Email)] = s
+	}
+	var subjects []Subject
+	if err := db.Find(&subjects).Error; err != nil {
+		return result, err
+	}
+	subjectsByCode := make(map[string]Subject, len(subjects))
+	for _, s := range subjects {
+		subjectsByCode[strings.ToUpper(s.Code)] = s
+	}
+	var semesters []Semester
+	if err := db.Find(&semesters).Error; err != nil {
+		return result, err
+	}
+	semestersByName := make(map[string]Semester, len(semesters))
+	for _, s := range semesters {
+		semestersByName[s.Name] = s
+	}
+	var sections []Section
+	if err := db.Find(&sections).Error; err != nil {
+		return result, err
+	}
+	type sectionKey struct {
+		subjectID, semesterID uint
+		code                  string
+	}
+	sectionsByKey := make(map[sectionKey]Section, len(sections))
+	for _, s := range sections {
+		sectionsByKey[sectionKey{s.SubjectID, s.SemesterID, strings.ToUpper(s.Code)}] = s
+	}
+
+	type enrollmentKey struct {
+		studentID, subjectID, semesterID uint
+	}
+	var existing []StudentEnrollment
+	if err := db.Find(&existing).Error; err != nil {
+		return result, err
+	}
+	enrolled := make(map[enrollmentKey]int, len(existing)) // row of the file, or 0 when stored
+	for _, e := range existing {
+		enrolled[enrollmentKey{e.StudentID, e.SubjectID, e.SemesterID}] = 0
+	}
+
+	var pending []StudentEnrollment
+	for i, record := range table.rows {
+		row := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+		result.Rows++
+		rowErrors := len(result.Errors)
+
+		email := normalizeEmail(table.value(record, "email"))
+		student, ok := studentsByEmail[email]
+		if !ok {
+			var user User
+			message := "Unknown student"
+			if email == "" {
+				message = "Email is required"
+			} else if db.Where("LOWER(email) = ?", email).First(&user).Error == nil {
+				message = "User is not a student"
+			}
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "email", Message: message})
+		}
+
+		code := table.value(record, "subject_code")
+		subject, ok := subjectsByCode[strings.ToUpper(code)]
+		if !ok {
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "subject_code", Message: fmt.Sprintf("Unknown subject code %q", code)})
+		}
+
+		name := table.value(record, "semester")
+		semester, ok := semestersByName[name]
+		if !ok {
+			message := fmt.Sprintf("Unknown semester %q", name)
+			if name == "" {
+				message = "Semester is required"
+			}
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "semester", Message: message})
+		}
+		if len(result.Errors) > rowErrors {
+			continue
+		}
+
+		enrollment := StudentEnrollment{StudentID: student.ID, SubjectID: subject.ID, SemesterID: semester.ID}
+		if sectionCode := table.value(record, "section"); sectionCode != "" {
+			section, ok := sectionsByKey[sectionKey{subject.ID, semester.ID, strings.ToUpper(sectionCode)}]
+			if !ok {
+				result.Errors = append(result.Errors, ImportRowError{Row: row, Field: "section", Message: fmt.Sprintf("Unknown section %q for %s in %s", sectionCode, subject.Code, semester.Name)})
+				continue
+			}
+			enrollment.SectionID = section.ID
+		}
+
+		key := enrollmentKey{student.ID, subject.ID, semester.ID}
+		if first, ok := enrolled[key]; ok {
+			message := "Student is already enrolled in this subject and semester"
+			if first != 0 {
+				message = fmt.Sprintf("Duplicate enrollment, already on row %d", first)
+			}
+			result.Errors = append(result.Errors, ImportRowError{Row: row, Message: message})
+			continue
+		}
+		enrolled[key] = row
+		pending = append(pending, enrollment)
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if len(pending) > 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			for i := range pending {
+				if err := tx.Create(&pending[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return result, err
+		}
+	}
+	result.Created = len(pending)
+	return result, nil
+}
+
+// sortImportErrors orders errors by row, keeping the order of errors within a row
+func sortImportErrors(errs []ImportRowError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Row < errs[j].Row })
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportUsers(t *testing.T) {
+	db := setupTestDB()
+
+	existing := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&existing)
+
+	t.Run("Invalid Header", func(t *testing.T) {
+		_, err := ImportUsers(db, strings.NewReader("name,email\nMaria,maria@example.com\n"), false)
+		assert.ErrorIs(t, err, ErrInvalidCSV)
+	})
+
+	t.Run("Dry Run Reports Row Errors", func(t *testing.T) {
+		csv := "First_Name,Last_Name,Email,Role\n" +
+			"Ana,Souza,ana@example.com,\n" +
+			"Joao,Santos,MARIA@example.com,professor\n" +
+			"Ana,Other,ana@example.com,student\n" +
+			",Lima,lima@example.com,janitor\n"
+		result, err := ImportUsers(db, strings.NewReader(csv), true)
+		assert.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 4, result.Rows)
+		assert.Equal(t, 0, result.Created)
+		assert.Equal(t, []ImportRowError{
+			{Row: 3, Field: "email", Message: "Email already exists"},
+			{Row: 4, Field: "email", Message: "Duplicate email, already on row 2"},
+			{Row: 5, Field: "first_name", Message: "First name is required"},
+			{Row: 5, Field: "role", Message: "Invalid role"},
+		}, result.Errors)
+	})
+
+	t.Run("Nothing Created When A Row Is Invalid", func(t *testing.T) {
+		result, err := ImportUsers(db, strings.NewReader("first_name,last_name,email\nAna,Souza,ana@example.com\nJoao,Santos,maria@example.com\n"), false)
+		assert.NoError(t, err)
+		assert.Len(t, result.Errors, 1)
+		var count int64
+		db.Model(&User{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Creates Users", func(t *testing.T) {
+		csv := "\ufefffirst_name,last_name,email,role,password\n" +
+			"Ana,Souza,Ana@Example.com,,secret123\n" +
+			"\n" +
+			"Joao,Santos,joao@example.com,professor,\n"
+		result, err := ImportUsers(db, strings.NewReader(csv), false)
+		assert.NoError(t, err)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, 2, result.Created)
+		assert.Empty(t, result.Users[0].TemporaryPassword)
+		assert.NotEmpty(t, result.Users[1].TemporaryPassword)
+
+		var ana User
+		db.Where("email = ?", "ana@example.com").First(&ana)
+		assert.Equal(t, RoleStudent, ana.Role)
+		assert.True(t, CheckPasswordHash("secret123", ana.Password))
+
+		var joao User
+		db.First(&joao, result.Users[1].ID)
+		assert.Equal(t, RoleProfessor, joao.Role)
+		assert.Equal(t, RoleProfessor, joao.RequestedRole)
+		assert.True(t, CheckPasswordHash(result.Users[1].TemporaryPassword, joao.Password))
+	})
+}
+
+func TestImportEnrollments(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	ana := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&ana)
+	joao := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&joao)
+
+	subject := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+	section := Section{SubjectID: subject.ID, SemesterID: semester.ID, Code: "A"}
+	db.Create(&section)
+	db.Create(&StudentEnrollment{StudentID: ana.ID, SubjectID: subject.ID, SemesterID: semester.ID})
+
+	t.Run("Dry Run Reports Row Errors", func(t *testing.T) {
+		csv := "email,subject_code,semester,section\n" +
+			"ana@example.com,MAT101,2024.1,\n" +
+			"joao@example.com,FIS999,2024.1,\n" +
+			"joao@example.com,MAT101,2031.2,\n" +
+			"maria@example.com,MAT101,2024.1,\n" +
+			"nobody@example.com,MAT101,2024.1,\n" +
+			"joao@example.com,MAT101,2024.1,Z\n" +
+			"joao@example.com,mat101,2024.1,a\n" +
+			"joao@example.com,MAT101,2024.1,\n"
+		result, err := ImportEnrollments(db, strings.NewReader(csv), true)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, result.Rows)
+		assert.Equal(t, []ImportRowError{
+			{Row: 2, Message: "Student is already enrolled in this subject and semester"},
+			{Row: 3, Field: "subject_code", Message: `Unknown subject code "FIS999"`},
+			{Row: 4, Field: "semester", Message: `Unknown semester "2031.2"`},
+			{Row: 5, Field: "email", Message: "User is not a student"},
+			{Row: 6, Field: "email", Message: "Unknown student"},
+			{Row: 7, Field: "section", Message: `Unknown section "Z" for MAT101 in 2024.1`},
+			{Row: 9, Message: "Duplicate enrollment, already on row 8"},
+		}, result.Errors)
+
+		var count int64
+		db.Model(&StudentEnrollment{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Creates Enrollments", func(t *testing.T) {
+		other := Semester{Name: "2024.2", Year: 2024, Period: 2, StartDate: time.Now().AddDate(0, 6, 0), EndDate: time.Now().AddDate(0, 10, 0)}
+		db.Create(&other)
+
+		csv := "email,subject_code,semester,section\njoao@example.com,MAT101,2024.1,A\nana@example.com,MAT101,2024.2,\n"
+		result, err := ImportEnrollments(db, strings.NewReader(csv), false)
+		assert.NoError(t, err)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, 2, result.Created)
+
+		var enrollment StudentEnrollment
+		db.Where("student_id = ?", joao.ID).First(&enrollment)
+		assert.Equal(t, section.ID, enrollment.SectionID)
+		var unsectioned StudentEnrollment
+		assert.NoError(t, db.Where("student_id = ? AND semester_id = ?", ana.ID, other.ID).First(&unsectioned).Error)
+		assert.Equal(t, uint(0), unsectioned.SectionID)
+	})
+}
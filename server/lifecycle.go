@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Survey lifecycle statuses, set explicitly through transitions. They are independent of
+// the window status computed from the survey dates: a survey takes answers only while
+// its status is open and its window is open.
+const (
+	SurveyStatusDraft     = "draft"     // being written, hidden from students
+	SurveyStatusPublished = "published" // visible to students, not taking answers yet
+	SurveyStatusOpen      = "open"      // taking answers
+	SurveyStatusClosed    = "closed"    // no longer taking answers
+	SurveyStatusArchived  = "archived"  // hidden everywhere until restored
+)
+
+// ErrCodeQuestionLocked is returned when changing or deleting questions of a survey that has responses
+const ErrCodeQuestionLocked = "question_locked"
+
+var (
+	// ErrInvalidSurveyStatus is returned for a status other than the lifecycle statuses
+	ErrInvalidSurveyStatus = errors.New("Status must be draft, published, open, closed or archived")
+	// ErrSurveyHasNoQuestions is returned when publishing or opening a survey without questions
+	ErrSurveyHasNoQuestions = errors.New("Add at least one question before publishing the survey")
+	// ErrQuestionLocked is returned when a change would make the answers already given meaningless
+	ErrQuestionLocked = errors.New("The survey has responses; question text, type, options, staff and whether it is required can no longer change, questions cannot be deleted and new questions must be optional")
+)
+
+// SurveyTransitionError is returned when a survey cannot move from its status to another
+type SurveyTransitionError struct {
+	From, To string
+}
+
+func (e *SurveyTransitionError) Error() string {
+	return fmt.Sprintf("Cannot change survey status from %s to %s", e.From, e.To)
+}
+
+// surveyTransitions lists the statuses each status can move to. Archived surveys come
+// back through RestoreSurvey.
+var surveyTransitions = map[string][]string{
+	SurveyStatusDraft:     {SurveyStatusPublished, SurveyStatusOpen, SurveyStatusArchived},
+	SurveyStatusPublished: {SurveyStatusDraft, SurveyStatusOpen, SurveyStatusArchived},
+	SurveyStatusOpen:      {SurveyStatusClosed, SurveyStatusArchived},
+	SurveyStatusClosed:    {SurveyStatusOpen, SurveyStatusArchived},
+}
+
+// studentSurveyStatuses are the statuses of the surveys students can see
+var studentSurveyStatuses = []string{SurveyStatusPublished, SurveyStatusOpen, SurveyStatusClosed}
+
+func isSurveyStatus(status string) bool {
+	_, ok := surveyTransitions[status]
+	return ok || status == SurveyStatusArchived
+}
+
+// CanTransition reports whether a survey can move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range surveyTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionSurvey moves a survey to another status. Surveys need questions to leave
+// draft, and moving to archived archives the survey with its questions and responses.
+func TransitionSurvey(db *gorm.DB, survey *Survey, to string, now time.Time) error {
+	if !isSurveyStatus(to) {
+		return ErrInvalidSurveyStatus
+	}
+	if !CanTransition(survey.Status, to) {
+		return &SurveyTransitionError{From: survey.Status, To: to}
+	}
+	if to == SurveyStatusArchived {
+		return ArchiveSurvey(db, survey, now)
+	}
+
+	if survey.Status == SurveyStatusDraft {
+		var questions int64
+		if err := db.Model(&Question{}).Where("survey_id = ?", survey.ID).Count(&questions).Error; err != nil {
+			return err
+		}
+		if questions == 0 {
+			return ErrSurveyHasNoQuestions
+		}
+	}
+	if err := db.Model(survey).Update("status", to).Error; err != nil {
+		return err
+	}
+	survey.Status = to
+	return nil
+}
+
+// CheckSurveyStatus returns an error when the survey status doesn't let students answer
+func CheckSurveyStatus(survey Survey) *SurveyWindowError {
+	switch survey.Status {
+	case SurveyStatusOpen:
+		return nil
+	case SurveyStatusClosed, SurveyStatusArchived:
+		return &SurveyWindowError{Code: ErrCodeSurveyClosed, Message: "Survey is closed"}
+	default:
+		return &SurveyWindowError{Code: ErrCodeSurveyNotOpen, Message: "Survey is not open yet"}
+	}
+}
+
+// SurveyHasResponses reports whether anyone answered the survey: it has responses, deleted
+// ones included, or anonymous participation records. Answered surveys lock their questions,
+// anonymity and embargo, and can only be archived.
+func SurveyHasResponses(db *gorm.DB, surveyID uint) (bool, error) {
+	var count int64
+	if err := db.Unscoped().Model(&Response{}).Where("survey_id = ?", surveyID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Model(&SurveyParticipation{}).Where("survey_id = ?", surveyID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CheckQuestionChange returns ErrQuestionLocked when the survey has responses and the
+// change touches what the answers mean: the text, the type, the options, the staff member
+// or whether the question is required
+func CheckQuestionChange(db *gorm.DB, original, updated Question) error {
+	if original.Text == updated.Text && original.Type == updated.Type && original.Options == updated.Options &&
+		original.StaffID == updated.StaffID && original.Required == updated.Required {
+		return nil
+	}
+	answered, err := SurveyHasResponses(db, original.SurveyID)
+	if err != nil {
+		return err
+	}
+	if answered {
+		return ErrQuestionLocked
+	}
+	return nil
+}
+
+// CheckQuestionAdd returns ErrQuestionLocked when adding a required question to a survey
+// that has responses, since the students who already answered would have skipped it
+func CheckQuestionAdd(db *gorm.DB, question Question) error {
+	if !question.Required {
+		return nil
+	}
+	answered, err := SurveyHasResponses(db, question.SurveyID)
+	if err != nil {
+		return err
+	}
+	if answered {
+		return ErrQuestionLocked
+	}
+	return nil
+}
+
+// CheckQuestionDelete returns ErrQuestionLocked when the survey has responses
+func CheckQuestionDelete(db *gorm.DB, question Question) error {
+	answered, err := SurveyHasResponses(db, question.SurveyID)
+	if err != nil {
+		return err
+	}
+	if answered {
+		return ErrQuestionLocked
+	}
+	return nil
+}
+
+// needsSurveyStatusBackfill reports whether the surveys table predates statuses, to be
+// checked before migrating
+func needsSurveyStatusBackfill(db *gorm.DB) bool {
+	migrator := db.Migrator()
+	return migrator.HasTable(&Survey{}) && !migrator.HasColumn(&Survey{}, "status")
+}
+
+// backfillSurveyStatus opens the surveys created before statuses existed, which were
+// governed by their dates and active flag alone, and archives the soft-deleted ones
+func backfillSurveyStatus(db *gorm.DB) error {
+	if err := db.Unscoped().Model(&Survey{}).Where("deleted_at IS NULL").Update("status", SurveyStatusOpen).Error; err != nil {
+		return err
+	}
+	return db.Unscoped().Model(&Survey{}).Where("deleted_at IS NOT NULL").Update("status", SurveyStatusArchived).Error
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSurveyLifecycle(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	student := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	subject := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+
+	newSurvey := func(title string) Survey {
+		survey := Survey{Title: title, SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+		db.Create(&survey)
+		return survey
+	}
+
+	t.Run("New Surveys Are Drafts", func(t *testing.T) {
+		survey := newSurvey("Draft")
+		var loaded Survey
+		db.First(&loaded, survey.ID)
+		assert.Equal(t, SurveyStatusDraft, loaded.Status)
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		assert.True(t, CanTransition(SurveyStatusDraft, SurveyStatusPublished))
+		assert.True(t, CanTransition(SurveyStatusPublished, SurveyStatusOpen))
+		assert.True(t, CanTransition(SurveyStatusOpen, SurveyStatusClosed))
+		assert.True(t, CanTransition(SurveyStatusClosed, SurveyStatusOpen))
+		assert.False(t, CanTransition(SurveyStatusOpen, SurveyStatusDraft))
+		assert.False(t, CanTransition(SurveyStatusClosed, SurveyStatusPublished))
+		assert.False(t, CanTransition(SurveyStatusArchived, SurveyStatusOpen))
+	})
+
+	t.Run("Drafts Need Questions", func(t *testing.T) {
+		survey := newSurvey("Empty")
+		assert.ErrorIs(t, TransitionSurvey(db, &survey, SurveyStatusPublished, time.Now()), ErrSurveyHasNoQuestions)
+
+		db.Create(&Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1})
+		assert.NoError(t, TransitionSurvey(db, &survey, SurveyStatusPublished, time.Now()))
+		assert.NoError(t, TransitionSurvey(db, &survey, SurveyStatusOpen, time.Now()))
+
+		var loaded Survey
+		db.First(&loaded, survey.ID)
+		assert.Equal(t, SurveyStatusOpen, loaded.Status)
+	})
+
+	t.Run("Invalid Transitions Are Rejected", func(t *testing.T) {
+		survey := newSurvey("Invalid")
+		assert.ErrorIs(t, TransitionSurvey(db, &survey, "finished", time.Now()), ErrInvalidSurveyStatus)
+
+		var transitionErr *SurveyTransitionError
+		assert.ErrorAs(t, TransitionSurvey(db, &survey, SurveyStatusClosed, time.Now()), &transitionErr)
+		assert.Equal(t, SurveyStatusDraft, transitionErr.From)
+	})
+
+	t.Run("Archiving Through A Transition", func(t *testing.T) {
+		survey := newSurvey("Archived")
+		assert.NoError(t, TransitionSurvey(db, &survey, SurveyStatusArchived, time.Now()))
+		assert.Error(t, db.First(&Survey{}, survey.ID).Error)
+
+		var archived Survey
+		db.Unscoped().First(&archived, survey.ID)
+		assert.Equal(t, SurveyStatusArchived, archived.Status)
+
+		// Restored surveys without responses go back to draft
+		assert.NoError(t, RestoreSurvey(db, &archived))
+		assert.Equal(t, SurveyStatusDraft, archived.Status)
+	})
+
+	t.Run("Only Open Surveys Take Answers", func(t *testing.T) {
+		assert.Nil(t, CheckSurveyStatus(Survey{Status: SurveyStatusOpen}))
+		assert.Equal(t, ErrCodeSurveyNotOpen, CheckSurveyStatus(Survey{Status: SurveyStatusDraft}).Code)
+		assert.Equal(t, ErrCodeSurveyNotOpen, CheckSurveyStatus(Survey{Status: SurveyStatusPublished}).Code)
+		assert.Equal(t, ErrCodeSurveyClosed, CheckSurveyStatus(Survey{Status: SurveyStatusClosed}).Code)
+	})
+
+	t.Run("Questions Lock After The First Response", func(t *testing.T) {
+		survey := newSurvey("Answered")
+		rating := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&rating)
+
+		changed := rating
+		changed.Type = QuestionTypeNPS
+		assert.NoError(t, CheckQuestionChange(db, rating, changed))
+		assert.NoError(t, CheckQuestionDelete(db, rating))
+
+		db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: rating.ID, Answer: "4"})
+		assert.ErrorIs(t, CheckQuestionChange(db, rating, changed), ErrQuestionLocked)
+		assert.ErrorIs(t, CheckQuestionDelete(db, rating), ErrQuestionLocked)
+
+		// Rewording or requiring the question would change what the answers mean
+		reworded := rating
+		reworded.Text = "Clarity of the lectures"
+		assert.ErrorIs(t, CheckQuestionChange(db, rating, reworded), ErrQuestionLocked)
+		required := rating
+		required.Required = true
+		assert.ErrorIs(t, CheckQuestionChange(db, rating, required), ErrQuestionLocked)
+
+		// Reordering stays allowed
+		reordered := rating
+		reordered.Order = 2
+		assert.NoError(t, CheckQuestionChange(db, rating, reordered))
+
+		// Only optional questions can be added
+		assert.ErrorIs(t, CheckQuestionAdd(db, Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true}), ErrQuestionLocked)
+		assert.NoError(t, CheckQuestionAdd(db, Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Anything else?"}))
+	})
+
+	t.Run("Anonymous Participation Locks Questions", func(t *testing.T) {
+		survey := newSurvey("Anonymous")
+		rating := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&rating)
+		assert.NoError(t, CheckQuestionAdd(db, Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true}))
+
+		db.Create(&SurveyParticipation{SurveyID: survey.ID, StudentID: student.ID})
+		assert.ErrorIs(t, CheckQuestionDelete(db, rating), ErrQuestionLocked)
+		assert.ErrorIs(t, CheckQuestionAdd(db, Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true}), ErrQuestionLocked)
+	})
+
+	t.Run("Existing Surveys Are Backfilled", func(t *testing.T) {
+		assert.False(t, needsSurveyStatusBackfill(db))
+
+		legacy, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		assert.NoError(t, err)
+		// A surveys table created before lifecycle statuses existed
+		assert.NoError(t, legacy.Exec(`CREATE TABLE surveys (id integer PRIMARY KEY AUTOINCREMENT, title text NOT NULL,
+			description text, subject_id integer NOT NULL, semester_id integer NOT NULL, professor_id integer NOT NULL,
+			is_active numeric DEFAULT true, created_at datetime, updated_at datetime, deleted_at datetime)`).Error)
+		legacy.Exec("INSERT INTO surveys (title, subject_id, semester_id, professor_id) VALUES ('Live', 1, 1, 1)")
+		legacy.Exec("INSERT INTO surveys (title, subject_id, semester_id, professor_id, deleted_at) VALUES ('Gone', 1, 1, 1, CURRENT_TIMESTAMP)")
+		assert.True(t, needsSurveyStatusBackfill(legacy))
+
+		// Migrating adds the column with its draft default
+		assert.NoError(t, legacy.Exec("ALTER TABLE surveys ADD COLUMN status text NOT NULL DEFAULT 'draft'").Error)
+		assert.NoError(t, backfillSurveyStatus(legacy))
+
+		var live, gone Survey
+		legacy.Where("title = ?", "Live").First(&live)
+		legacy.Unscoped().Where("title = ?", "Gone").First(&gone)
+		assert.Equal(t, SurveyStatusOpen, live.Status)
+		assert.Equal(t, SurveyStatusArchived, gone.Status)
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mail is a plain text email
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. MAIL_TRANSPORT picks the implementation: smtp, file or log.
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// SMTPMailer sends emails through an SMTP server, authenticating when a username is set
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the mail with net/smtp, which upgrades to TLS when the server offers it
+func (m SMTPMailer) Send(mail Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{mail.To}, formatMail(m.From, mail, time.Now()))
+}
+
+// WriterMailer writes emails to a writer instead of sending them, for local development
+// and tests. Use NewFileMailer to append them to a file or NewLogMailer to log them.
+type WriterMailer struct {
+	From string
+	mu   sync.Mutex
+	out  io.Writer
+}
+
+// NewFileMailer returns a mailer that appends every email to the file at path
+func NewFileMailer(path, from string) (*WriterMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterMailer{From: from, out: f}, nil
+}
+
+// NewLogMailer returns a mailer that writes every email to the standard logger
+func NewLogMailer(from string) *WriterMailer {
+	return &WriterMailer{From: from, out: log.Writer()}
+}
+
+// Send writes the mail followed by a blank line
+func (m *WriterMailer) Send(mail Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.out, "%s\r\n", formatMail(m.From, mail, time.Now()))
+	return err
+}
+
+// headerValue strips line breaks that would let a value add headers of its own
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// formatMail renders a mail as an RFC 5322 message
+func formatMail(from string, mail Mail, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue.Replace(mail.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue.Replace(mail.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// newMailerFromEnv builds the mailer configured by MAIL_TRANSPORT (log by default),
+// MAIL_FROM, MAIL_FILE and the SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD settings
+func newMailerFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch transport := os.Getenv("MAIL_TRANSPORT"); transport {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			path = "mail.log"
+		}
+		mailer, err := NewFileMailer(path, from)
+		if err != nil {
+			log.Printf("⚠️  Cannot open MAIL_FILE %q, logging emails instead: %v", path, err)
+			return NewLogMailer(from)
+		}
+		return mailer
+	case "", "log":
+		return NewLogMailer(from)
+	default:
+		log.Printf("⚠️  Invalid MAIL_TRANSPORT %q, logging emails instead", transport)
+		return NewLogMailer(from)
+	}
+}
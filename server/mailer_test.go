@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMail(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Headers And Body", func(t *testing.T) {
+		message := string(formatMail("no-reply@example.com", Mail{To: "ana@example.com", Subject: "Welcome", Body: "Line one\nLine two"}, now))
+
+		headers, body, found := strings.Cut(message, "\r\n\r\n")
+		assert.True(t, found)
+		assert.Equal(t, []string{
+			"From: no-reply@example.com",
+			"To: ana@example.com",
+			"Subject: Welcome",
+			"Date: Wed, 01 May 2024 10:00:00 +0000",
+			"MIME-Version: 1.0",
+			"Content-Type: text/plain; charset=UTF-8",
+		}, strings.Split(headers, "\r\n"))
+		// Bodies use CRLF line endings
+		assert.Equal(t, "Line one\r\nLine two\r\n", body)
+	})
+
+	t.Run("Accented Subjects Are Encoded", func(t *testing.T) {
+		message := string(formatMail("no-reply@example.com", Mail{To: "ana@example.com", Subject: "Redefinição de senha", Body: "Olá"}, now))
+		assert.Contains(t, message, "Subject: =?utf-8?q?")
+		assert.NotContains(t, message, "Redefinição")
+		// The body is sent as UTF-8
+		assert.Contains(t, message, "\r\n\r\nOlá\r\n")
+	})
+
+	t.Run("Line Breaks Cannot Add Headers", func(t *testing.T) {
+		message := string(formatMail("no-reply@example.com\nBcc: eve@example.com", Mail{
+			To:      "ana@example.com\r\nBcc: eve@example.com",
+			Subject: "Hi\r\nBcc: eve@example.com",
+		}, now))
+		headers, _, _ := strings.Cut(message, "\r\n\r\n")
+		for _, line := range strings.Split(headers, "\r\n") {
+			assert.False(t, strings.HasPrefix(line, "Bcc:"), line)
+		}
+	})
+}
+
+func TestWriterMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	fileMailer, err := NewFileMailer(path, "no-reply@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, fileMailer.Send(Mail{To: "ana@example.com", Subject: "First", Body: "One"}))
+	assert.NoError(t, fileMailer.Send(Mail{To: "bia@example.com", Subject: "Second", Body: "Two"}))
+
+	// Emails are appended, each followed by a blank line
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(contents), "From: no-reply@example.com\r\n"))
+	assert.Contains(t, string(contents), "One\r\n\r\nFrom: no-reply@example.com\r\nTo: bia@example.com\r\n")
+	assert.True(t, strings.HasSuffix(string(contents), "Two\r\n\r\n"))
+}
+
+func TestNewMailerFromEnv(t *testing.T) {
+	setMailEnv := func(t *testing.T, transport string) {
+		t.Setenv("MAIL_TRANSPORT", transport)
+		t.Setenv("MAIL_FROM", "")
+		t.Setenv("MAIL_FILE", filepath.Join(t.TempDir(), "mail.log"))
+		t.Setenv("SMTP_HOST", "")
+		t.Setenv("SMTP_PORT", "")
+		t.Setenv("SMTP_USERNAME", "")
+		t.Setenv("SMTP_PASSWORD", "")
+	}
+	// assertLogMailer checks that emails go to the standard logger
+	assertLogMailer := func(t *testing.T, mailer Mailer) {
+		writerMailer, ok := mailer.(*WriterMailer)
+		if assert.True(t, ok) {
+			assert.Equal(t, log.Writer(), writerMailer.out)
+		}
+	}
+
+	t.Run("Log By Default", func(t *testing.T) {
+		setMailEnv(t, "")
+		mailer := newMailerFromEnv()
+		assertLogMailer(t, mailer)
+		assert.Equal(t, "no-reply@localhost", mailer.(*WriterMailer).From)
+
+		setMailEnv(t, "log")
+		assertLogMailer(t, newMailerFromEnv())
+	})
+
+	t.Run("File", func(t *testing.T) {
+		setMailEnv(t, "file")
+		t.Setenv("MAIL_FROM", "avaliacao@example.com")
+		mailer := newMailerFromEnv()
+		writerMailer, ok := mailer.(*WriterMailer)
+		assert.True(t, ok)
+		assert.NotEqual(t, log.Writer(), writerMailer.out)
+		assert.NoError(t, mailer.Send(Mail{To: "ana@example.com", Subject: "Hi", Body: "Hello"}))
+
+		contents, err := os.ReadFile(os.Getenv("MAIL_FILE"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(contents), "From: avaliacao@example.com\r\n")
+		assert.Contains(t, string(contents), "To: ana@example.com\r\n")
+	})
+
+	t.Run("Unwritable File Falls Back To Log", func(t *testing.T) {
+		setMailEnv(t, "file")
+		t.Setenv("MAIL_FILE", filepath.Join(t.TempDir(), "missing", "mail.log"))
+		assertLogMailer(t, newMailerFromEnv())
+	})
+
+	t.Run("SMTP", func(t *testing.T) {
+		setMailEnv(t, "smtp")
+		t.Setenv("SMTP_HOST", "smtp.example.com")
+		t.Setenv("SMTP_USERNAME", "mailer")
+		t.Setenv("SMTP_PASSWORD", "secret")
+		assert.Equal(t, SMTPMailer{Host: "smtp.example.com", Port: "587", Username: "mailer", Password: "secret", From: "no-reply@localhost"}, newMailerFromEnv())
+
+		t.Setenv("SMTP_PORT", "2525")
+		assert.Equal(t, "2525", newMailerFromEnv().(SMTPMailer).Port)
+	})
+
+	t.Run("Invalid Transport Falls Back To Log", func(t *testing.T) {
+		setMailEnv(t, "carrier-pigeon")
+		assertLogMailer(t, newMailerFromEnv())
+	})
+}
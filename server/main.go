@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -20,11 +26,20 @@ import (
 
 // User roles constants
 const (
-	RoleStudent   = "student"
-	RoleProfessor = "professor"
-	RoleAdmin     = "admin"
+	RoleStudent     = "student"
+	RoleProfessor   = "professor"
+	RoleCoordinator = "coordinator" // professor who also sees aggregates of their department or program
+	RoleAdmin       = "admin"
 )
 
+// allUserRoles lists every user role, for endpoints any logged-in user may call
+var allUserRoles = []string{RoleStudent, RoleProfessor, RoleCoordinator, RoleAdmin}
+
+// isRole reports whether role is one of the user roles
+func isRole(role string) bool {
+	return role == RoleStudent || role == RoleProfessor || role == RoleCoordinator || role == RoleAdmin
+}
+
 // Question types constants
 const (
 	QuestionTypeNPS      = "nps"
@@ -35,8 +50,9 @@ const (
 
 // JWT Claims structure
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid,omitempty"` // session the token was issued for, see sessions.go
 	jwt.RegisteredClaims
 }
 
@@ -51,12 +67,24 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// getJWTSecret returns the key tokens are signed with
+func getJWTSecret() []byte {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		jwtSecret = "fallback_secret_key_change_in_production"
+	}
+	return []byte(jwtSecret)
+}
+
 // JWT token utilities
-func GenerateJWT(userID uint, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+
+// GenerateSessionJWT issues an access token that stops working when its session is revoked
+func GenerateSessionJWT(userID uint, role string, sessionID uint) (string, error) {
+	expirationTime := time.Now().Add(accessTokenTTL)
 	claims := &Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -64,22 +92,13 @@ func GenerateJWT(userID uint, role string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "fallback_secret_key_change_in_production"
-	}
-	return token.SignedString([]byte(jwtSecret))
+	return token.SignedString(getJWTSecret())
 }
 
 func ValidateJWT(tokenString string) (*Claims, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "fallback_secret_key_change_in_production"
-	}
-
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
+		return getJWTSecret(), nil
 	})
 
 	if err != nil || !token.Valid {
@@ -91,42 +110,148 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 
 // User model with proper role handling
 type User struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	FirstName     string    `json:"first_name" gorm:"not null"`
-	LastName      string    `json:"last_name" gorm:"not null"`
-	Email         string    `json:"email" gorm:"uniqueIndex;not null"`
-	Password      string    `json:"password" gorm:"not null"`
-	Role          string    `json:"role" gorm:"not null;check:role IN ('student','professor','admin')"`
-	RequestedRole string    `json:"requested_role" gorm:"not null;default:'student'"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	FirstName       string     `json:"first_name" gorm:"not null"`
+	LastName        string     `json:"last_name" gorm:"not null"`
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`
+	Password        string     `json:"password" gorm:"not null"`
+	Role            string     `json:"role" gorm:"not null;check:role IN ('student','professor','coordinator','admin')"`
+	RequestedRole   string     `json:"requested_role" gorm:"not null;default:'student'"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty" gorm:"index"`             // set when an admin disables the account
+	EmailUnverified bool       `json:"email_unverified" gorm:"not null;default:false"` // set on self-registration until the email is confirmed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Session (a login, kept server-side so its refresh token can be rotated and revoked)
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	User              User       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	TokenHash         string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the current refresh token
+	PreviousTokenHash string     `json:"-" gorm:"index"`                // refresh token replaced last, to detect reuse
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// PasswordReset (single-use token emailed by POST /password/forgot)
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// EmailVerification (single-use token emailed on registration to confirm the address)
+type EmailVerification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AllowedDomain (email domain self-registration is restricted to, e.g. usp.br; none means any)
+type AllowedDomain struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Domain    string    `json:"domain" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserIdentity (a user's account at an SSO identity provider)
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_identity_user_provider"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_user_identity_user_provider;uniqueIndex:idx_user_identity_subject"` // oidc or saml
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_user_identity_subject"`                                              // the provider's ID for the user
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoginTicket (single-use hand-off from an SSO callback to the client, see sso.go)
+type LoginTicket struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 // Subject (course information)
 type Subject struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"not null"`
-	Code        string    `json:"code" gorm:"uniqueIndex;not null"`
-	Description string    `json:"description"`
-	ProfessorID uint      `json:"professor_id" gorm:"not null"`
-	Professor   User      `json:"professor" gorm:"foreignKey:ProfessorID;references:ID"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	Name         string      `json:"name" gorm:"not null"`
+	Code         string      `json:"code" gorm:"uniqueIndex;not null"`
+	Description  string      `json:"description"`
+	ProfessorID  uint        `json:"professor_id" gorm:"not null"`
+	Professor    User        `json:"professor" gorm:"foreignKey:ProfessorID;references:ID"`
+	DepartmentID uint        `json:"department_id,omitempty" gorm:"default:null;index"`
+	Department   *Department `json:"department,omitempty" gorm:"foreignKey:DepartmentID;references:ID"`
+	ProgramID    uint        `json:"program_id,omitempty" gorm:"default:null;index"`
+	Program      *Program    `json:"program,omitempty" gorm:"foreignKey:ProgramID;references:ID"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
 }
 
-// Semester (academic periods)
-type Semester struct {
+// Department (academic unit offering subjects)
+type Department struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"` // e.g., "2024.1", "2024.2"
-	Year      int       `json:"year" gorm:"not null"`
-	Period    int       `json:"period" gorm:"not null"` // 1 or 2
-	StartDate time.Time `json:"start_date" gorm:"not null"`
-	EndDate   time.Time `json:"end_date" gorm:"not null"`
-	IsActive  bool      `json:"is_active" gorm:"default:false"`
+	Name      string    `json:"name" gorm:"not null"`
+	Code      string    `json:"code" gorm:"uniqueIndex;not null"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Program (degree program within a department, e.g. BCC)
+type Program struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Name         string     `json:"name" gorm:"not null"`
+	Code         string     `json:"code" gorm:"uniqueIndex;not null"`
+	DepartmentID uint       `json:"department_id" gorm:"not null"`
+	Department   Department `json:"department" gorm:"foreignKey:DepartmentID;references:ID"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CoordinatorAssignment (unit whose subjects a coordinator can follow), either a department or a program
+type CoordinatorAssignment struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	UserID       uint        `json:"user_id" gorm:"not null;index"`
+	User         User        `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	DepartmentID uint        `json:"department_id,omitempty" gorm:"default:null"`
+	Department   *Department `json:"department,omitempty" gorm:"foreignKey:DepartmentID;references:ID"`
+	ProgramID    uint        `json:"program_id,omitempty" gorm:"default:null"`
+	Program      *Program    `json:"program,omitempty" gorm:"foreignKey:ProgramID;references:ID"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// Semester (academic periods)
+type Semester struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Name            string    `json:"name" gorm:"not null"` // e.g., "2024.1", "2024.2"
+	Year            int       `json:"year" gorm:"not null"`
+	Period          int       `json:"period" gorm:"not null"` // 1 or 2
+	StartDate       time.Time `json:"start_date" gorm:"not null"`
+	EndDate         time.Time `json:"end_date" gorm:"not null"`
+	IsActive        bool      `json:"is_active" gorm:"default:false"`
+	GradesFinalized bool      `json:"grades_finalized" gorm:"default:false"` // lifts the until_grades_finalized results embargo
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
 // StudentEnrollment (student-subject-semester relationships)
 type StudentEnrollment struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
@@ -136,56 +261,184 @@ type StudentEnrollment struct {
 	Subject    Subject   `json:"subject" gorm:"foreignKey:SubjectID;references:ID"`
 	SemesterID uint      `json:"semester_id" gorm:"not null"`
 	Semester   Semester  `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
+	SectionID  uint      `json:"section_id,omitempty" gorm:"default:null;index"` // section the student attends, if the subject has sections
+	Section    *Section  `json:"section,omitempty" gorm:"foreignKey:SectionID;references:ID"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Section (class/turma of a subject in a semester, with its own professor and schedule)
+type Section struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	SubjectID   uint      `json:"subject_id" gorm:"not null;uniqueIndex:idx_sections_offering_code"`
+	Subject     Subject   `json:"subject" gorm:"foreignKey:SubjectID;references:ID"`
+	SemesterID  uint      `json:"semester_id" gorm:"not null;uniqueIndex:idx_sections_offering_code"`
+	Semester    Semester  `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
+	Code        string    `json:"code" gorm:"not null;uniqueIndex:idx_sections_offering_code"` // e.g. "A", "B"
+	ProfessorID uint      `json:"professor_id,omitempty" gorm:"default:null"`                  // professor teaching the section
+	Professor   *User     `json:"professor,omitempty" gorm:"foreignKey:ProfessorID;references:ID"`
+	Schedule    string    `json:"schedule"` // e.g. "Mon/Wed 08:00-10:00"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StaffAssignment (teaching staff of a subject offering, i.e. a subject in a semester)
+type StaffAssignment struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SubjectID  uint      `json:"subject_id" gorm:"not null;uniqueIndex:idx_staff_offering_user"`
+	Subject    Subject   `json:"subject" gorm:"foreignKey:SubjectID;references:ID"`
+	SemesterID uint      `json:"semester_id" gorm:"not null;uniqueIndex:idx_staff_offering_user"`
+	Semester   Semester  `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_staff_offering_user"`
+	User       User      `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	Role       string    `json:"role" gorm:"not null;check:role IN ('lead','co_professor','ta')"` // TAs get read-only access to the offering's surveys
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // Survey (feedback forms created by professors)
 type Survey struct {
-	ID          uint       `json:"id" gorm:"primaryKey"`
-	Title       string     `json:"title" gorm:"not null"`
-	Description string     `json:"description"`
-	SubjectID   uint       `json:"subject_id" gorm:"not null"`
-	Subject     Subject    `json:"subject" gorm:"foreignKey:SubjectID;references:ID"`
-	SemesterID  uint       `json:"semester_id" gorm:"not null"`
-	Semester    Semester   `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
-	ProfessorID uint       `json:"professor_id" gorm:"not null"`
-	Professor   User       `json:"professor" gorm:"foreignKey:ProfessorID;references:ID"`
-	IsActive    bool       `json:"is_active" gorm:"default:true"`
-	OpenDate    time.Time  `json:"open_date"`
-	CloseDate   time.Time  `json:"close_date"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Questions   []Question `json:"questions" gorm:"foreignKey:SurveyID"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Title          string         `json:"title" gorm:"not null"`
+	Description    string         `json:"description"`
+	SubjectID      uint           `json:"subject_id" gorm:"not null;uniqueIndex:idx_surveys_campaign_offering"`
+	Subject        Subject        `json:"subject" gorm:"foreignKey:SubjectID;references:ID"`
+	SemesterID     uint           `json:"semester_id" gorm:"not null"`
+	Semester       Semester       `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
+	SectionID      uint           `json:"section_id" gorm:"not null;default:0;uniqueIndex:idx_surveys_campaign_offering"` // 0 targets every section; never NULL so campaigns survey a subject once
+	Section        *Section       `json:"section,omitempty" gorm:"foreignKey:SectionID;references:ID;constraint:-"`
+	ProfessorID    uint           `json:"professor_id" gorm:"not null"`
+	Professor      User           `json:"professor" gorm:"foreignKey:ProfessorID;references:ID"`
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	Status         string         `json:"status" gorm:"not null;default:'draft';check:status IN ('draft','published','open','closed','archived')"` // lifecycle status, see lifecycle.go
+	Anonymous      bool           `json:"anonymous" gorm:"default:false"`                                                                          // answers are stored without student identity
+	ResultsEmbargo string         `json:"results_embargo"`                                                                                         // none, until_close or until_grades_finalized; empty follows RESULTS_EMBARGO
+	CampaignID     uint           `json:"campaign_id,omitempty" gorm:"default:null;uniqueIndex:idx_surveys_campaign_offering"`
+	OpenDate       time.Time      `json:"open_date"`
+	CloseDate      time.Time      `json:"close_date"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"archived_at" gorm:"index"` // set while the survey is archived
+	Questions      []Question     `json:"questions" gorm:"foreignKey:SurveyID"`
+
+	// Computed fields, only filled in by endpoints that report them
+	ResponseRate *ResponseRate `json:"response_rate,omitempty" gorm:"-"`
+	WindowStatus string        `json:"window_status,omitempty" gorm:"-"`
+	Submitted    bool          `json:"submitted,omitempty" gorm:"-"`
+	Access       string        `json:"access,omitempty" gorm:"-"` // manage or read, for the staff listing their surveys
 }
 
 // Question (individual questions with types)
 type Question struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	SurveyID  uint      `json:"survey_id" gorm:"not null"`
-	Survey    Survey    `json:"survey" gorm:"foreignKey:SurveyID;references:ID"`
-	Type      string    `json:"type" gorm:"not null;check:type IN ('nps','free_text','rating','multiple_choice')"`
-	Text      string    `json:"text" gorm:"not null"`
-	Required  bool      `json:"required" gorm:"default:false"`
-	Order     int       `json:"order" gorm:"not null"`
-	Options   string    `json:"options"` // JSON string for multiple choice options
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	SurveyID         uint           `json:"survey_id" gorm:"not null"`
+	Survey           Survey         `json:"survey" gorm:"foreignKey:SurveyID;references:ID"`
+	Type             string         `json:"type" gorm:"not null;check:type IN ('nps','free_text','rating','multiple_choice')"`
+	Text             string         `json:"text" gorm:"not null"`
+	Required         bool           `json:"required" gorm:"default:false"`
+	Order            int            `json:"order" gorm:"not null"`
+	Options          string         `json:"options"`                                      // JSON string for multiple choice options
+	Key              string         `json:"key" gorm:"index"`                             // same key across templates and clones, used by trend reports
+	CampaignQuestion bool           `json:"campaign_question" gorm:"default:false"`       // shared by every survey of a campaign, read-only for professors
+	StaffID          uint           `json:"staff_id,omitempty" gorm:"default:null;index"` // staff member evaluated by the question, if any
+	Staff            *User          `json:"staff,omitempty" gorm:"foreignKey:StaffID;references:ID"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"index"` // set when the question is removed or its survey archived
 }
 
-// Response (student answers)
+// Label is the question text followed by the name of the staff member it evaluates,
+// when Staff is loaded
+func (q *Question) Label() string {
+	if q.Staff == nil {
+		return q.Text
+	}
+	return fmt.Sprintf("%s (%s %s)", q.Text, q.Staff.FirstName, q.Staff.LastName)
+}
+
+// OptionList decodes the JSON-encoded multiple choice options of the question.
+// Malformed or empty options yield an empty list.
+func (q *Question) OptionList() []string {
+	var options []string
+	if q.Options == "" {
+		return options
+	}
+	if err := json.Unmarshal([]byte(q.Options), &options); err != nil {
+		return nil
+	}
+	return options
+}
+
+// Response (student answers), at most one per student and question of a survey.
+// Answers to anonymous surveys have no student (NULL StudentID) and are only
+// grouped by a random SubmissionToken.
 type Response struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	SurveyID    uint      `json:"survey_id" gorm:"not null"`
-	Survey      Survey    `json:"survey" gorm:"foreignKey:SurveyID;references:ID"`
-	StudentID   uint      `json:"student_id" gorm:"not null"`
-	Student     User      `json:"student" gorm:"foreignKey:StudentID;references:ID"`
-	QuestionID  uint      `json:"question_id" gorm:"not null"`
-	Question    Question  `json:"question" gorm:"foreignKey:QuestionID;references:ID"`
-	Answer      string    `json:"answer" gorm:"not null"`
-	SubmittedAt time.Time `json:"submitted_at" gorm:"autoCreateTime"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	SurveyID        uint           `json:"survey_id" gorm:"not null;uniqueIndex:idx_responses_student_question"`
+	Survey          Survey         `json:"survey" gorm:"foreignKey:SurveyID;references:ID"`
+	StudentID       uint           `json:"student_id" gorm:"default:null;uniqueIndex:idx_responses_student_question"`
+	Student         User           `json:"student" gorm:"foreignKey:StudentID;references:ID"`
+	QuestionID      uint           `json:"question_id" gorm:"not null;uniqueIndex:idx_responses_student_question"`
+	Question        Question       `json:"question" gorm:"foreignKey:QuestionID;references:ID"`
+	Answer          string         `json:"answer" gorm:"not null"`
+	SubmissionToken string         `json:"-" gorm:"index"`
+	SubmittedAt     time.Time      `json:"submitted_at" gorm:"autoCreateTime"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"` // set when its question is removed or its survey archived
+}
+
+// SurveyParticipation records that a student submitted an anonymous survey, which
+// prevents double voting. It deliberately has no surrogate key or timestamps so it
+// cannot be lined up with the stored answers.
+type SurveyParticipation struct {
+	SurveyID  uint `json:"survey_id" gorm:"primaryKey;autoIncrement:false"`
+	StudentID uint `json:"student_id" gorm:"primaryKey;autoIncrement:false"`
+}
+
+// SurveyTemplate (reusable question sets for recurring surveys)
+type SurveyTemplate struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	Name        string             `json:"name" gorm:"not null"`
+	Description string             `json:"description"`
+	OwnerID     uint               `json:"owner_id" gorm:"not null"`
+	Owner       User               `json:"owner" gorm:"foreignKey:OwnerID;references:ID"`
+	Shared      bool               `json:"shared" gorm:"default:false"` // visible to every professor, set for admin templates
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Questions   []TemplateQuestion `json:"questions" gorm:"foreignKey:TemplateID"`
+}
+
+// TemplateQuestion (questions copied into every survey created from a template)
+type TemplateQuestion struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TemplateID uint      `json:"template_id" gorm:"not null;index"`
+	Type       string    `json:"type" gorm:"not null;check:type IN ('nps','free_text','rating','multiple_choice')"`
+	Text       string    `json:"text" gorm:"not null"`
+	Required   bool      `json:"required" gorm:"default:false"`
+	Order      int       `json:"order" gorm:"not null"`
+	Options    string    `json:"options"` // JSON string for multiple choice options
+	Key        string    `json:"key"`     // copied into the questions of surveys created from the template
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Campaign (institution-wide survey generated for every subject offered in a semester)
+type Campaign struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"not null"`
+	Description    string    `json:"description"`
+	SemesterID     uint      `json:"semester_id" gorm:"not null"`
+	Semester       Semester  `json:"semester" gorm:"foreignKey:SemesterID;references:ID"`
+	TemplateID     uint      `json:"template_id" gorm:"not null"` // question set copied into every survey
+	OpenDate       time.Time `json:"open_date"`
+	CloseDate      time.Time `json:"close_date"`
+	Anonymous      bool      `json:"anonymous" gorm:"default:false"`
+	ResultsEmbargo string    `json:"results_embargo"`
+	CreatedByID    uint      `json:"created_by_id" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Surveys        []Survey  `json:"surveys" gorm:"foreignKey:CampaignID"`
 }
 
 // AnonymousResponse is a DTO that excludes student identity for privacy
@@ -224,6 +477,16 @@ func ToAnonymousList(responses []Response) []AnonymousResponse {
 // Global database variable
 var db *gorm.DB
 
+// migrationModels lists every persisted model in dependency order
+func migrationModels() []interface{} {
+	return []interface{}{&User{}, &Session{}, &PasswordReset{}, &EmailVerification{}, &AllowedDomain{}, &UserIdentity{}, &LoginTicket{}, &Department{}, &Program{}, &CoordinatorAssignment{}, &Subject{}, &Semester{}, &Section{}, &StudentEnrollment{}, &StaffAssignment{}, &Campaign{}, &Survey{}, &Question{}, &Response{}, &SurveyParticipation{}, &SurveyTemplate{}, &TemplateQuestion{}}
+}
+
+// isUniqueViolation reports whether err comes from a unique constraint (PostgreSQL or SQLite)
+func isUniqueViolation(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "UNIQUE constraint"))
+}
+
 // getCORSConfig returns CORS configuration for the server
 func getCORSConfig() cors.Config {
 	allowedOrigin := os.Getenv("CORS_ORIGIN")
@@ -273,10 +536,35 @@ func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 			return
 		}
 
-		// Check if user role is allowed
+		var user User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if user.IsDisabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			c.Abort()
+			return
+		}
+		if user.EmailUnverified {
+			c.JSON(http.StatusForbidden, gin.H{"error": ErrEmailUnverified.Error(), "code": ErrCodeEmailUnverified})
+			c.Abort()
+			return
+		}
+
+		// Tokens of a revoked session stop working before they expire, and every token must
+		// belong to a session so none can outlive a logout
+		if err := CheckSession(db, claims.SessionID, user.ID, time.Now()); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		// Check the user's current role, which may have changed since the token was issued
 		allowed := false
 		for _, role := range allowedRoles {
-			if claims.Role == role {
+			if user.Role == role {
 				allowed = true
 				break
 			}
@@ -289,20 +577,39 @@ func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 		}
 
 		// Store user data in context for later use
-		var user User
-		if err := db.First(&user, claims.UserID).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		c.Set("currentUser", user)
+		c.Set("userID", claims.UserID)
+		c.Set("userRole", user.Role)
+		c.Set("sessionID", claims.SessionID)
+		c.Next()
+	}
+}
+
+// RateLimitAuth limits the requests each client IP makes to the endpoint it guards
+func RateLimitAuth(limiter *AuthLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wait, err := limiter.Allow(c.FullPath(), c.ClientIP(), time.Now())
+		if errors.Is(err, ErrTooManyAttempts) {
+			log.Printf("⚠️  Rate limited %s %s from %s", c.Request.Method, c.FullPath(), c.ClientIP())
+			respondTooManyAttempts(c, wait)
 			c.Abort()
 			return
 		}
-
-		c.Set("currentUser", user)
-		c.Set("userID", claims.UserID)
-		c.Set("userRole", claims.Role)
+		// A failing store lets requests through rather than lock everyone out
+		if err != nil {
+			log.Printf("⚠️  Rate limit store failed: %v", err)
+		}
 		c.Next()
 	}
 }
 
+// respondTooManyAttempts answers 429, telling the client how many seconds to wait
+func respondTooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": ErrTooManyAttempts.Error(), "code": ErrCodeTooManyAttempts, "retry_after": seconds})
+}
+
 func main() {
 
 	// Load .env file if it exists (optional in production)
@@ -326,24 +633,54 @@ func main() {
 		panic("failed to connect database")
 	}
 
+	// Duplicate answers would block the unique response index from being created
+	if err := removeDuplicateResponses(db); err != nil {
+		log.Printf("⚠️  Failed to remove duplicate responses: %v", err)
+	}
+
+	// The coordinator role was added after the users table was created
+	if err := refreshUserRoleCheck(db); err != nil {
+		log.Printf("⚠️  Failed to refresh user role check: %v", err)
+	}
+
+	// Sections joined the campaign unique index, which GORM won't change in place
+	if err := dropLegacyCampaignIndex(db); err != nil {
+		log.Printf("⚠️  Failed to drop legacy campaign index: %v", err)
+	}
+
+	// Surveys created before lifecycle statuses get one after migrating
+	needsStatus := needsSurveyStatusBackfill(db)
+
 	// Auto-migrate all the new models
 	log.Println("🔧 Running database migrations...")
-	migrationErr := db.AutoMigrate(&User{}, &Subject{}, &Semester{}, &StudentEnrollment{}, &Survey{}, &Question{}, &Response{})
+	migrationErr := db.AutoMigrate(migrationModels()...)
 	if migrationErr != nil {
 		log.Printf("⚠️  Migration error: %v", migrationErr)
 		log.Println("🔄 Attempting to reset database...")
 
 		// Drop all tables and recreate them
-		db.Migrator().DropTable(&Response{}, &Question{}, &Survey{}, &StudentEnrollment{}, &Subject{}, &Semester{}, &User{})
+		models := migrationModels()
+		for i := len(models) - 1; i >= 0; i-- {
+			db.Migrator().DropTable(models[i])
+		}
 
 		// Retry migration
-		migrationErr = db.AutoMigrate(&User{}, &Subject{}, &Semester{}, &StudentEnrollment{}, &Survey{}, &Question{}, &Response{})
+		migrationErr = db.AutoMigrate(migrationModels()...)
 		if migrationErr != nil {
 			log.Fatal("Failed to migrate database after reset: ", migrationErr)
 		}
 		log.Println("✅ Database reset and migrated successfully")
 	}
 
+	if err := backfillQuestionKeys(db); err != nil {
+		log.Printf("⚠️  Failed to backfill question keys: %v", err)
+	}
+	if needsStatus {
+		if err := backfillSurveyStatus(db); err != nil {
+			log.Printf("⚠️  Failed to backfill survey statuses: %v", err)
+		}
+	}
+
 	// Seed database with sample data (comment out after first run if you want to keep data)
 	// Seed database if SEED_DB environment variable is set to "true"
 	if os.Getenv("SEED_DB") == "true" {
@@ -351,7 +688,32 @@ func main() {
 		seedDatabase(db)
 	}
 
+	// Results of surveys with fewer submissions than this are withheld
+	minCohortSize := getMinCohortSize()
+	// Institution-wide results embargo for surveys that don't set their own
+	defaultEmbargo := getDefaultEmbargoPolicy()
+	// Password reset links are emailed through MAIL_TRANSPORT
+	mailer := newMailerFromEnv()
+	passwordResetURL := getPasswordResetURL()
+	emailVerificationURL := getEmailVerificationURL()
+	// Institutional single sign-on, each protocol enabled by its own settings
+	oidcProvider := newOIDCProviderFromEnv()
+	samlProvider, err := newSAMLProviderFromEnv()
+	if err != nil {
+		log.Printf("⚠️  SAML login disabled: %v", err)
+	}
+	ssoRoleMapping := getSSORoleMapping()
+	ssoCallbackURL := getSSOCallbackURL()
+	// Brute-force protection for the authentication endpoints
+	authLimiter := newAuthLimiterFromEnv()
+
 	r := gin.Default()
+	// Client IPs come from X-Forwarded-For only when it is set by the listed proxies
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := r.SetTrustedProxies(splitList(proxies)); err != nil {
+			log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+		}
+	}
 
 	// Apply CORS middleware to all routes
 	r.Use(cors.New(getCORSConfig()))
@@ -376,7 +738,7 @@ func main() {
 	})
 
 	// Authentication endpoints
-	r.POST("/register", func(c *gin.Context) {
+	r.POST("/register", RateLimitAuth(authLimiter), func(c *gin.Context) {
 		var newUser User
 		if err := c.BindJSON(&newUser); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
@@ -413,16 +775,28 @@ func main() {
 		}
 
 		// Validate requested role
-		if requestedRole != RoleStudent && requestedRole != RoleProfessor && requestedRole != RoleAdmin {
+		if !isRole(requestedRole) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid requested role"})
 			return
 		}
 
+		// Only members of the allowed domains can register
+		if err := CheckEmailDomain(db, newUser.Email); err != nil {
+			if errors.Is(err, ErrEmailDomainNotAllowed) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": ErrCodeEmailDomainNotAllowed})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email domain"})
+			return
+		}
+
 		// Store requested role separately
 		newUser.RequestedRole = requestedRole
 
 		// Effective role is always student at registration time
 		newUser.Role = RoleStudent
+		// The account can't be used until the email is confirmed
+		newUser.EmailUnverified = true
 
 		// Hash the password before storing
 		hashedPassword, err := HashPassword(newUser.Password)
@@ -435,444 +809,2885 @@ func main() {
 		result := db.Create(&newUser)
 		if result.Error != nil {
 			// Check if it's a unique constraint violation (email already exists)
-			if strings.Contains(result.Error.Error(), "duplicate key") || strings.Contains(result.Error.Error(), "UNIQUE constraint") {
+			if isUniqueViolation(result.Error) {
 				c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
 				return
 			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 			return
 		}
+		// A failed email can be sent again through POST /email/verify/resend
+		if err := StartEmailVerification(db, mailer, &newUser, emailVerificationURL, time.Now()); err != nil {
+			log.Printf("⚠️  Failed to send verification email: %v", err)
+		}
 
 		// Create response user without password
 		responseUser := map[string]interface{}{
-			"id":             newUser.ID,
-			"first_name":     newUser.FirstName,
-			"last_name":      newUser.LastName,
-			"email":          newUser.Email,
-			"role":           newUser.Role,
-			"requested_role": newUser.RequestedRole,
-			"created_at":     newUser.CreatedAt,
-			"updated_at":     newUser.UpdatedAt,
+			"id":               newUser.ID,
+			"first_name":       newUser.FirstName,
+			"last_name":        newUser.LastName,
+			"email":            newUser.Email,
+			"role":             newUser.Role,
+			"requested_role":   newUser.RequestedRole,
+			"email_unverified": newUser.EmailUnverified,
+			"created_at":       newUser.CreatedAt,
+			"updated_at":       newUser.UpdatedAt,
 		}
 
 		c.JSON(http.StatusCreated, responseUser)
 	})
 
-	r.POST("/login", func(c *gin.Context) {
+	// respondWithLogin opens a session for a user who proved who they are and answers
+	// with its first access and refresh tokens
+	respondWithLogin := func(c *gin.Context, user User) {
+		session, refreshToken, err := StartSession(db, user, c.Request.UserAgent(), c.ClientIP(), time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+			return
+		}
+		token, err := GenerateSessionJWT(user.ID, user.Role, session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		// Create response with user data and tokens
+		response := map[string]interface{}{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"expires_in":    int(accessTokenTTL.Seconds()),
+			"user": map[string]interface{}{
+				"id":             user.ID,
+				"first_name":     user.FirstName,
+				"last_name":      user.LastName,
+				"email":          user.Email,
+				"role":           user.Role,
+				"requested_role": user.RequestedRole,
+				"created_at":     user.CreatedAt,
+				"updated_at":     user.UpdatedAt,
+			},
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+
+	// loginFailed logs a failed login and counts it towards a lockout
+	loginFailed := func(c *gin.Context, email, reason string) {
+		log.Printf("⚠️  Failed login for %q from %s: %s", email, c.ClientIP(), reason)
+		locked, err := authLimiter.LoginFailed(email, c.ClientIP(), time.Now())
+		if err != nil {
+			log.Printf("⚠️  Rate limit store failed: %v", err)
+		}
+		if locked {
+			log.Printf("🔒 Locked out logins for %q or from %s for %s", email, c.ClientIP(), authLimiter.Lockout)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	}
+
+	r.POST("/login", RateLimitAuth(authLimiter), func(c *gin.Context) {
 		var user User
 		if err := c.BindJSON(&user); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 			return
 		}
 
+		// Locked out accounts and IPs are refused before bcrypt runs
+		wait, err := authLimiter.CheckLogin(user.Email, c.ClientIP(), time.Now())
+		if errors.Is(err, ErrTooManyAttempts) {
+			log.Printf("⚠️  Refused login for %q from %s: locked out", user.Email, c.ClientIP())
+			respondTooManyAttempts(c, wait)
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Rate limit store failed: %v", err)
+		}
+
 		var foundUser User
 		result := db.Where("email = ?", user.Email).First(&foundUser)
 		if result.Error != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			loginFailed(c, user.Email, "unknown email")
 			return
 		}
 
 		// Verify password using bcrypt
 		if !CheckPasswordHash(user.Password, foundUser.Password) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			loginFailed(c, user.Email, "wrong password")
 			return
 		}
-
-		// Generate JWT token
-		token, err := GenerateJWT(foundUser.ID, foundUser.Role)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		if err := authLimiter.LoginSucceeded(user.Email); err != nil {
+			log.Printf("⚠️  Rate limit store failed: %v", err)
+		}
+		if foundUser.IsDisabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
 			return
 		}
-
-		// Create response with user data and token
-		response := map[string]interface{}{
-			"token": token,
-			"user": map[string]interface{}{
-				"id":             foundUser.ID,
-				"first_name":     foundUser.FirstName,
-				"last_name":      foundUser.LastName,
-				"email":          foundUser.Email,
-				"role":           foundUser.Role,
-				"requested_role": foundUser.RequestedRole,
-				"created_at":     foundUser.CreatedAt,
-				"updated_at":     foundUser.UpdatedAt,
-			},
+		if foundUser.EmailUnverified {
+			c.JSON(http.StatusForbidden, gin.H{"error": ErrEmailUnverified.Error(), "code": ErrCodeEmailUnverified})
+			return
 		}
 
-		c.JSON(http.StatusOK, response)
+		respondWithLogin(c, foundUser)
 	})
 
-	// =============================================================================
-	// ADMIN ENDPOINTS
-	// =============================================================================
+	// Exchange a refresh token for a new access token and a new refresh token
+	r.POST("/refresh", RateLimitAuth(authLimiter), func(c *gin.Context) {
+		var body struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+			return
+		}
 
-	adminGroup := r.Group("/admin")
-	adminGroup.Use(RequireRole(RoleAdmin))
-	{
-		// Semester Management
-		adminGroup.POST("/semesters", func(c *gin.Context) {
-			var semester Semester
-			if err := c.BindJSON(&semester); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-				return
-			}
-			if err := db.Create(&semester).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create semester"})
-				return
-			}
-			c.JSON(http.StatusCreated, gin.H{"semester": semester})
-		})
+		session, refreshToken, err := RefreshSession(db, body.RefreshToken, time.Now())
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			return
+		}
 
-		adminGroup.GET("/semesters", func(c *gin.Context) {
-			var semesters []Semester
-			if err := db.Find(&semesters).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch semesters"})
-				return
-			}
-			c.JSON(http.StatusOK, gin.H{"semesters": semesters})
-		})
+		var user User
+		if err := db.First(&user, session.UserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+		if user.IsDisabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			return
+		}
 
-		adminGroup.PUT("/semesters/:id/activate", func(c *gin.Context) {
-			id := c.Param("id")
-			// Deactivate all semesters first
-			db.Model(&Semester{}).Update("is_active", false)
-			// Activate the selected semester
-			if err := db.Model(&Semester{}).Where("id = ?", id).Update("is_active", true).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate semester"})
-				return
-			}
-			c.JSON(http.StatusOK, gin.H{"message": "Semester activated successfully"})
+		// The new access token carries the user's current role
+		token, err := GenerateSessionJWT(user.ID, user.Role, session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"expires_in":    int(accessTokenTTL.Seconds()),
+			"user":          gin.H{"id": user.ID, "role": user.Role},
 		})
+	})
 
-		// Subject Management
-		adminGroup.POST("/subjects", func(c *gin.Context) {
-			var subject Subject
-			if err := c.BindJSON(&subject); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-				return
-			}
-			if err := db.Create(&subject).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subject"})
-				return
+	// End the session the access token belongs to
+	r.POST("/logout", RequireRole(allUserRoles...), func(c *gin.Context) {
+		sessionID := c.GetUint("sessionID")
+		if sessionID != 0 {
+			var session Session
+			if err := db.First(&session, sessionID).Error; err == nil {
+				if err := RevokeSession(db, &session, time.Now()); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+					return
+				}
 			}
-			c.JSON(http.StatusCreated, gin.H{"subject": subject})
-		})
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	})
 
-		adminGroup.GET("/subjects", func(c *gin.Context) {
-			var subjects []Subject
-			if err := db.Preload("Professor").Find(&subjects).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+	// Email a password reset link. The answer is the same whether or not the email
+	// belongs to an account.
+	r.POST("/password/forgot", RateLimitAuth(authLimiter), func(c *gin.Context) {
+		var body struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+			return
+		}
+		if err := RequestPasswordReset(db, mailer, body.Email, passwordResetURL, time.Now()); err != nil {
+			log.Printf("⚠️  Failed to send password reset email: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "If the email belongs to an account, a password reset link has been sent"})
+	})
+
+	// Set a new password with the token from a reset email
+	r.POST("/password/reset", RateLimitAuth(authLimiter), func(c *gin.Context) {
+		var body struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token and password are required"})
+			return
+		}
+
+		err := ResetPassword(db, body.Token, body.Password, time.Now())
+		if errors.Is(err, ErrWeakPassword) || errors.Is(err, ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, log in with the new password"})
+	})
+
+	// Confirm an email with the token from a verification email
+	r.POST("/email/verify", func(c *gin.Context) {
+		var body struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+			return
+		}
+
+		_, err := VerifyEmail(db, body.Token, time.Now())
+		switch {
+		case errors.Is(err, ErrInvalidVerificationToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, ErrEmailDomainNotAllowed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": ErrCodeEmailDomainNotAllowed})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully, you can log in now"})
+	})
+
+	// Email a new verification link. The answer is the same whether or not the email
+	// belongs to an unverified account.
+	r.POST("/email/verify/resend", RateLimitAuth(authLimiter), func(c *gin.Context) {
+		var body struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+			return
+		}
+		if err := ResendEmailVerification(db, mailer, body.Email, emailVerificationURL, time.Now()); err != nil {
+			log.Printf("⚠️  Failed to send verification email: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "If the email belongs to an unverified account, a verification link has been sent"})
+	})
+
+	// =============================================================================
+	// SINGLE SIGN-ON ENDPOINTS
+	// =============================================================================
+
+	// The login state rides in a signed cookie between the redirect to the identity provider
+	// and its answer. SAML answers with a cross-site POST, which only carries SameSite=None
+	// cookies, and those need HTTPS.
+	const ssoStateCookie = "sso_state"
+	secureSSOCookie := strings.HasPrefix(getAPIPublicURL(), "https://")
+	setSSOState := func(c *gin.Context, value string, maxAge int) {
+		if secureSSOCookie {
+			c.SetSameSite(http.SameSiteNoneMode)
+		} else {
+			c.SetSameSite(http.SameSiteLaxMode)
+		}
+		c.SetCookie(ssoStateCookie, value, maxAge, "/sso", "", secureSSOCookie, true)
+	}
+	startSSO := func(c *gin.Context, authURL string, login SSOState, err error) {
+		if errors.Is(err, ErrSSONotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to start single sign-on: %v", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+			return
+		}
+		signed, err := SignSSOState(login, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+			return
+		}
+		setSSOState(c, signed, int(ssoStateTTL.Seconds()))
+		c.Redirect(http.StatusFound, authURL)
+	}
+	loginState := func(c *gin.Context) (SSOState, error) {
+		signed, err := c.Cookie(ssoStateCookie)
+		if err != nil {
+			return SSOState{}, ErrInvalidSSOState
+		}
+		setSSOState(c, "", -1)
+		return ParseSSOState(signed)
+	}
+	// finishSSO sends the browser back to the client with a login ticket, or an error code
+	finishSSO := func(c *gin.Context, identity SSOIdentity, err error) {
+		code := "sso_failed"
+		var user User
+		if err == nil {
+			user, err = ProvisionSSOUser(db, identity, ssoRoleMapping)
+		}
+		if err == nil && user.IsDisabled() {
+			err = errors.New("account is disabled")
+			code = "account_disabled"
+		}
+		var ticket string
+		if err == nil {
+			ticket, err = IssueLoginTicket(db, user, time.Now())
+		}
+
+		query := url.Values{}
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrEmailDomainNotAllowed):
+				code = ErrCodeEmailDomainNotAllowed
+			case errors.Is(err, ErrSSONotMember):
+				code = "not_member"
+			case errors.Is(err, ErrSSOEmailMissing), errors.Is(err, ErrSSOEmailUnverified):
+				code = "email_unavailable"
+			}
+			log.Printf("⚠️  Single sign-on failed (%s): %v", code, err)
+			query.Set("error", code)
+		} else {
+			query.Set("ticket", ticket)
+		}
+		c.Redirect(http.StatusFound, ssoCallbackURL+"?"+query.Encode())
+	}
+
+	// Which identity providers the login page can offer
+	r.GET("/sso/providers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"oidc": oidcProvider != nil, "saml": samlProvider != nil})
+	})
+
+	r.GET("/sso/oidc/login", func(c *gin.Context) {
+		authURL, login, err := oidcProvider.AuthURL(c.Request.Context())
+		startSSO(c, authURL, login, err)
+	})
+
+	r.GET("/sso/oidc/callback", func(c *gin.Context) {
+		login, err := loginState(c)
+		if err == nil && c.Query("error") != "" {
+			err = fmt.Errorf("identity provider error: %s", c.Query("error"))
+		}
+		var identity SSOIdentity
+		if err == nil {
+			identity, err = oidcProvider.Identity(c.Request.Context(), login, c.Query("state"), c.Query("code"))
+		}
+		finishSSO(c, identity, err)
+	})
+
+	r.GET("/sso/saml/login", func(c *gin.Context) {
+		authURL, login, err := samlProvider.AuthURL(c.Request.Context())
+		startSSO(c, authURL, login, err)
+	})
+
+	// Assertion consumer service
+	r.POST("/sso/saml/acs", func(c *gin.Context) {
+		login, err := loginState(c)
+		var identity SSOIdentity
+		if err == nil {
+			identity, err = samlProvider.Identity(c.Request, login)
+		}
+		finishSSO(c, identity, err)
+	})
+
+	// Service provider metadata to register with the identity provider
+	r.GET("/sso/saml/metadata", func(c *gin.Context) {
+		metadata, err := samlProvider.Metadata(c.Request.Context())
+		if errors.Is(err, ErrSSONotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+			return
+		}
+		c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+	})
+
+	// Exchange the ticket of an SSO callback for the same tokens /login returns
+	r.POST("/sso/exchange", func(c *gin.Context) {
+		var body struct {
+			Ticket string `json:"ticket" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket is required"})
+			return
+		}
+		user, err := RedeemLoginTicket(db, body.Ticket, time.Now())
+		if errors.Is(err, ErrInvalidLoginTicket) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+		if user.IsDisabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			return
+		}
+		respondWithLogin(c, user)
+	})
+
+	// =============================================================================
+	// ACCOUNT ENDPOINTS (any logged-in user)
+	// =============================================================================
+
+	meGroup := r.Group("/me")
+	meGroup.Use(RequireRole(allUserRoles...))
+	{
+		// Change the password, logging out every other session
+		meGroup.PUT("/password", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var body struct {
+				CurrentPassword string `json:"current_password" binding:"required"`
+				NewPassword     string `json:"new_password" binding:"required"`
+			}
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Current and new password are required"})
+				return
+			}
+
+			err := ChangePassword(db, &user, body.CurrentPassword, body.NewPassword)
+			switch {
+			case errors.Is(err, ErrWrongPassword):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			case errors.Is(err, ErrWeakPassword):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+				return
+			}
+			if _, err := RevokeOtherSessions(db, user.ID, c.GetUint("sessionID"), time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+		})
+	}
+
+	// =============================================================================
+	// ADMIN ENDPOINTS
+	// =============================================================================
+
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(RequireRole(RoleAdmin))
+	{
+		// Semester Management
+		adminGroup.POST("/semesters", func(c *gin.Context) {
+			var semester Semester
+			if err := c.BindJSON(&semester); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := db.Create(&semester).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create semester"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"semester": semester})
+		})
+
+		adminGroup.GET("/semesters", func(c *gin.Context) {
+			var semesters []Semester
+			if err := db.Find(&semesters).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch semesters"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"semesters": semesters})
+		})
+
+		// Fix a semester's name, period or dates (fields left out are kept)
+		adminGroup.PUT("/semesters/:id", func(c *gin.Context) {
+			var semester Semester
+			if err := db.Where("id = ?", c.Param("id")).First(&semester).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+
+			var body struct {
+				Name      *string    `json:"name"`
+				Year      *int       `json:"year"`
+				Period    *int       `json:"period"`
+				StartDate *time.Time `json:"start_date"`
+				EndDate   *time.Time `json:"end_date"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if body.Name == nil && body.Year == nil && body.Period == nil && body.StartDate == nil && body.EndDate == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "No changes provided"})
+				return
+			}
+			if (body.Name != nil && *body.Name == "") || (body.Year != nil && *body.Year <= 0) || (body.Period != nil && *body.Period <= 0) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty, and year and period must be positive"})
+				return
+			}
+			if body.Name != nil {
+				semester.Name = *body.Name
+			}
+			if body.Year != nil {
+				semester.Year = *body.Year
+			}
+			if body.Period != nil {
+				semester.Period = *body.Period
+			}
+			if body.StartDate != nil {
+				semester.StartDate = *body.StartDate
+			}
+			if body.EndDate != nil {
+				semester.EndDate = *body.EndDate
+			}
+			if !semester.EndDate.After(semester.StartDate) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be after start date"})
+				return
+			}
+
+			if err := db.Model(&semester).Select("name", "year", "period", "start_date", "end_date").Updates(semester).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update semester"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"semester": semester})
+		})
+
+		// Delete an empty semester
+		adminGroup.DELETE("/semesters/:id", func(c *gin.Context) {
+			var semester Semester
+			if err := db.Where("id = ?", c.Param("id")).First(&semester).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+			if err := DeleteSemester(db, semester); err != nil {
+				if errors.Is(err, ErrSemesterActive) || errors.Is(err, ErrSemesterInUse) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete semester"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Semester deleted successfully"})
+		})
+
+		adminGroup.PUT("/semesters/:id/activate", func(c *gin.Context) {
+			id := c.Param("id")
+			// Deactivate all semesters first
+			db.Model(&Semester{}).Update("is_active", false)
+			// Activate the selected semester
+			if err := db.Model(&Semester{}).Where("id = ?", id).Update("is_active", true).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate semester"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Semester activated successfully"})
+		})
+
+		// Mark a semester's grades as finalized, lifting until_grades_finalized embargoes
+		adminGroup.PUT("/semesters/:id/finalize-grades", func(c *gin.Context) {
+			semesterID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid semester ID"})
+				return
+			}
+
+			var semester Semester
+			if err := db.First(&semester, semesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+			if err := db.Model(&semester).Update("grades_finalized", true).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize grades"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"semester": semester})
+		})
+
+		// Response rates of every subject offered in a semester
+		adminGroup.GET("/semesters/:id/response-rates", func(c *gin.Context) {
+			semesterID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid semester ID"})
+				return
+			}
+
+			var semester Semester
+			if err := db.First(&semester, semesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+
+			subjects, err := SemesterResponseRates(db, semester.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"semester": semester, "subjects": subjects})
+		})
+
+		// Campaigns: one survey per offered subject with shared questions and window
+		adminGroup.POST("/campaigns", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var req CampaignRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			campaign := req.Campaign(user)
+			if err := ValidateSurveyWindow(Survey{OpenDate: campaign.OpenDate, CloseDate: campaign.CloseDate}); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := ValidateEmbargoPolicy(campaign.ResultsEmbargo); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			var semester Semester
+			if err := db.First(&semester, campaign.SemesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+			var template SurveyTemplate
+			if err := db.Preload("Questions").First(&template, campaign.TemplateID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+				return
+			}
+
+			subjects, err := OfferedSubjects(db, semester.ID, req.SubjectIDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+				return
+			}
+			if err := CreateCampaign(db, &campaign, template, subjects); err != nil {
+				if errors.Is(err, ErrNoOfferedSubjects) || errors.Is(err, ErrEnrollmentsWithoutSection) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"campaign": campaign})
+		})
+
+		adminGroup.GET("/campaigns", func(c *gin.Context) {
+			var campaigns []Campaign
+			if err := db.Preload("Semester").Preload("Surveys.Subject").Preload("Surveys.Section").Order("created_at DESC").Find(&campaigns).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaigns"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+		})
+
+		// Campaign details with the response rate of every generated survey
+		adminGroup.GET("/campaigns/:id", func(c *gin.Context) {
+			campaignID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+				return
+			}
+
+			var campaign Campaign
+			if err := db.Preload("Semester").Preload("Surveys.Subject").Preload("Surveys.Section").Preload("Surveys.Professor").First(&campaign, campaignID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+				return
+			}
+
+			rates, err := ComputeResponseRates(db, campaign.Surveys)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
+				return
+			}
+			for i := range campaign.Surveys {
+				rate := rates[campaign.Surveys[i].ID]
+				campaign.Surveys[i].ResponseRate = &rate
+			}
+			c.JSON(http.StatusOK, gin.H{"campaign": campaign})
+		})
+
+		// Subject Management
+		adminGroup.POST("/subjects", func(c *gin.Context) {
+			var subject Subject
+			if err := c.BindJSON(&subject); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := db.Create(&subject).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subject"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"subject": subject})
+		})
+
+		adminGroup.GET("/subjects", func(c *gin.Context) {
+			var subjects []Subject
+			if err := db.Preload("Professor").Find(&subjects).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"subjects": subjects})
+		})
+
+		// Fix a subject's name, code, description or professor (fields left out are kept)
+		adminGroup.PUT("/subjects/:id", func(c *gin.Context) {
+			var subject Subject
+			if err := db.Where("id = ?", c.Param("id")).First(&subject).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+
+			var body struct {
+				Name        *string `json:"name"`
+				Code        *string `json:"code"`
+				Description *string `json:"description"`
+				ProfessorID *uint   `json:"professor_id"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if (body.Name != nil && *body.Name == "") || (body.Code != nil && *body.Code == "") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Name and code cannot be empty"})
+				return
+			}
+			if body.ProfessorID != nil {
+				var professor User
+				if err := db.First(&professor, *body.ProfessorID).Error; err != nil || (professor.Role != RoleProfessor && professor.Role != RoleCoordinator) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Subject professor must be a professor"})
+					return
+				}
+			}
+
+			updates := map[string]interface{}{}
+			if body.Name != nil {
+				updates["name"] = *body.Name
+			}
+			if body.Code != nil {
+				updates["code"] = *body.Code
+			}
+			if body.Description != nil {
+				updates["description"] = *body.Description
+			}
+			if body.ProfessorID != nil {
+				updates["professor_id"] = *body.ProfessorID
+			}
+			if len(updates) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "No changes provided"})
+				return
+			}
+			if err := db.Model(&subject).Updates(updates).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Subject code already exists"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subject"})
+				return
+			}
+			db.Preload("Professor").Preload("Department").Preload("Program").First(&subject, subject.ID)
+			c.JSON(http.StatusOK, gin.H{"subject": subject})
+		})
+
+		// Delete a subject with its enrollments, sections, staff and surveys, unless they have responses
+		adminGroup.DELETE("/subjects/:id", func(c *gin.Context) {
+			var subject Subject
+			if err := db.Where("id = ?", c.Param("id")).First(&subject).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+			if err := DeleteSubject(db, subject); err != nil {
+				if errors.Is(err, ErrSubjectHasResponses) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subject"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Subject deleted successfully"})
+		})
+
+		// Move a subject into a department and/or program (0 clears)
+		adminGroup.PUT("/subjects/:id/unit", func(c *gin.Context) {
+			var subject Subject
+			if err := db.Where("id = ?", c.Param("id")).First(&subject).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+
+			var body struct {
+				DepartmentID uint `json:"department_id"`
+				ProgramID    uint `json:"program_id"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if body.DepartmentID != 0 {
+				if err := db.First(&Department{}, body.DepartmentID).Error; err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+					return
+				}
+			}
+			if body.ProgramID != 0 {
+				if err := db.First(&Program{}, body.ProgramID).Error; err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+					return
+				}
+			}
+
+			// Zero IDs are stored as NULL
+			updates := map[string]interface{}{"department_id": nil, "program_id": nil}
+			if body.DepartmentID != 0 {
+				updates["department_id"] = body.DepartmentID
+			}
+			if body.ProgramID != 0 {
+				updates["program_id"] = body.ProgramID
+			}
+			if err := db.Model(&subject).Updates(updates).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subject"})
+				return
+			}
+			db.Preload("Professor").Preload("Department").Preload("Program").First(&subject, subject.ID)
+			c.JSON(http.StatusOK, gin.H{"subject": subject})
+		})
+
+		// Cross-semester NPS and rating trends of a subject
+		adminGroup.GET("/subjects/:id/trends", func(c *gin.Context) {
+			subjectID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject ID"})
+				return
+			}
+
+			var subject Subject
+			if err := db.First(&subject, subjectID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+
+			trends, err := LoadSubjectTrends(db, subject, TrendOptions{MinCohort: minCohortSize, Now: time.Now()})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trends"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"trends": trends})
+		})
+
+		// Department and Program Management
+		adminGroup.POST("/departments", func(c *gin.Context) {
+			var department Department
+			if err := c.BindJSON(&department); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := db.Create(&department).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Department code already exists"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create department"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"department": department})
+		})
+
+		adminGroup.GET("/departments", func(c *gin.Context) {
+			var departments []Department
+			if err := db.Order("code ASC").Find(&departments).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch departments"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"departments": departments})
+		})
+
+		adminGroup.POST("/programs", func(c *gin.Context) {
+			var program Program
+			if err := c.BindJSON(&program); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := db.First(&program.Department, program.DepartmentID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+				return
+			}
+			if err := db.Omit("Department").Create(&program).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Program code already exists"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create program"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"program": program})
+		})
+
+		adminGroup.GET("/programs", func(c *gin.Context) {
+			var programs []Program
+			if err := db.Preload("Department").Order("code ASC").Find(&programs).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch programs"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"programs": programs})
+		})
+
+		// Section Management
+		adminGroup.POST("/sections", func(c *gin.Context) {
+			var section Section
+			if err := c.BindJSON(&section); err != nil || section.Code == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			section.ID = 0
+
+			if err := db.First(&Subject{}, section.SubjectID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+			if err := db.First(&Semester{}, section.SemesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+			if section.ProfessorID != 0 {
+				var professor User
+				if err := db.First(&professor, section.ProfessorID).Error; err != nil || (professor.Role != RoleProfessor && professor.Role != RoleCoordinator) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Section professor must be a professor"})
+					return
+				}
+			}
+
+			if err := db.Omit("Subject", "Semester", "Professor").Create(&section).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Section code already exists for this subject and semester"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create section"})
+				return
+			}
+			db.Preload("Subject").Preload("Semester").Preload("Professor").First(&section, section.ID)
+			c.JSON(http.StatusCreated, gin.H{"section": section})
+		})
+
+		adminGroup.GET("/sections", func(c *gin.Context) {
+			query := db.Preload("Subject").Preload("Semester").Preload("Professor")
+			if subjectID := c.Query("subject_id"); subjectID != "" {
+				query = query.Where("subject_id = ?", subjectID)
+			}
+			if semesterID := c.Query("semester_id"); semesterID != "" {
+				query = query.Where("semester_id = ?", semesterID)
+			}
+
+			var sections []Section
+			if err := query.Order("code ASC").Find(&sections).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sections"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"sections": sections})
+		})
+
+		adminGroup.PUT("/sections/:id", func(c *gin.Context) {
+			var section Section
+			if err := db.Where("id = ?", c.Param("id")).First(&section).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+				return
+			}
+
+			// Fields left out are kept
+			var body struct {
+				Code        *string `json:"code"`
+				ProfessorID *uint   `json:"professor_id"`
+				Schedule    *string `json:"schedule"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if body.Code != nil && *body.Code == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Code cannot be empty"})
+				return
+			}
+			if body.ProfessorID != nil && *body.ProfessorID != 0 {
+				var professor User
+				if err := db.First(&professor, *body.ProfessorID).Error; err != nil || (professor.Role != RoleProfessor && professor.Role != RoleCoordinator) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Section professor must be a professor"})
+					return
+				}
+			}
+
+			updates := map[string]interface{}{}
+			if body.Code != nil {
+				updates["code"] = *body.Code
+			}
+			if body.Schedule != nil {
+				updates["schedule"] = *body.Schedule
+			}
+			if body.ProfessorID != nil {
+				// A zero professor ID is stored as NULL
+				updates["professor_id"] = nil
+				if *body.ProfessorID != 0 {
+					updates["professor_id"] = *body.ProfessorID
+				}
+			}
+			if len(updates) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "No changes provided"})
+				return
+			}
+			if err := db.Model(&section).Updates(updates).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Section code already exists for this subject and semester"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update section"})
+				return
+			}
+			db.Preload("Subject").Preload("Semester").Preload("Professor").First(&section, section.ID)
+			c.JSON(http.StatusOK, gin.H{"section": section})
+		})
+
+		adminGroup.DELETE("/sections/:id", func(c *gin.Context) {
+			var section Section
+			if err := db.Where("id = ?", c.Param("id")).First(&section).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+				return
+			}
+
+			// Sections with enrollments or surveys would leave them pointing nowhere
+			var enrollments, surveys int64
+			db.Model(&StudentEnrollment{}).Where("section_id = ?", section.ID).Count(&enrollments)
+			db.Unscoped().Model(&Survey{}).Where("section_id = ?", section.ID).Count(&surveys)
+			if enrollments > 0 || surveys > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Section has enrollments or surveys"})
+				return
+			}
+
+			if err := db.Delete(&section).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete section"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Section deleted successfully"})
+		})
+
+		// Offering Staff Management
+		adminGroup.POST("/staff", func(c *gin.Context) {
+			var assignment StaffAssignment
+			if err := c.BindJSON(&assignment); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			assignment.ID = 0
+
+			if err := db.First(&Subject{}, assignment.SubjectID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Subject not found"})
+				return
+			}
+			if err := db.First(&Semester{}, assignment.SemesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
+				return
+			}
+			var user User
+			if err := db.First(&user, assignment.UserID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if user.Role != RoleProfessor && user.Role != RoleCoordinator {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Staff members must have the professor or coordinator role"})
+				return
+			}
+			if err := ValidateStaffAssignment(db, assignment); err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ErrLeadAlreadyAssigned) {
+					status = http.StatusConflict
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := db.Omit("Subject", "Semester", "User").Create(&assignment).Error; err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "User is already on the staff of this offering"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign staff"})
+				return
+			}
+			db.Preload("Subject").Preload("Semester").Preload("User").First(&assignment, assignment.ID)
+			c.JSON(http.StatusCreated, gin.H{"staff": assignment})
+		})
+
+		adminGroup.GET("/staff", func(c *gin.Context) {
+			query := db.Preload("Subject").Preload("Semester").Preload("User")
+			if subjectID := c.Query("subject_id"); subjectID != "" {
+				query = query.Where("subject_id = ?", subjectID)
+			}
+			if semesterID := c.Query("semester_id"); semesterID != "" {
+				query = query.Where("semester_id = ?", semesterID)
+			}
+
+			var assignments []StaffAssignment
+			if err := query.Find(&assignments).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"staff": assignments})
+		})
+
+		adminGroup.PUT("/staff/:id", func(c *gin.Context) {
+			var assignment StaffAssignment
+			if err := db.Where("id = ?", c.Param("id")).First(&assignment).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Staff assignment not found"})
+				return
+			}
+
+			var body struct {
+				Role string `json:"role"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			assignment.Role = body.Role
+			if err := ValidateStaffAssignment(db, assignment); err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ErrLeadAlreadyAssigned) {
+					status = http.StatusConflict
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := db.Model(&assignment).Update("role", assignment.Role).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update staff"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"staff": assignment})
+		})
+
+		adminGroup.DELETE("/staff/:id", func(c *gin.Context) {
+			result := db.Where("id = ?", c.Param("id")).Delete(&StaffAssignment{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staff"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Staff assignment not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Staff removed successfully"})
+		})
+
+		// Coordinator Management
+		adminGroup.POST("/coordinators", func(c *gin.Context) {
+			var assignment CoordinatorAssignment
+			if err := c.BindJSON(&assignment); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := ValidateCoordinatorAssignment(assignment); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			var user User
+			if err := db.First(&user, assignment.UserID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if user.Role != RoleCoordinator {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "User must have the coordinator role"})
+				return
+			}
+			if assignment.DepartmentID != 0 {
+				if err := db.First(&Department{}, assignment.DepartmentID).Error; err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+					return
+				}
+			}
+			if assignment.ProgramID != 0 {
+				if err := db.First(&Program{}, assignment.ProgramID).Error; err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+					return
+				}
+			}
+
+			assignment.ID = 0
+			if err := db.Omit("User", "Department", "Program").Create(&assignment).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign coordinator"})
+				return
+			}
+			db.Preload("User").Preload("Department").Preload("Program").First(&assignment, assignment.ID)
+			c.JSON(http.StatusCreated, gin.H{"coordinator": assignment})
+		})
+
+		adminGroup.GET("/coordinators", func(c *gin.Context) {
+			var assignments []CoordinatorAssignment
+			if err := db.Preload("User").Preload("Department").Preload("Program").Find(&assignments).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coordinators"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"coordinators": assignments})
+		})
+
+		adminGroup.DELETE("/coordinators/:id", func(c *gin.Context) {
+			result := db.Where("id = ?", c.Param("id")).Delete(&CoordinatorAssignment{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove coordinator"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Coordinator assignment not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Coordinator removed successfully"})
+		})
+
+		// Student Enrollment Management
+		adminGroup.POST("/enrollments", func(c *gin.Context) {
+			var enrollment StudentEnrollment
+			if err := c.BindJSON(&enrollment); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			if err := ValidateEnrollment(db, enrollment); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Student not found"})
+					return
+				}
+				if errors.Is(err, ErrAlreadyEnrolled) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := db.Create(&enrollment).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create enrollment"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"enrollment": enrollment})
+		})
+
+		adminGroup.GET("/enrollments", func(c *gin.Context) {
+			var enrollments []StudentEnrollment
+			if err := db.Preload("Student").Preload("Subject").Preload("Semester").Preload("Section").Find(&enrollments).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollments"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"enrollments": enrollments})
+		})
+
+		// Fix an enrollment's student, subject, semester or section (zero IDs are kept, except the section which is cleared)
+		adminGroup.PUT("/enrollments/:id", func(c *gin.Context) {
+			var enrollment StudentEnrollment
+			if err := db.Where("id = ?", c.Param("id")).First(&enrollment).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Enrollment not found"})
+				return
+			}
+
+			var body struct {
+				StudentID  uint `json:"student_id"`
+				SubjectID  uint `json:"subject_id"`
+				SemesterID uint `json:"semester_id"`
+				SectionID  uint `json:"section_id"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			changes := StudentEnrollment{StudentID: enrollment.StudentID, SubjectID: enrollment.SubjectID, SemesterID: enrollment.SemesterID, SectionID: body.SectionID}
+			if body.StudentID != 0 {
+				changes.StudentID = body.StudentID
+			}
+			if body.SubjectID != 0 {
+				if err := db.First(&Subject{}, body.SubjectID).Error; err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Subject not found"})
+					return
+				}
+				changes.SubjectID = body.SubjectID
+			}
+			if body.SemesterID != 0 {
+				if err := db.First(&Semester{}, body.SemesterID).Error; err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Semester not found"})
+					return
+				}
+				changes.SemesterID = body.SemesterID
+			}
+
+			if err := UpdateEnrollment(db, &enrollment, changes); err != nil {
+				switch {
+				case errors.Is(err, gorm.ErrRecordNotFound):
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Student not found"})
+				case errors.Is(err, ErrAlreadyEnrolled), errors.Is(err, ErrEnrollmentHasResponses):
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				case errors.Is(err, ErrNotStudent), errors.Is(err, ErrSectionNotInOffering):
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				default:
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update enrollment"})
+				}
+				return
+			}
+			db.Preload("Student").Preload("Subject").Preload("Semester").Preload("Section").First(&enrollment, enrollment.ID)
+			c.JSON(http.StatusOK, gin.H{"enrollment": enrollment})
+		})
+
+		adminGroup.DELETE("/enrollments/:id", func(c *gin.Context) {
+			var enrollment StudentEnrollment
+			if err := db.Where("id = ?", c.Param("id")).First(&enrollment).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Enrollment not found"})
+				return
+			}
+			if err := DeleteEnrollment(db, enrollment); err != nil {
+				if errors.Is(err, ErrEnrollmentHasResponses) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete enrollment"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Enrollment deleted successfully"})
+		})
+
+		// Bulk import from CSV, sent as the "file" form field or as the request body.
+		// With ?dry_run=true rows are only validated; otherwise nothing is created unless every row is valid.
+		importCSV := func(importer func(*gorm.DB, io.Reader, bool) (ImportResult, error)) gin.HandlerFunc {
+			return func(c *gin.Context) {
+				var body io.Reader = c.Request.Body
+				if file, err := c.FormFile("file"); err == nil {
+					f, err := file.Open()
+					if err != nil {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+						return
+					}
+					defer f.Close()
+					body = f
+				}
+
+				result, err := importer(db, body, c.Query("dry_run") == "true")
+				if errors.Is(err, ErrInvalidCSV) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import"})
+					return
+				}
+				switch {
+				case result.DryRun:
+					c.JSON(http.StatusOK, gin.H{"result": result})
+				case len(result.Errors) > 0:
+					c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Some rows are invalid, nothing was imported", "result": result})
+				default:
+					c.JSON(http.StatusCreated, gin.H{"result": result})
+				}
+			}
+		}
+		adminGroup.POST("/import/users", importCSV(ImportUsers))
+		adminGroup.POST("/import/enrollments", importCSV(ImportEnrollments))
+
+		// View All Responses
+		adminGroup.GET("/responses", func(c *gin.Context) {
+			var responses []Response
+			if err := db.Preload("Survey").Preload("Question").Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			// Surveys below the minimum cohort only report their submission count
+			responses, cohorts, err := FilterByCohort(db, responses, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			// Return anonymous responses (without student identity)
+			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses), "cohorts": cohorts})
+		})
+
+		// Get all users
+		adminGroup.GET("/users", func(c *gin.Context) {
+			var users []User
+			if err := db.Find(&users).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"users": users})
+		})
+
+		// List users whose requested role differs from their current role
+		adminGroup.GET("/role-requests", func(c *gin.Context) {
+			var users []User
+			if err := db.Where("requested_role <> role").Find(&users).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role requests"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"users": users})
+		})
+
+		// Update a user's effective role (approve/reject role requests)
+		adminGroup.PUT("/users/:id/role", func(c *gin.Context) {
+			userIDParam := c.Param("id")
+			userID, err := strconv.ParseUint(userIDParam, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+				return
+			}
+
+			var body struct {
+				Role string `json:"role"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			if !isRole(body.Role) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+				return
+			}
+
+			var user User
+			if err := db.First(&user, userID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+
+			roleChanged := user.Role != body.Role
+			user.Role = body.Role
+			user.RequestedRole = body.Role
+
+			if err := db.Save(&user).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+				return
+			}
+			// Sessions started with the old role have to log in again
+			if roleChanged {
+				if _, err := RevokeUserSessions(db, user.ID, time.Now()); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+					return
+				}
+			}
+
+			c.JSON(http.StatusOK, gin.H{"user": user})
+		})
+
+		// Fix a user's name or email (empty fields are kept)
+		adminGroup.PUT("/users/:id", func(c *gin.Context) {
+			var user User
+			if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+
+			var body struct {
+				FirstName string `json:"first_name"`
+				LastName  string `json:"last_name"`
+				Email     string `json:"email"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			updates := map[string]interface{}{}
+			if body.FirstName != "" {
+				updates["first_name"] = body.FirstName
+			}
+			if body.LastName != "" {
+				updates["last_name"] = body.LastName
+			}
+			if email := normalizeEmail(body.Email); email != "" && email != user.Email {
+				// Emails are stored lowercased so login and password resets find the same account
+				var count int64
+				if err := db.Model(&User{}).Where("LOWER(email) = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+					return
+				}
+				if count > 0 {
+					c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+					return
+				}
+				if err := CheckEmailDomain(db, email); err != nil {
+					if errors.Is(err, ErrEmailDomainNotAllowed) {
+						c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": ErrCodeEmailDomainNotAllowed})
+						return
+					}
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email domain"})
+					return
+				}
+				updates["email"] = email
+			}
+			if len(updates) > 0 {
+				if err := db.Model(&user).Updates(updates).Error; err != nil {
+					if isUniqueViolation(err) {
+						c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+						return
+					}
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+					return
+				}
+			}
+			user.Password = ""
+			c.JSON(http.StatusOK, gin.H{"user": user})
+		})
+
+		// Users are disabled rather than deleted, so their surveys and responses stay intact
+		adminGroup.DELETE("/users/:id", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			admin := currentUser.(User)
+
+			var user User
+			if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if user.ID == admin.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account"})
+				return
+			}
+			if !user.IsDisabled() {
+				if err := DisableUser(db, &user, time.Now()); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable user"})
+					return
+				}
+			}
+			if _, err := RevokeUserSessions(db, user.ID, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+				return
+			}
+			user.Password = ""
+			c.JSON(http.StatusOK, gin.H{"user": user})
+		})
+
+		// List a user's active sessions
+		adminGroup.GET("/users/:id/sessions", func(c *gin.Context) {
+			var sessions []Session
+			if err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", c.Param("id"), time.Now()).
+				Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+		})
+
+		// Log a user out everywhere, e.g. after a lost device or a compromised password
+		adminGroup.DELETE("/users/:id/sessions", func(c *gin.Context) {
+			var user User
+			if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			revoked, err := RevokeUserSessions(db, user.ID, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+		})
+
+		// Confirm a user's email for them, e.g. when the verification email doesn't arrive
+		adminGroup.PUT("/users/:id/verify", func(c *gin.Context) {
+			var user User
+			if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if err := MarkEmailVerified(db, &user); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
+				return
+			}
+			user.Password = ""
+			c.JSON(http.StatusOK, gin.H{"user": user})
+		})
+
+		// Email domains self-registration is restricted to. With none, any domain can register.
+		adminGroup.GET("/allowed-domains", func(c *gin.Context) {
+			var domains []AllowedDomain
+			if err := db.Order("domain").Find(&domains).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allowed domains"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"domains": domains})
+		})
+
+		adminGroup.POST("/allowed-domains", func(c *gin.Context) {
+			var body struct {
+				Domain string `json:"domain" binding:"required"`
+			}
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Domain is required"})
+				return
+			}
+
+			domain, err := AddAllowedDomain(db, body.Domain)
+			switch {
+			case errors.Is(err, ErrInvalidDomain):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			case isUniqueViolation(err):
+				c.JSON(http.StatusConflict, gin.H{"error": "Domain is already allowed"})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add allowed domain"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"domain": domain})
+		})
+
+		adminGroup.DELETE("/allowed-domains/:id", func(c *gin.Context) {
+			result := db.Where("id = ?", c.Param("id")).Delete(&AllowedDomain{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove allowed domain"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Allowed domain not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Allowed domain removed"})
+		})
+
+		adminGroup.PUT("/users/:id/enable", func(c *gin.Context) {
+			var user User
+			if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if err := EnableUser(db, &user); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable user"})
+				return
+			}
+			user.Password = ""
+			c.JSON(http.StatusOK, gin.H{"user": user})
+		})
+
+		// Survey archive: admins can archive and restore any survey, and purge archived ones for good
+		adminGroup.GET("/surveys/archived", func(c *gin.Context) {
+			var surveys []Survey
+			if err := ArchivedSurveys(db).Preload("Subject").Preload("Semester").Preload("Section").Preload("Professor").
+				Order("deleted_at DESC").Find(&surveys).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
+		})
+
+		adminGroup.POST("/surveys/:id/archive", func(c *gin.Context) {
+			var survey Survey
+			if err := db.Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found"})
+				return
+			}
+			if err := ArchiveSurvey(db, &survey, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive survey"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
+
+		adminGroup.POST("/surveys/:id/restore", func(c *gin.Context) {
+			var survey Survey
+			if err := ArchivedSurveys(db).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Archived survey not found"})
+				return
+			}
+			if err := RestoreSurvey(db, &survey); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore survey"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
+
+		// Move any survey through its lifecycle
+		adminGroup.PUT("/surveys/:id/status", func(c *gin.Context) {
+			var survey Survey
+			if err := db.Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found"})
+				return
+			}
+
+			var request struct {
+				Status string `json:"status" binding:"required"`
+			}
+			if err := c.ShouldBindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+				return
+			}
+
+			var transitionErr *SurveyTransitionError
+			err := TransitionSurvey(db, &survey, request.Status, time.Now())
+			switch {
+			case errors.Is(err, ErrInvalidSurveyStatus):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			case errors.As(err, &transitionErr), errors.Is(err, ErrSurveyHasNoQuestions):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change survey status"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
+
+		// Permanently delete an archived survey with its questions and responses
+		adminGroup.DELETE("/surveys/:id/purge", func(c *gin.Context) {
+			var survey Survey
+			if err := db.Unscoped().Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found"})
+				return
+			}
+			if err := PurgeSurvey(db, survey); err != nil {
+				if errors.Is(err, ErrSurveyNotArchived) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge survey"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Survey purged successfully"})
+		})
+
+		// Seed database endpoint (admin only)
+		adminGroup.POST("/seed", func(c *gin.Context) {
+			seedDatabase(db)
+			c.JSON(http.StatusOK, gin.H{"message": "Database seeded successfully"})
+		})
+	}
+
+	// =============================================================================
+	// PROFESSOR ENDPOINTS
+	// =============================================================================
+
+	professorGroup := r.Group("/professor")
+	professorGroup.Use(RequireRole(RoleProfessor, RoleCoordinator))
+	{
+		// Get professor's subjects
+		professorGroup.GET("/subjects", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var subjects []Subject
+			if err := StaffSubjects(db, user).Find(&subjects).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"subjects": subjects})
+		})
+
+		// Sections of the professor's subjects, optionally in one semester
+		professorGroup.GET("/sections", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			query := db.Preload("Subject").Preload("Semester").Preload("Professor", publicUser).
+				Where("(subject_id IN (?) OR professor_id = ?)", StaffSubjects(db.Model(&Subject{}), user).Select("subjects.id"), user.ID)
+			if semesterID := c.Query("semester_id"); semesterID != "" {
+				query = query.Where("semester_id = ?", semesterID)
+			}
+
+			var sections []Section
+			if err := query.Order("code ASC").Find(&sections).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sections"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"sections": sections})
+		})
+
+		// Cross-semester NPS and rating trends of a subject
+		professorGroup.GET("/subjects/:id/trends", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			// Verify that the user teaches the subject
+			var subject Subject
+			if err := StaffSubjects(db, user).Where("id = ?", c.Param("id")).First(&subject).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Subject not found or access denied"})
+				return
+			}
+
+			trends, err := LoadSubjectTrends(db, subject, TrendOptions{
+				MinCohort:     minCohortSize,
+				ApplyEmbargo:  true,
+				EmbargoPolicy: defaultEmbargo,
+				Now:           time.Now(),
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trends"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"trends": trends})
+		})
+
+		// Create survey
+		professorGroup.POST("/surveys", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := c.BindJSON(&survey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			// Verify that the professor can manage the subject (or section) in the semester
+			allowed, err := CanCreateSurvey(db, user, survey.SubjectID, survey.SemesterID, survey.SectionID)
+			if errors.Is(err, ErrSectionNotInOffering) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify subject access"})
+				return
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only create surveys for your subjects"})
+				return
+			}
+
+			if err := ValidateSurveyWindow(survey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := ValidateEmbargoPolicy(survey.ResultsEmbargo); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			// New surveys start as drafts and move on through PUT /surveys/:id/status
+			survey.ProfessorID = user.ID
+			survey.Status = SurveyStatusDraft
+			survey.DeletedAt = gorm.DeletedAt{}
+			if err := db.Create(&survey).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create survey"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"survey": survey})
+		})
+
+		// Get professor's surveys
+		professorGroup.GET("/surveys", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var surveys []Survey
+			if err := ReadableSurveys(db.Preload("Subject").Preload("Semester").Preload("Section").Preload("Questions"), user).Find(&surveys).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+				return
+			}
+			manageable, err := ManageableSurveyIDs(db, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+				return
+			}
+
+			rates, err := ComputeResponseRates(db, surveys)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
+				return
+			}
+			for i := range surveys {
+				rate := rates[surveys[i].ID]
+				surveys[i].ResponseRate = &rate
+				surveys[i].Access = SurveyAccessRead
+				if manageable[surveys[i].ID] {
+					surveys[i].Access = SurveyAccessManage
+				}
+			}
+			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
+		})
+
+		// Get one survey with its questions, response rate and window status
+		professorGroup.GET("/surveys/:id", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ReadableSurveys(db.Preload("Subject").Preload("Semester").Preload("Section").Preload("Questions", func(db *gorm.DB) *gorm.DB {
+				return db.Order("\"order\" ASC")
+			}).Preload("Questions.Staff", func(db *gorm.DB) *gorm.DB {
+				return db.Select("id", "first_name", "last_name")
+			}), user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+			manageable, err := ManageableSurveyIDs(db, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch survey"})
+				return
+			}
+			rates, err := ComputeResponseRates(db, []Survey{survey})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
+				return
+			}
+
+			rate := rates[survey.ID]
+			survey.ResponseRate = &rate
+			survey.WindowStatus = survey.WindowStatusAt(time.Now())
+			survey.Access = SurveyAccessRead
+			if manageable[survey.ID] {
+				survey.Access = SurveyAccessManage
+			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
+
+		// Update the title, description, window, active flag, anonymity or embargo of a survey
+		professorGroup.PUT("/surveys/:id", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			var changes SurveyChanges
+			if err := c.BindJSON(&changes); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+			original := survey
+			if err := changes.Apply(&survey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			err := CheckSurveyChange(db, original, survey)
+			switch {
+			case errors.Is(err, ErrCampaignSurveySettings):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			case errors.Is(err, ErrAnonymityLocked), errors.Is(err, ErrEmbargoLocked):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update survey"})
+				return
+			}
+
+			if err := SaveSurveyChanges(db, &survey); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update survey"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
+
+		// Delete a survey nobody answered; answered surveys are archived instead
+		professorGroup.DELETE("/surveys/:id", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			err := DeleteSurvey(db, survey)
+			switch {
+			case errors.Is(err, ErrCampaignSurveyDelete):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			case errors.Is(err, ErrSurveyHasResponses):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete survey"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Survey deleted successfully"})
+		})
+
+		// Create survey from a template
+		professorGroup.POST("/surveys/from-template", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var req SurveyInstanceRequest
+			if err := c.ShouldBindJSON(&req); err != nil || req.TemplateID == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			var template SurveyTemplate
+			if err := VisibleTemplates(db, user).Preload("Questions").First(&template, req.TemplateID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+				return
+			}
+
+			// Verify that the professor can manage the subject (or section) in the semester
+			allowed, err := CanCreateSurvey(db, user, req.SubjectID, req.SemesterID, req.SectionID)
+			if errors.Is(err, ErrSectionNotInOffering) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify subject access"})
+				return
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only create surveys for your subjects"})
+				return
+			}
+
+			survey := req.Apply(TemplateSurvey(template))
+			if err := ValidateSurveyWindow(survey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			survey.ProfessorID = user.ID
+			if err := CreateSurveyWithQuestions(db, &survey, QuestionsFromTemplate(template)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create survey"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"survey": survey})
+		})
+
+		// Clone survey into another subject and/or semester
+		professorGroup.POST("/surveys/:id/clone", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+
+			// Verify survey access
+			var source Survey
+			if err := ReadableSurveys(db.Preload("Questions"), user).Where("id = ?", surveyID).First(&source).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			var req SurveyInstanceRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			// Verify that the professor can manage the target subject (or section) in the semester
+			allowed, err := CanCreateSurvey(db, user, req.SubjectID, req.SemesterID, req.SectionID)
+			if errors.Is(err, ErrSectionNotInOffering) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify subject access"})
+				return
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only create surveys for your subjects"})
+				return
+			}
+
+			survey := req.Apply(source)
+			if err := ValidateSurveyWindow(survey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			// Questions about staff members who don't teach the target offering are left out
+			staff, err := OfferingStaff(db, survey.SubjectID, survey.SemesterID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+				return
+			}
+			questions := make([]Question, 0, len(source.Questions))
+			for _, q := range CloneQuestions(source.Questions) {
+				if q.StaffID == 0 || IsOfferingStaff(staff, q.StaffID) {
+					questions = append(questions, q)
+				}
+			}
+
+			survey.ProfessorID = user.ID
+			if err := CreateSurveyWithQuestions(db, &survey, questions); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone survey"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"survey": survey})
+		})
+
+		// Add question to survey
+		professorGroup.POST("/surveys/:id/questions", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+
+			// Verify that the user can manage the survey
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			var question Question
+			if err := c.BindJSON(&question); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			if question.StaffID != 0 {
+				staff, err := OfferingStaff(db, survey.SubjectID, survey.SemesterID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+					return
+				}
+				if !IsOfferingStaff(staff, question.StaffID) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": ErrNotOfferingStaff.Error()})
+					return
+				}
+			}
+
+			surveyIDUint, _ := strconv.ParseUint(surveyID, 10, 32)
+			question.SurveyID = uint(surveyIDUint)
+			question.CampaignQuestion = false
+			question.Staff = nil
+
+			// Students who already answered would have skipped a new required question
+			if err := CheckQuestionAdd(db, question); err != nil {
+				if errors.Is(err, ErrQuestionLocked) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": ErrCodeQuestionLocked})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create question"})
+				return
+			}
+			if err := db.Create(&question).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create question"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"question": question})
+		})
+
+		// Add one copy of a question per staff member of the survey's offering
+		professorGroup.POST("/surveys/:id/staff-questions", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+
+			// Verify that the user can manage the survey
+			var survey Survey
+			if err := ManageableSurveys(db.Preload("Questions"), user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			var question Question
+			if err := c.BindJSON(&question); err != nil || !isQuestionType(question.Type) || question.Text == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			staff, err := OfferingStaff(db, survey.SubjectID, survey.SemesterID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+				return
+			}
+
+			// Staff questions go after the existing ones
+			nextOrder := 1
+			for _, q := range survey.Questions {
+				if q.Order >= nextOrder {
+					nextOrder = q.Order + 1
+				}
+			}
+			question.SurveyID = survey.ID
+			if err := CheckQuestionAdd(db, question); err != nil {
+				if errors.Is(err, ErrQuestionLocked) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": ErrCodeQuestionLocked})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create questions"})
+				return
+			}
+			questions := StaffQuestions(question, staff, nextOrder)
+			if err := db.Create(&questions).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create questions"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"questions": questions})
+		})
+
+		// Staff of the survey's offering
+		professorGroup.GET("/surveys/:id/staff", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			// Verify survey access
+			var survey Survey
+			if err := ReadableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			staff, err := OfferingStaff(db, survey.SubjectID, survey.SemesterID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"staff": staff})
+		})
+
+		// Update question
+		professorGroup.PUT("/surveys/:id/questions/:questionId", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+			questionID := c.Param("questionId")
+
+			// Verify that the user can manage the survey
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			// Find the question
+			var question Question
+			if err := db.Where("id = ? AND survey_id = ?", questionID, surveyID).First(&question).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+				return
+			}
+			if question.CampaignQuestion {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Campaign questions cannot be changed"})
+				return
+			}
+
+			// Bind update data
+			var updateData struct {
+				Text     string `json:"text"`
+				Type     string `json:"type"`
+				Required *bool  `json:"required"` // left out keeps the current value
+				Options  string `json:"options"`
+				Order    int    `json:"order"`
+			}
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"subjects": subjects})
-		})
 
-		// Student Enrollment Management
-		adminGroup.POST("/enrollments", func(c *gin.Context) {
-			var enrollment StudentEnrollment
-			if err := c.BindJSON(&enrollment); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			// Update fields
+			original := question
+			if updateData.Text != "" {
+				question.Text = updateData.Text
+			}
+			if updateData.Type != "" {
+				question.Type = updateData.Type
+			}
+			if updateData.Required != nil {
+				question.Required = *updateData.Required
+			}
+			if updateData.Options != "" {
+				question.Options = updateData.Options
+			}
+			if updateData.Order > 0 {
+				question.Order = updateData.Order
+			}
+
+			// Answers already given must keep their meaning
+			if err := CheckQuestionChange(db, original, question); err != nil {
+				if errors.Is(err, ErrQuestionLocked) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": ErrCodeQuestionLocked})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
 				return
 			}
-			if err := db.Create(&enrollment).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create enrollment"})
+
+			if err := db.Save(&question).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
 				return
 			}
-			c.JSON(http.StatusCreated, gin.H{"enrollment": enrollment})
+			c.JSON(http.StatusOK, gin.H{"question": question})
 		})
 
-		adminGroup.GET("/enrollments", func(c *gin.Context) {
-			var enrollments []StudentEnrollment
-			if err := db.Preload("Student").Preload("Subject").Preload("Semester").Find(&enrollments).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollments"})
+		// Delete question
+		professorGroup.DELETE("/surveys/:id/questions/:questionId", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+			questionID := c.Param("questionId")
+
+			// Verify that the user can manage the survey
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"enrollments": enrollments})
-		})
 
-		// View All Responses
-		adminGroup.GET("/responses", func(c *gin.Context) {
-			var responses []Response
-			if err := db.Preload("Survey").Preload("Question").Find(&responses).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+			var question Question
+			if err := db.Where("id = ? AND survey_id = ?", questionID, surveyID).First(&question).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
 				return
 			}
-			// Return anonymous responses (without student identity)
-			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses)})
+			if question.CampaignQuestion {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Campaign questions cannot be deleted"})
+				return
+			}
+			if err := CheckQuestionDelete(db, question); err != nil {
+				if errors.Is(err, ErrQuestionLocked) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": ErrCodeQuestionLocked})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+				return
+			}
+
+			// Soft-delete the question, keeping the answers it already has
+			if err := DeleteQuestion(db, &question, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully", "question": question})
 		})
 
-		// Get all users
-		adminGroup.GET("/users", func(c *gin.Context) {
-			var users []User
-			if err := db.Find(&users).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		// List the deleted questions of a survey, which can still be restored
+		professorGroup.GET("/surveys/:id/questions/deleted", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"users": users})
+
+			var questions []Question
+			if err := db.Unscoped().Where("survey_id = ? AND deleted_at IS NOT NULL", survey.ID).Order("deleted_at DESC").Find(&questions).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"questions": questions})
 		})
 
-		// List users whose requested role differs from their current role
-		adminGroup.GET("/role-requests", func(c *gin.Context) {
-			var users []User
-			if err := db.Where("requested_role <> role").Find(&users).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role requests"})
+		professorGroup.POST("/surveys/:id/questions/:questionId/restore", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"users": users})
+
+			var question Question
+			if err := db.Unscoped().Where("id = ? AND survey_id = ?", c.Param("questionId"), survey.ID).First(&question).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+				return
+			}
+			if err := RestoreQuestion(db, &question); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore question"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"question": question})
 		})
 
-		// Update a user's effective role (approve/reject role requests)
-		adminGroup.PUT("/users/:id/role", func(c *gin.Context) {
-			userIDParam := c.Param("id")
-			userID, err := strconv.ParseUint(userIDParam, 10, 64)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		// Move a survey through its lifecycle: draft, published, open, closed, archived
+		professorGroup.PUT("/surveys/:id/status", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
 
-			var body struct {
-				Role string `json:"role"`
+			var request struct {
+				Status string `json:"status" binding:"required"`
 			}
-			if err := c.BindJSON(&body); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			if err := c.ShouldBindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
 				return
 			}
 
-			if body.Role != RoleStudent && body.Role != RoleProfessor && body.Role != RoleAdmin {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			var transitionErr *SurveyTransitionError
+			err := TransitionSurvey(db, &survey, request.Status, time.Now())
+			switch {
+			case errors.Is(err, ErrInvalidSurveyStatus):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			case errors.As(err, &transitionErr), errors.Is(err, ErrSurveyHasNoQuestions):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change survey status"})
 				return
 			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
 
-			var user User
-			if err := db.First(&user, userID).Error; err != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		// Archive a survey, hiding it with its questions and responses until restored
+		professorGroup.POST("/surveys/:id/archive", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var survey Survey
+			if err := ManageableSurveys(db, user).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+			if err := ArchiveSurvey(db, &survey, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive survey"})
 				return
 			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
 
-			user.Role = body.Role
-			user.RequestedRole = body.Role
+		professorGroup.POST("/surveys/:id/restore", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
 
-			if err := db.Save(&user).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+			var survey Survey
+			if err := ArchivedSurveys(ManageableSurveys(db, user)).Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Archived survey not found or access denied"})
+				return
+			}
+			if err := RestoreSurvey(db, &survey); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore survey"})
 				return
 			}
+			c.JSON(http.StatusOK, gin.H{"survey": survey})
+		})
 
-			c.JSON(http.StatusOK, gin.H{"user": user})
+		professorGroup.GET("/surveys/archived", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var surveys []Survey
+			if err := ArchivedSurveys(ReadableSurveys(db, user)).Preload("Subject").Preload("Semester").Preload("Section").
+				Order("deleted_at DESC").Find(&surveys).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
 		})
 
-		// Seed database endpoint (admin only)
-		adminGroup.POST("/seed", func(c *gin.Context) {
-			seedDatabase(db)
-			c.JSON(http.StatusOK, gin.H{"message": "Database seeded successfully"})
+		// Get responses for professor's surveys
+		professorGroup.GET("/responses", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+
+			var responses []Response
+			if err := db.Preload("Survey.Semester").Preload("Question").
+				Where("survey_id IN (?)", ReadableSurveys(db.Model(&Survey{}), user).Select("surveys.id")).
+				Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			responses = FilterByEmbargo(responses, defaultEmbargo, time.Now())
+			responses, cohorts, err := FilterByCohort(db, responses, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			// Return anonymous responses (without student identity)
+			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses), "cohorts": cohorts})
 		})
-	}
 
-	// =============================================================================
-	// PROFESSOR ENDPOINTS
-	// =============================================================================
+		// Get responses for specific survey
+		professorGroup.GET("/surveys/:id/responses", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
 
-	professorGroup := r.Group("/professor")
-	professorGroup.Use(RequireRole(RoleProfessor))
-	{
-		// Get professor's subjects
-		professorGroup.GET("/subjects", func(c *gin.Context) {
+			// Verify survey access
+			var survey Survey
+			if err := ReadableSurveys(db.Preload("Semester"), user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			if embargo := SurveyEmbargo(survey, defaultEmbargo, time.Now()); embargo.Active {
+				c.JSON(http.StatusOK, gin.H{"responses": []AnonymousResponse{}, "embargo": embargo})
+				return
+			}
+
+			cohort, err := SurveyCohort(db, survey.ID, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			if !cohort.Met() {
+				c.JSON(http.StatusOK, gin.H{"responses": []AnonymousResponse{}, "cohort": cohort})
+				return
+			}
+
+			var responses []Response
+			if err := db.Preload("Question").Where("survey_id = ?", surveyID).Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			// Return anonymous responses (without student identity)
+			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses), "cohort": cohort})
+		})
+
+		// Export responses for specific survey as a spreadsheet
+		professorGroup.GET("/surveys/:id/responses/export", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
+			surveyID := c.Param("id")
 
-			var subjects []Subject
-			if err := db.Where("professor_id = ?", user.ID).Find(&subjects).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+			format := c.DefaultQuery("format", ExportFormatCSV)
+			if format != ExportFormatCSV && format != ExportFormatXLSX {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export format, use csv or xlsx"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"subjects": subjects})
+
+			// Verify survey access
+			var survey Survey
+			if err := ReadableSurveys(db.Preload("Questions.Staff").Preload("Semester"), user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			if embargo := SurveyEmbargo(survey, defaultEmbargo, time.Now()); embargo.Active {
+				c.JSON(http.StatusConflict, gin.H{"error": "Results are embargoed", "code": ErrCodeResultsEmbargoed, "embargo": embargo})
+				return
+			}
+
+			cohort, err := SurveyCohort(db, survey.ID, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			if !cohort.Met() {
+				c.JSON(http.StatusConflict, gin.H{"error": "Not enough responses to export results", "code": CohortStatusNotEnoughResponses, "cohort": cohort})
+				return
+			}
+
+			var responses []Response
+			if err := db.Where("survey_id = ?", surveyID).Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+
+			// Rows are anonymous: student identity is only used to group answers
+			table := BuildResponseTable(survey.Questions, responses)
+			var buf bytes.Buffer
+			contentType := "text/csv; charset=utf-8"
+			if format == ExportFormatXLSX {
+				contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+				err = table.WriteXLSX(&buf)
+			} else {
+				err = table.WriteCSV(&buf)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export responses"})
+				return
+			}
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"survey-%d-responses.%s\"", survey.ID, format))
+			c.Data(http.StatusOK, contentType, buf.Bytes())
 		})
 
-		// Create survey
-		professorGroup.POST("/surveys", func(c *gin.Context) {
+		// Get aggregated analytics for specific survey
+		professorGroup.GET("/surveys/:id/analytics", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
+			surveyID := c.Param("id")
 
+			// Verify survey access
 			var survey Survey
-			if err := c.BindJSON(&survey); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			if err := ReadableSurveys(db.Preload("Questions.Staff").Preload("Semester"), user).Where("id = ?", surveyID).First(&survey).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
 
-			// Verify that the professor owns the subject
-			var subject Subject
-			if err := db.Where("id = ? AND professor_id = ?", survey.SubjectID, user.ID).First(&subject).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You can only create surveys for your subjects"})
+			if embargo := SurveyEmbargo(survey, defaultEmbargo, time.Now()); embargo.Active {
+				c.JSON(http.StatusOK, gin.H{"analytics": nil, "embargo": embargo})
 				return
 			}
 
-			survey.ProfessorID = user.ID
-			if err := db.Create(&survey).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create survey"})
+			cohort, err := SurveyCohort(db, survey.ID, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			if !cohort.Met() {
+				c.JSON(http.StatusOK, gin.H{"analytics": nil, "cohort": cohort})
+				return
+			}
+
+			var responses []Response
+			if err := db.Where("survey_id = ?", surveyID).Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
 				return
 			}
-			c.JSON(http.StatusCreated, gin.H{"survey": survey})
+			// Only aggregates are returned, never individual answers or student identity
+			c.JSON(http.StatusOK, gin.H{"analytics": BuildSurveyAnalytics(survey, responses), "cohort": cohort})
 		})
+	}
 
-		// Get professor's surveys
-		professorGroup.GET("/surveys", func(c *gin.Context) {
+	// =============================================================================
+	// COORDINATOR ENDPOINTS (aggregates for the subjects of their units)
+	// =============================================================================
+
+	coordinatorGroup := r.Group("/coordinator")
+	coordinatorGroup.Use(RequireRole(RoleCoordinator))
+	{
+		// Subjects in the coordinator's departments and programs
+		coordinatorGroup.GET("/subjects", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
 
-			var surveys []Survey
-			if err := db.Preload("Subject").Preload("Semester").Preload("Questions").Where("professor_id = ?", user.ID).Find(&surveys).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+			subjectIDs, err := CoordinatedSubjectIDs(db, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
+			subjects := []Subject{}
+			if len(subjectIDs) > 0 {
+				if err := db.Preload("Professor", publicUser).Preload("Department").Preload("Program").
+					Where("id IN ?", subjectIDs).Order("code ASC").Find(&subjects).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subjects"})
+					return
+				}
+			}
+			c.JSON(http.StatusOK, gin.H{"subjects": subjects})
 		})
 
-		// Add question to survey
-		professorGroup.POST("/surveys/:id/questions", func(c *gin.Context) {
+		// Surveys of the coordinated subjects with their response rates
+		coordinatorGroup.GET("/surveys", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
-			surveyID := c.Param("id")
 
-			// Verify survey ownership
-			var survey Survey
-			if err := db.Where("id = ? AND professor_id = ?", surveyID, user.ID).First(&survey).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+			subjectIDs, err := CoordinatedSubjectIDs(db, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
 				return
 			}
-
-			var question Question
-			if err := c.BindJSON(&question); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-				return
+			surveys := []Survey{}
+			if len(subjectIDs) > 0 {
+				if err := db.Preload("Subject").Preload("Semester").Preload("Professor", publicUser).
+					Where("subject_id IN ?", subjectIDs).Find(&surveys).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
+					return
+				}
 			}
 
-			surveyIDUint, _ := strconv.ParseUint(surveyID, 10, 32)
-			question.SurveyID = uint(surveyIDUint)
-			if err := db.Create(&question).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create question"})
+			rates, err := ComputeResponseRates(db, surveys)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
 				return
 			}
-			c.JSON(http.StatusCreated, gin.H{"question": question})
+			for i := range surveys {
+				rate := rates[surveys[i].ID]
+				surveys[i].ResponseRate = &rate
+			}
+			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
 		})
 
-		// Update question
-		professorGroup.PUT("/surveys/:id/questions/:questionId", func(c *gin.Context) {
+		// Aggregated analytics of a survey in the coordinator's units
+		coordinatorGroup.GET("/surveys/:id/analytics", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
-			surveyID := c.Param("id")
-			questionID := c.Param("questionId")
 
-			// Verify survey ownership
 			var survey Survey
-			if err := db.Where("id = ? AND professor_id = ?", surveyID, user.ID).First(&survey).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+			if err := db.Preload("Questions.Staff").Preload("Semester").Where("id = ?", c.Param("id")).First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found"})
 				return
 			}
-
-			// Find the question
-			var question Question
-			if err := db.Where("id = ? AND survey_id = ?", questionID, surveyID).First(&question).Error; err != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+			coordinates, err := CoordinatesSubject(db, user, survey.SubjectID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch survey"})
 				return
 			}
-
-			// Bind update data
-			var updateData struct {
-				Text     string `json:"text"`
-				Type     string `json:"type"`
-				Required bool   `json:"required"`
-				Options  string `json:"options"`
-				Order    int    `json:"order"`
-			}
-			if err := c.BindJSON(&updateData); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			if !coordinates {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
 
-			// Update fields
-			if updateData.Text != "" {
-				question.Text = updateData.Text
-			}
-			if updateData.Type != "" {
-				question.Type = updateData.Type
+			// Coordinators may teach in their own units, so they wait for the embargo like professors
+			if embargo := SurveyEmbargo(survey, defaultEmbargo, time.Now()); embargo.Active {
+				c.JSON(http.StatusOK, gin.H{"analytics": nil, "embargo": embargo})
+				return
 			}
-			question.Required = updateData.Required
-			if updateData.Options != "" {
-				question.Options = updateData.Options
+
+			cohort, err := SurveyCohort(db, survey.ID, minCohortSize)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
 			}
-			if updateData.Order > 0 {
-				question.Order = updateData.Order
+			if !cohort.Met() {
+				c.JSON(http.StatusOK, gin.H{"analytics": nil, "cohort": cohort})
+				return
 			}
 
-			if err := db.Save(&question).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
+			var responses []Response
+			if err := db.Where("survey_id = ?", survey.ID).Find(&responses).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"question": question})
+			c.JSON(http.StatusOK, gin.H{"analytics": BuildSurveyAnalytics(survey, responses), "cohort": cohort})
 		})
 
-		// Delete question
-		professorGroup.DELETE("/surveys/:id/questions/:questionId", func(c *gin.Context) {
+		// Response rates of the coordinated subjects offered in a semester
+		coordinatorGroup.GET("/semesters/:id/response-rates", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
-			surveyID := c.Param("id")
-			questionID := c.Param("questionId")
 
-			// Verify survey ownership
-			var survey Survey
-			if err := db.Where("id = ? AND professor_id = ?", surveyID, user.ID).First(&survey).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+			semesterID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid semester ID"})
 				return
 			}
 
-			// Delete the question
-			if err := db.Where("id = ? AND survey_id = ?", questionID, surveyID).Delete(&Question{}).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+			var semester Semester
+			if err := db.First(&semester, semesterID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Semester not found"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully"})
-		})
-
-		// Get responses for professor's surveys
-		professorGroup.GET("/responses", func(c *gin.Context) {
-			currentUser, _ := c.Get("currentUser")
-			user := currentUser.(User)
 
-			var responses []Response
-			if err := db.Preload("Survey").Preload("Question").
-				Joins("JOIN surveys ON responses.survey_id = surveys.id").
-				Where("surveys.professor_id = ?", user.ID).
-				Find(&responses).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+			subjectIDs, err := CoordinatedSubjectIDs(db, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
 				return
 			}
-			// Return anonymous responses (without student identity)
-			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses)})
+			subjects, err := SemesterResponseRates(db, semester.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response rates"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"semester": semester, "subjects": FilterSubjectResponseRates(subjects, subjectIDs)})
 		})
 
-		// Get responses for specific survey
-		professorGroup.GET("/surveys/:id/responses", func(c *gin.Context) {
+		// Cross-semester NPS and rating trends of a coordinated subject
+		coordinatorGroup.GET("/subjects/:id/trends", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
 			user := currentUser.(User)
-			surveyID := c.Param("id")
 
-			// Verify survey ownership
-			var survey Survey
-			if err := db.Where("id = ? AND professor_id = ?", surveyID, user.ID).First(&survey).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Survey not found or access denied"})
+			subjectID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject ID"})
+				return
+			}
+			coordinates, err := CoordinatesSubject(db, user, uint(subjectID))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trends"})
+				return
+			}
+			var subject Subject
+			if !coordinates || db.First(&subject, subjectID).Error != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Subject not found or access denied"})
 				return
 			}
 
-			var responses []Response
-			if err := db.Preload("Question").Where("survey_id = ?", surveyID).Find(&responses).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+			trends, err := LoadSubjectTrends(db, subject, TrendOptions{
+				MinCohort:     minCohortSize,
+				ApplyEmbargo:  true,
+				EmbargoPolicy: defaultEmbargo,
+				Now:           time.Now(),
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trends"})
 				return
 			}
-			// Return anonymous responses (without student identity)
-			c.JSON(http.StatusOK, gin.H{"responses": ToAnonymousList(responses)})
+			c.JSON(http.StatusOK, gin.H{"trends": trends})
 		})
 	}
 
+	// =============================================================================
+	// SURVEY TEMPLATE ENDPOINTS (same handlers under /admin and /professor)
+	// =============================================================================
+
+	// Admins see and manage every template; professors see their own and the
+	// shared ones, and manage only their own
+	listTemplates := func(c *gin.Context) {
+		currentUser, _ := c.Get("currentUser")
+		user := currentUser.(User)
+
+		var templates []SurveyTemplate
+		if err := VisibleTemplates(db, user).Preload("Questions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"order\" ASC")
+		}).Order("name ASC").Find(&templates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"templates": templates})
+	}
+
+	getTemplate := func(c *gin.Context) {
+		currentUser, _ := c.Get("currentUser")
+		user := currentUser.(User)
+
+		var template SurveyTemplate
+		if err := VisibleTemplates(db, user).Preload("Questions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"order\" ASC")
+		}).Where("id = ?", c.Param("id")).First(&template).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"template": template})
+	}
+
+	createTemplate := func(c *gin.Context) {
+		currentUser, _ := c.Get("currentUser")
+		user := currentUser.(User)
+
+		var template SurveyTemplate
+		if err := c.BindJSON(&template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+		if err := ValidateTemplate(template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := CreateTemplate(db, user, &template); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"template": template})
+	}
+
+	updateTemplate := func(c *gin.Context) {
+		currentUser, _ := c.Get("currentUser")
+		user := currentUser.(User)
+
+		var template SurveyTemplate
+		if err := VisibleTemplates(db, user).Where("id = ?", c.Param("id")).First(&template).Error; err != nil || !CanEditTemplate(user, template) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Template not found or access denied"})
+			return
+		}
+
+		var changes SurveyTemplate
+		if err := c.BindJSON(&changes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+		if err := ValidateTemplate(changes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := UpdateTemplate(db, &template, changes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"template": template})
+	}
+
+	deleteTemplate := func(c *gin.Context) {
+		currentUser, _ := c.Get("currentUser")
+		user := currentUser.(User)
+
+		var template SurveyTemplate
+		if err := VisibleTemplates(db, user).Where("id = ?", c.Param("id")).First(&template).Error; err != nil || !CanEditTemplate(user, template) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Template not found or access denied"})
+			return
+		}
+
+		if err := DeleteTemplate(db, template); err != nil {
+			if errors.Is(err, ErrTemplateInUse) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+	}
+
+	for _, group := range []*gin.RouterGroup{adminGroup, professorGroup} {
+		group.GET("/templates", listTemplates)
+		group.GET("/templates/:id", getTemplate)
+		group.POST("/templates", createTemplate)
+		group.PUT("/templates/:id", updateTemplate)
+		group.DELETE("/templates/:id", deleteTemplate)
+	}
+
 	// =============================================================================
 	// STUDENT ENDPOINTS
 	// =============================================================================
@@ -886,7 +3701,7 @@ func main() {
 			user := currentUser.(User)
 
 			var enrollments []StudentEnrollment
-			if err := db.Preload("Subject").Preload("Semester").Where("student_id = ?", user.ID).Find(&enrollments).Error; err != nil {
+			if err := db.Preload("Subject").Preload("Semester").Preload("Section").Where("student_id = ?", user.ID).Find(&enrollments).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollments"})
 				return
 			}
@@ -899,42 +3714,30 @@ func main() {
 			user := currentUser.(User)
 
 			var surveys []Survey
-			if err := db.Preload("Subject").Preload("Semester").Preload("Questions").
-				Joins("JOIN student_enrollments ON surveys.subject_id = student_enrollments.subject_id AND surveys.semester_id = student_enrollments.semester_id").
-				Where("student_enrollments.student_id = ? AND surveys.is_active = ?", user.ID, true).
+			if err := db.Preload("Subject").Preload("Semester").Preload("Section").Preload("Questions").
+				Joins(surveyEnrollmentJoin).
+				Where("student_enrollments.student_id = ? AND surveys.is_active = ? AND surveys.status IN ?", user.ID, true, studentSurveyStatuses).
 				Find(&surveys).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
-		})
-
-		// Submit response to survey
-		studentGroup.POST("/responses", func(c *gin.Context) {
-			currentUser, _ := c.Get("currentUser")
-			user := currentUser.(User)
 
-			var response Response
-			if err := c.BindJSON(&response); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-				return
+			surveyIDs := make([]uint, len(surveys))
+			for i := range surveys {
+				surveyIDs[i] = surveys[i].ID
 			}
-
-			// Verify student is enrolled in the survey's subject
-			var enrollment StudentEnrollment
-			if err := db.Joins("JOIN surveys ON student_enrollments.subject_id = surveys.subject_id AND student_enrollments.semester_id = surveys.semester_id").
-				Where("student_enrollments.student_id = ? AND surveys.id = ?", user.ID, response.SurveyID).
-				First(&enrollment).Error; err != nil {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You are not enrolled in this survey's subject"})
+			submitted, err := SubmittedSurveyIDs(db, user.ID, surveyIDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch surveys"})
 				return
 			}
 
-			response.StudentID = user.ID
-			if err := db.Create(&response).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit response"})
-				return
+			now := time.Now()
+			for i := range surveys {
+				surveys[i].WindowStatus = surveys[i].WindowStatusAt(now)
+				surveys[i].Submitted = submitted[surveys[i].ID]
 			}
-			c.JSON(http.StatusCreated, gin.H{"response": response})
+			c.JSON(http.StatusOK, gin.H{"surveys": surveys})
 		})
 
 		// Get student's past responses
@@ -957,19 +3760,141 @@ func main() {
 			surveyID := c.Param("id")
 
 			var survey Survey
-			if err := db.Preload("Subject").Preload("Semester").Preload("Questions", func(db *gorm.DB) *gorm.DB {
+			if err := db.Preload("Subject").Preload("Semester").Preload("Section").Preload("Questions", func(db *gorm.DB) *gorm.DB {
 				return db.Order("\"order\" ASC")
+			}).Preload("Questions.Staff", func(db *gorm.DB) *gorm.DB {
+				return db.Select("id", "first_name", "last_name")
 			}).
-				Joins("JOIN student_enrollments ON surveys.subject_id = student_enrollments.subject_id AND surveys.semester_id = student_enrollments.semester_id").
-				Where("student_enrollments.student_id = ? AND surveys.id = ? AND surveys.is_active = ?", user.ID, surveyID, true).
+				Joins(surveyEnrollmentJoin).
+				Where("student_enrollments.student_id = ? AND surveys.id = ? AND surveys.is_active = ? AND surveys.status IN ?", user.ID, surveyID, true, studentSurveyStatuses).
 				First(&survey).Error; err != nil {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found or access denied"})
 				return
 			}
 
+			submitted, err := HasSubmitted(db, survey, user.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch survey"})
+				return
+			}
+			survey.WindowStatus = survey.WindowStatusAt(time.Now())
+			survey.Submitted = submitted
 			c.JSON(http.StatusOK, gin.H{"survey": survey})
 		})
 
+		// Submit every answer of a survey at once
+		studentGroup.POST("/surveys/:id/submission", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+
+			// Verify student is enrolled in the survey's subject
+			var survey Survey
+			if err := db.Preload("Questions").
+				Joins(surveyEnrollmentJoin).
+				Where("student_enrollments.student_id = ? AND surveys.id = ?", user.ID, surveyID).
+				First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			// Only accept submissions while the survey is open, active and within its window
+			if statusErr := CheckSurveyStatus(survey); statusErr != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": statusErr.Message, "code": statusErr.Code})
+				return
+			}
+			if windowErr := CheckSubmissionWindow(survey, time.Now()); windowErr != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": windowErr.Message, "code": windowErr.Code})
+				return
+			}
+
+			var submission SubmissionRequest
+			if err := c.BindJSON(&submission); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			if answerErrors := ValidateSubmission(survey.Questions, submission.Answers); len(answerErrors) > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission", "details": answerErrors})
+				return
+			}
+
+			// Answers can only be submitted once, later changes go through PUT
+			submitted, err := HasSubmitted(db, survey, user.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit responses"})
+				return
+			}
+			if submitted {
+				c.JSON(http.StatusConflict, gin.H{"error": "You have already answered this survey", "code": ErrCodeAlreadySubmitted})
+				return
+			}
+
+			responses, err := SubmitSurvey(db, survey, user.ID, submission.Answers)
+			if err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "You have already answered this survey", "code": ErrCodeAlreadySubmitted})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit responses"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"responses": responses})
+		})
+
+		// Edit the answers of a survey while it is still open
+		studentGroup.PUT("/surveys/:id/submission", func(c *gin.Context) {
+			currentUser, _ := c.Get("currentUser")
+			user := currentUser.(User)
+			surveyID := c.Param("id")
+
+			// Verify student is enrolled in the survey's subject
+			var survey Survey
+			if err := db.Preload("Questions").
+				Joins(surveyEnrollmentJoin).
+				Where("student_enrollments.student_id = ? AND surveys.id = ?", user.ID, surveyID).
+				First(&survey).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found or access denied"})
+				return
+			}
+
+			// Answers can only be changed while the survey is open, active and within its window
+			if statusErr := CheckSurveyStatus(survey); statusErr != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": statusErr.Message, "code": statusErr.Code})
+				return
+			}
+			if windowErr := CheckSubmissionWindow(survey, time.Now()); windowErr != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": windowErr.Message, "code": windowErr.Code})
+				return
+			}
+
+			var submission SubmissionRequest
+			if err := c.BindJSON(&submission); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+				return
+			}
+
+			if answerErrors := ValidateSubmission(survey.Questions, submission.Answers); len(answerErrors) > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission", "details": answerErrors})
+				return
+			}
+
+			responses, err := ResubmitSurvey(db, survey, user.ID, submission.Answers)
+			if err != nil {
+				if errors.Is(err, ErrNoSubmission) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "You have not answered this survey yet"})
+					return
+				}
+				if errors.Is(err, ErrAnonymousEdit) {
+					c.JSON(http.StatusConflict, gin.H{"error": "Answers to anonymous surveys cannot be edited", "code": ErrCodeAnonymousSurvey})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update responses"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"responses": responses})
+		})
+
 		// Get student's responses for a specific survey
 		studentGroup.GET("/surveys/:id/responses", func(c *gin.Context) {
 			currentUser, _ := c.Get("currentUser")
@@ -978,7 +3903,7 @@ func main() {
 
 			// Verify student has access to this survey
 			var survey Survey
-			if err := db.Joins("JOIN student_enrollments ON surveys.subject_id = student_enrollments.subject_id AND surveys.semester_id = student_enrollments.semester_id").
+			if err := db.Joins(surveyEnrollmentJoin).
 				Where("student_enrollments.student_id = ? AND surveys.id = ?", user.ID, surveyID).
 				First(&survey).Error; err != nil {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found or access denied"})
@@ -990,7 +3915,14 @@ func main() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"responses": responses})
+
+			// Anonymous answers cannot be listed back, only the fact that they were submitted
+			submitted, err := HasSubmitted(db, survey, user.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch responses"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"responses": responses, "submitted": submitted})
 		})
 	}
 
@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -81,8 +82,8 @@ func TestRequireRole(t *testing.T) {
 	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	// Auto-migrate User model
-	testDB.AutoMigrate(&User{})
+	// Auto-migrate the User and Session models
+	testDB.AutoMigrate(&User{}, &Session{})
 
 	// Save and restore original db
 	originalDB := db
@@ -122,9 +123,9 @@ func TestRequireRole(t *testing.T) {
 	testDB.Create(&admin)
 
 	// Generate JWT tokens for test users
-	studentToken, _ := GenerateJWT(student.ID, student.Role)
-	professorToken, _ := GenerateJWT(professor.ID, professor.Role)
-	adminToken, _ := GenerateJWT(admin.ID, admin.Role)
+	studentToken := sessionToken(testDB, student)
+	professorToken := sessionToken(testDB, professor)
+	adminToken := sessionToken(testDB, admin)
 
 	t.Run("Missing Authorization Header", func(t *testing.T) {
 		r := gin.New()
@@ -306,4 +307,58 @@ func TestRequireRole(t *testing.T) {
 		assert.Equal(t, 200, w.Code)
 		assert.Contains(t, w.Body.String(), "authorized")
 	})
+
+	t.Run("Disabled User Denied", func(t *testing.T) {
+		disabled := User{
+			FirstName: "Old",
+			LastName:  "Student",
+			Email:     "disabled@test.com",
+			Password:  "password123",
+			Role:      RoleStudent,
+		}
+		testDB.Create(&disabled)
+		DisableUser(testDB, &disabled, time.Now())
+		disabledToken := sessionToken(testDB, disabled)
+
+		r := gin.New()
+		r.Use(RequireRole(RoleStudent))
+		r.GET("/test", func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "authorized"})
+		})
+
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+disabledToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 403, w.Code)
+		assert.Contains(t, w.Body.String(), "Account is disabled")
+	})
+
+	t.Run("Unverified User Denied", func(t *testing.T) {
+		unverified := User{
+			FirstName:       "New",
+			LastName:        "Student",
+			Email:           "unverified@test.com",
+			Password:        "password123",
+			Role:            RoleStudent,
+			EmailUnverified: true,
+		}
+		testDB.Create(&unverified)
+		unverifiedToken := sessionToken(testDB, unverified)
+
+		r := gin.New()
+		r.Use(RequireRole(RoleStudent))
+		r.GET("/test", func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "authorized"})
+		})
+
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+unverifiedToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 403, w.Code)
+		assert.Contains(t, w.Body.String(), ErrCodeEmailUnverified)
+	})
 }
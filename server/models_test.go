@@ -17,7 +17,7 @@ func setupTestDB() *gorm.DB {
 	}
 
 	// Auto-migrate all models
-	db.AutoMigrate(&User{}, &Subject{}, &Semester{}, &StudentEnrollment{}, &Survey{}, &Question{}, &Response{})
+	db.AutoMigrate(migrationModels()...)
 
 	return db
 }
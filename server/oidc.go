@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider logs users in with an OpenID Connect identity provider using the
+// authorization code flow with PKCE
+type OIDCProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+
+	// Discovery happens on first use, so the API starts while the provider is down
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// newOIDCProviderFromEnv returns the provider configured by OIDC_ISSUER, OIDC_CLIENT_ID,
+// OIDC_CLIENT_SECRET, OIDC_SCOPES and OIDC_GROUPS_CLAIM, or nil when OIDC isn't configured
+func newOIDCProviderFromEnv() *OIDCProvider {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" || os.Getenv("OIDC_CLIENT_ID") == "" {
+		return nil
+	}
+	scopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	groupsClaim := os.Getenv("OIDC_GROUPS_CLAIM")
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	return &OIDCProvider{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  getAPIPublicURL() + "/sso/oidc/callback",
+		Scopes:       scopes,
+		GroupsClaim:  groupsClaim,
+	}
+}
+
+// discover fetches the provider's configuration once it succeeds
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	if p == nil {
+		return nil, nil, ErrSSONotConfigured
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth == nil {
+		provider, err := oidc.NewProvider(ctx, p.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.oauth = &oauth2.Config{
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}
+		p.verifier = provider.Verifier(&oidc.Config{ClientID: p.ClientID})
+	}
+	return p.oauth, p.verifier, nil
+}
+
+// AuthURL returns the provider's login page for a new login and the state to keep until
+// the callback
+func (p *OIDCProvider) AuthURL(ctx context.Context) (string, SSOState, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	state, err := newSecretToken()
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	nonce, err := newSecretToken()
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	login := SSOState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(login.Verifier)), login, nil
+}
+
+// Identity exchanges the code from the callback for a verified ID token and returns
+// the identity it carries
+func (p *OIDCProvider) Identity(ctx context.Context, login SSOState, state, code string) (SSOIdentity, error) {
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return SSOIdentity{}, err
+	}
+	if login.State == "" || state != login.State {
+		return SSOIdentity{}, ErrInvalidSSOState
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return SSOIdentity{}, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return SSOIdentity{}, errors.New("token response has no id_token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return SSOIdentity{}, err
+	}
+	if idToken.Nonce != login.Nonce {
+		return SSOIdentity{}, ErrInvalidSSOState
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return SSOIdentity{}, err
+	}
+	identity := SSOIdentity{
+		Provider:  SSOProviderOIDC,
+		Subject:   idToken.Subject,
+		Email:     stringClaim(claims, "email"),
+		FirstName: stringClaim(claims, "given_name"),
+		LastName:  stringClaim(claims, "family_name"),
+		Groups:    stringsClaim(claims, p.GroupsClaim),
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		identity.EmailUnverified = true
+	}
+	if identity.FirstName == "" && identity.LastName == "" {
+		identity.FirstName, identity.LastName = splitName(stringClaim(claims, "name"))
+	}
+	return identity, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// stringsClaim reads a claim that is either a list of strings or a single string
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// splitName splits a full name into the first name and the rest
+func splitName(name string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(name), " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
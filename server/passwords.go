@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MinPasswordLength applies to passwords set through a change or a reset
+	MinPasswordLength = 8
+	passwordResetTTL  = time.Hour
+)
+
+var (
+	// ErrWeakPassword is returned for new passwords shorter than MinPasswordLength
+	ErrWeakPassword = fmt.Errorf("Password must have at least %d characters", MinPasswordLength)
+	// ErrWrongPassword is returned when the current password given to a change doesn't match
+	ErrWrongPassword = errors.New("Current password is incorrect")
+	// ErrInvalidResetToken is returned for unknown, used or expired password reset tokens
+	ErrInvalidResetToken = errors.New("Invalid or expired password reset link")
+)
+
+// ValidatePassword checks a new password
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// setPassword hashes and stores a user's new password
+func setPassword(db *gorm.DB, user *User, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(user).Update("password", hashed).Error; err != nil {
+		return err
+	}
+	user.Password = hashed
+	return nil
+}
+
+// ChangePassword replaces the password of a user who knows their current one
+func ChangePassword(db *gorm.DB, user *User, current, next string) error {
+	if !CheckPasswordHash(current, user.Password) {
+		return ErrWrongPassword
+	}
+	return setPassword(db, user, next)
+}
+
+// getPasswordResetURL returns the client page reset links point to, PASSWORD_RESET_URL
+// or the reset page of the CORS origin
+func getPasswordResetURL() string {
+	return getClientURL("PASSWORD_RESET_URL", "/reset-password")
+}
+
+// getClientURL returns the URL in the env variable, or path on the client at CORS_ORIGIN
+func getClientURL(env, path string) string {
+	if configured := os.Getenv(env); configured != "" {
+		return configured
+	}
+	origin := os.Getenv("CORS_ORIGIN")
+	if origin == "" {
+		origin = "http://localhost:5173"
+	}
+	return strings.TrimSuffix(origin, "/") + path
+}
+
+// RequestPasswordReset emails a single-use reset link to the user with the given email.
+// Unknown and disabled accounts are skipped without an error, so the response doesn't
+// tell who has an account.
+func RequestPasswordReset(db *gorm.DB, mailer Mailer, email, resetURL string, now time.Time) error {
+	var user User
+	err := db.Where("LOWER(email) = ?", normalizeEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.IsDisabled() {
+		return nil
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	reset := PasswordReset{UserID: user.ID, TokenHash: hashSecretToken(token), ExpiresAt: now.Add(passwordResetTTL)}
+	if err := db.Create(&reset).Error; err != nil {
+		return err
+	}
+
+	link, err := url.Parse(resetURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Send(Mail{
+		To:      user.Email,
+		Subject: "Redefinição de senha",
+		Body: fmt.Sprintf("Olá, %s.\n\nRecebemos um pedido para redefinir a sua senha. Para escolher uma nova senha, acesse:\n\n%s\n\n"+
+			"O link vale por %d minutos e só pode ser usado uma vez. Se você não fez este pedido, ignore este email.\n",
+			user.FirstName, link.String(), int(passwordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password with a reset token, which can't be used again, and
+// ends every session of the user
+func ResetPassword(db *gorm.DB, token, password string, now time.Time) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var reset PasswordReset
+		if err := tx.Where("token_hash = ?", hashSecretToken(token)).First(&reset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		// Only one request can use the token
+		result := tx.Model(&PasswordReset{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		var user User
+		if err := tx.First(&user, reset.UserID).Error; err != nil {
+			return err
+		}
+		if user.IsDisabled() {
+			return ErrInvalidResetToken
+		}
+		if err := setPassword(tx, &user, password); err != nil {
+			return err
+		}
+		_, err := RevokeUserSessions(tx, user.ID, now)
+		return err
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswords(t *testing.T) {
+	db := setupTestDB()
+
+	hashed, _ := HashPassword("password123")
+	user := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: hashed, Role: RoleStudent}
+	db.Create(&user)
+	now := time.Now()
+
+	var outbox bytes.Buffer
+	mailer := &WriterMailer{From: "no-reply@example.com", out: &outbox}
+	// resetToken requests a reset and returns the token from the emailed link
+	resetToken := func(t *testing.T) string {
+		outbox.Reset()
+		assert.NoError(t, RequestPasswordReset(db, mailer, "  ANA@example.com ", "http://localhost:5173/reset-password", now))
+		link := regexp.MustCompile(`http://\S+`).FindString(outbox.String())
+		parsed, err := url.Parse(link)
+		assert.NoError(t, err)
+		return parsed.Query().Get("token")
+	}
+
+	t.Run("Change Password", func(t *testing.T) {
+		assert.ErrorIs(t, ChangePassword(db, &user, "wrong", "newpassword1"), ErrWrongPassword)
+		assert.ErrorIs(t, ChangePassword(db, &user, "password123", "short"), ErrWeakPassword)
+		assert.NoError(t, ChangePassword(db, &user, "password123", "newpassword1"))
+
+		var loaded User
+		db.First(&loaded, user.ID)
+		assert.True(t, CheckPasswordHash("newpassword1", loaded.Password))
+	})
+
+	t.Run("Change Keeps The Current Session", func(t *testing.T) {
+		current, _, _ := StartSession(db, user, "test", "127.0.0.1", now)
+		other, _, _ := StartSession(db, user, "test", "127.0.0.1", now)
+
+		revoked, err := RevokeOtherSessions(db, user.ID, current.ID, now)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+		assert.NoError(t, CheckSession(db, current.ID, user.ID, now))
+		assert.ErrorIs(t, CheckSession(db, other.ID, user.ID, now), ErrSessionRevoked)
+	})
+
+	t.Run("Reset Email", func(t *testing.T) {
+		token := resetToken(t)
+		assert.NotEmpty(t, token)
+		assert.Contains(t, outbox.String(), "To: ana@example.com")
+		// Only the hash of the token is stored
+		var stored int64
+		db.Model(&PasswordReset{}).Where("token_hash = ?", token).Count(&stored)
+		assert.Zero(t, stored)
+	})
+
+	t.Run("Unknown Emails Get No Email", func(t *testing.T) {
+		outbox.Reset()
+		assert.NoError(t, RequestPasswordReset(db, mailer, "nobody@example.com", "http://localhost:5173/reset-password", now))
+		assert.Empty(t, outbox.String())
+	})
+
+	t.Run("Reset Password", func(t *testing.T) {
+		session, _, _ := StartSession(db, user, "test", "127.0.0.1", now)
+		token := resetToken(t)
+
+		assert.ErrorIs(t, ResetPassword(db, token, "short", now), ErrWeakPassword)
+		assert.NoError(t, ResetPassword(db, token, "resetpassword1", now))
+
+		var loaded User
+		db.First(&loaded, user.ID)
+		assert.True(t, CheckPasswordHash("resetpassword1", loaded.Password))
+		// Every session ends, and the token can't be used twice
+		assert.ErrorIs(t, CheckSession(db, session.ID, user.ID, now), ErrSessionRevoked)
+		assert.ErrorIs(t, ResetPassword(db, token, "anotherpassword1", now), ErrInvalidResetToken)
+	})
+
+	t.Run("Invalid Reset Tokens", func(t *testing.T) {
+		assert.ErrorIs(t, ResetPassword(db, "unknown", "resetpassword1", now), ErrInvalidResetToken)
+
+		token := resetToken(t)
+		assert.ErrorIs(t, ResetPassword(db, token, "resetpassword1", now.Add(passwordResetTTL)), ErrInvalidResetToken)
+	})
+
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCodeTooManyAttempts is returned with 429 responses from rate limited endpoints
+const ErrCodeTooManyAttempts = "too_many_attempts"
+
+// ErrTooManyAttempts is returned while a client or an account is rate limited or locked out
+var ErrTooManyAttempts = errors.New("Too many attempts, please try again later")
+
+// RateLimitStore keeps the counters behind AuthLimiter. MemoryRateLimitStore keeps them in
+// the process; a store shared by several API instances (e.g. Redis INCR and EXPIRE) can
+// implement the same interface.
+type RateLimitStore interface {
+	// Increment adds one to the counter at key and returns its new value and when it
+	// resets. A missing or expired counter starts over at one and resets after window.
+	Increment(key string, window time.Duration, now time.Time) (int, time.Time, error)
+	// Count returns the counter at key and when it resets, or zero once it expired
+	Count(key string, now time.Time) (int, time.Time, error)
+	// Reset deletes the counter at key
+	Reset(key string) error
+}
+
+// MemoryRateLimitStore is a RateLimitStore for a single API instance
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	counters  map[string]rateCounter
+	nextSweep time.Time
+}
+
+type rateCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counters: map[string]rateCounter{}}
+}
+
+// Increment adds one to the counter at key, dropping expired counters once a minute so
+// clients that stop coming back don't use memory forever
+func (s *MemoryRateLimitStore) Increment(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !now.Before(s.nextSweep) {
+		for k, counter := range s.counters {
+			if !now.Before(counter.resetAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		counter = rateCounter{resetAt: now.Add(window)}
+	}
+	counter.count++
+	s.counters[key] = counter
+	return counter.count, counter.resetAt, nil
+}
+
+// Count returns the counter at key
+func (s *MemoryRateLimitStore) Count(key string, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		return 0, time.Time{}, nil
+	}
+	return counter.count, counter.resetAt, nil
+}
+
+// Reset deletes the counter at key
+func (s *MemoryRateLimitStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// RateLimit allows Max events per Window. A Max of 0 disables the limit.
+type RateLimit struct {
+	Max    int
+	Window time.Duration
+}
+
+// AuthLimiter protects the authentication endpoints: it limits the requests each IP
+// makes to each endpoint, and locks an account or an IP out for a while after repeated
+// failed logins, before bcrypt spends any time on them
+type AuthLimiter struct {
+	Store RateLimitStore
+	// Requests limits the requests of an IP to each endpoint
+	Requests RateLimit
+	// AccountFailures and IPFailures set how many failed logins lock an account or an IP out
+	AccountFailures RateLimit
+	IPFailures      RateLimit
+	Lockout         time.Duration
+}
+
+// Defaults for the settings of newAuthLimiterFromEnv
+const (
+	DefaultAuthRateLimit      = 20 // requests per minute
+	DefaultAuthMaxFailures    = 5  // per account
+	DefaultAuthMaxIPFailures  = 20 // per IP
+	DefaultAuthLockoutMinutes = 15
+)
+
+// newAuthLimiterFromEnv returns an in-memory limiter configured by AUTH_RATE_LIMIT,
+// AUTH_MAX_FAILURES, AUTH_MAX_IP_FAILURES and AUTH_LOCKOUT_MINUTES. Failures are counted
+// over the lockout period.
+func newAuthLimiterFromEnv() *AuthLimiter {
+	lockout := time.Duration(getEnvInt("AUTH_LOCKOUT_MINUTES", DefaultAuthLockoutMinutes)) * time.Minute
+	return &AuthLimiter{
+		Store:           NewMemoryRateLimitStore(),
+		Requests:        RateLimit{Max: getEnvInt("AUTH_RATE_LIMIT", DefaultAuthRateLimit), Window: time.Minute},
+		AccountFailures: RateLimit{Max: getEnvInt("AUTH_MAX_FAILURES", DefaultAuthMaxFailures), Window: lockout},
+		IPFailures:      RateLimit{Max: getEnvInt("AUTH_MAX_IP_FAILURES", DefaultAuthMaxIPFailures), Window: lockout},
+		Lockout:         lockout,
+	}
+}
+
+// getEnvInt reads a non-negative integer setting, or returns fallback when it's unset or invalid
+func getEnvInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("⚠️  Invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// Allow counts a request from ip to endpoint and returns ErrTooManyAttempts, with the
+// time until the limit resets, once the IP made too many
+func (l *AuthLimiter) Allow(endpoint, ip string, now time.Time) (time.Duration, error) {
+	if l.Requests.Max == 0 {
+		return 0, nil
+	}
+	count, resetAt, err := l.Store.Increment("requests:"+endpoint+":"+ip, l.Requests.Window, now)
+	if err != nil {
+		return 0, err
+	}
+	if count > l.Requests.Max {
+		return resetAt.Sub(now), ErrTooManyAttempts
+	}
+	return 0, nil
+}
+
+// CheckLogin returns ErrTooManyAttempts, with the time until the lockout ends, while the
+// account or the IP is locked out
+func (l *AuthLimiter) CheckLogin(email, ip string, now time.Time) (time.Duration, error) {
+	for _, key := range []string{"lockout:account:" + normalizeEmail(email), "lockout:ip:" + ip} {
+		count, resetAt, err := l.Store.Count(key, now)
+		if err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			return resetAt.Sub(now), ErrTooManyAttempts
+		}
+	}
+	return 0, nil
+}
+
+// LoginFailed counts a failed login for the account and the IP, and reports whether
+// either is now locked out. Unknown emails count too, so they can't be probed faster.
+func (l *AuthLimiter) LoginFailed(email, ip string, now time.Time) (bool, error) {
+	accountLocked, err := l.countFailure("account:"+normalizeEmail(email), l.AccountFailures, now)
+	if err != nil {
+		return false, err
+	}
+	ipLocked, err := l.countFailure("ip:"+ip, l.IPFailures, now)
+	return accountLocked || ipLocked, err
+}
+
+// countFailure locks key out once it fails limit.Max times, starting the count over
+func (l *AuthLimiter) countFailure(key string, limit RateLimit, now time.Time) (bool, error) {
+	if limit.Max == 0 {
+		return false, nil
+	}
+	count, _, err := l.Store.Increment("failures:"+key, limit.Window, now)
+	if err != nil || count < limit.Max {
+		return false, err
+	}
+	if _, _, err := l.Store.Increment("lockout:"+key, l.Lockout, now); err != nil {
+		return false, err
+	}
+	return true, l.Store.Reset("failures:" + key)
+}
+
+// LoginSucceeded forgets the failed logins of the account. Those of the IP are kept, so
+// an attacker can't reset them with an account of their own.
+func (l *AuthLimiter) LoginSucceeded(email string) error {
+	return l.Store.Reset("failures:account:" + normalizeEmail(email))
+}
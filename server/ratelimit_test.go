@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuthLimiter() *AuthLimiter {
+	return &AuthLimiter{
+		Store:           NewMemoryRateLimitStore(),
+		Requests:        RateLimit{Max: 3, Window: time.Minute},
+		AccountFailures: RateLimit{Max: 3, Window: 15 * time.Minute},
+		IPFailures:      RateLimit{Max: 5, Window: 15 * time.Minute},
+		Lockout:         15 * time.Minute,
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+
+	t.Run("Counters Reset After Their Window", func(t *testing.T) {
+		count, resetAt, err := store.Increment("a", time.Minute, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Equal(t, now.Add(time.Minute), resetAt)
+		count, _, _ = store.Increment("a", time.Minute, now.Add(30*time.Second))
+		assert.Equal(t, 2, count)
+
+		count, _, _ = store.Count("a", now.Add(time.Minute))
+		assert.Equal(t, 0, count)
+		count, _, _ = store.Increment("a", time.Minute, now.Add(time.Minute))
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		store.Increment("b", time.Minute, now)
+		assert.NoError(t, store.Reset("b"))
+		count, _, _ := store.Count("b", now)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Expired Counters Are Dropped", func(t *testing.T) {
+		store.Increment("c", time.Second, now)
+		store.Increment("d", time.Hour, now.Add(2*time.Minute))
+		assert.NotContains(t, store.counters, "c")
+		assert.Contains(t, store.counters, "d")
+	})
+}
+
+func TestAuthLimiter(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Requests Per IP And Endpoint", func(t *testing.T) {
+		limiter := newTestAuthLimiter()
+		for i := 0; i < 3; i++ {
+			_, err := limiter.Allow("/login", "10.0.0.1", now)
+			assert.NoError(t, err)
+		}
+		wait, err := limiter.Allow("/login", "10.0.0.1", now.Add(20*time.Second))
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		assert.Equal(t, 40*time.Second, wait)
+
+		// Other IPs and endpoints have limits of their own
+		_, err = limiter.Allow("/login", "10.0.0.2", now)
+		assert.NoError(t, err)
+		_, err = limiter.Allow("/register", "10.0.0.1", now)
+		assert.NoError(t, err)
+
+		_, err = limiter.Allow("/login", "10.0.0.1", now.Add(time.Minute))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Account Lockout", func(t *testing.T) {
+		limiter := newTestAuthLimiter()
+		for i := 0; i < 2; i++ {
+			locked, err := limiter.LoginFailed("ana@usp.br", "10.0.0.1", now)
+			assert.NoError(t, err)
+			assert.False(t, locked)
+		}
+		_, err := limiter.CheckLogin("ana@usp.br", "10.0.0.1", now)
+		assert.NoError(t, err)
+
+		// Emails compare case-insensitively, and the lockout follows the account to other IPs
+		locked, err := limiter.LoginFailed("ANA@usp.br", "10.0.0.2", now)
+		assert.NoError(t, err)
+		assert.True(t, locked)
+		wait, err := limiter.CheckLogin("ana@usp.br", "10.0.0.3", now.Add(time.Minute))
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		assert.Equal(t, 14*time.Minute, wait)
+
+		// Other accounts can still log in
+		_, err = limiter.CheckLogin("bruno@usp.br", "10.0.0.1", now)
+		assert.NoError(t, err)
+
+		// The lockout is temporary
+		_, err = limiter.CheckLogin("ana@usp.br", "10.0.0.3", now.Add(limiter.Lockout))
+		assert.NoError(t, err)
+	})
+
+	t.Run("IP Lockout", func(t *testing.T) {
+		limiter := newTestAuthLimiter()
+		emails := []string{"a@usp.br", "b@usp.br", "c@usp.br", "d@usp.br", "e@usp.br"}
+		var locked bool
+		for _, email := range emails {
+			var err error
+			locked, err = limiter.LoginFailed(email, "10.0.0.1", now)
+			assert.NoError(t, err)
+		}
+		assert.True(t, locked)
+
+		_, err := limiter.CheckLogin("f@usp.br", "10.0.0.1", now)
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		_, err = limiter.CheckLogin("f@usp.br", "10.0.0.2", now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Successful Login Forgets Account Failures", func(t *testing.T) {
+		limiter := newTestAuthLimiter()
+		limiter.LoginFailed("ana@usp.br", "10.0.0.1", now)
+		limiter.LoginFailed("ana@usp.br", "10.0.0.1", now)
+		assert.NoError(t, limiter.LoginSucceeded("ana@usp.br"))
+
+		locked, err := limiter.LoginFailed("ana@usp.br", "10.0.0.1", now)
+		assert.NoError(t, err)
+		assert.False(t, locked)
+	})
+
+	t.Run("Zero Disables Limits", func(t *testing.T) {
+		limiter := &AuthLimiter{Store: NewMemoryRateLimitStore()}
+		for i := 0; i < 100; i++ {
+			_, err := limiter.Allow("/login", "10.0.0.1", now)
+			assert.NoError(t, err)
+			locked, err := limiter.LoginFailed("ana@usp.br", "10.0.0.1", now)
+			assert.NoError(t, err)
+			assert.False(t, locked)
+		}
+	})
+}
+
+func TestRateLimitAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", RateLimitAuth(newTestAuthLimiter()), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCodeTooManyAttempts)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSemesterActive is returned when deleting the active semester
+	ErrSemesterActive = errors.New("The active semester cannot be deleted")
+	// ErrSemesterInUse is returned when deleting a semester that still has enrollments, sections, surveys or campaigns
+	ErrSemesterInUse = errors.New("Semester has enrollments, sections, surveys or campaigns")
+	// ErrSubjectHasResponses is returned when deleting a subject whose surveys have responses
+	ErrSubjectHasResponses = errors.New("Subject has surveys with responses")
+	// ErrEnrollmentHasResponses is returned when deleting or moving an enrollment whose student answered surveys of the offering
+	ErrEnrollmentHasResponses = errors.New("Student has already answered surveys of this subject and semester")
+	// ErrAlreadyEnrolled is returned when an enrollment would duplicate another one
+	ErrAlreadyEnrolled = errors.New("Student is already enrolled in this subject and semester")
+	// ErrNotStudent is returned when enrolling a user who is not a student
+	ErrNotStudent = errors.New("Only students can be enrolled")
+)
+
+// IsDisabled reports whether an admin disabled the user, which blocks login and API access
+func (u User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// DisableUser blocks a user from logging in and using the API, keeping their data
+func DisableUser(db *gorm.DB, user *User, now time.Time) error {
+	if err := db.Model(user).Update("disabled_at", now).Error; err != nil {
+		return err
+	}
+	user.DisabledAt = &now
+	return nil
+}
+
+// EnableUser lifts DisableUser
+func EnableUser(db *gorm.DB, user *User) error {
+	if err := db.Model(user).Update("disabled_at", nil).Error; err != nil {
+		return err
+	}
+	user.DisabledAt = nil
+	return nil
+}
+
+// DeleteSemester deletes a semester nothing refers to. Semesters with enrollments,
+// sections, surveys or campaigns must be emptied first.
+func DeleteSemester(db *gorm.DB, semester Semester) error {
+	if semester.IsActive {
+		return ErrSemesterActive
+	}
+	for _, model := range []interface{}{&StudentEnrollment{}, &Section{}, &StaffAssignment{}, &Survey{}, &Campaign{}} {
+		var count int64
+		if err := db.Unscoped().Model(model).Where("semester_id = ?", semester.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSemesterInUse
+		}
+	}
+	return db.Delete(&semester).Error
+}
+
+// DeleteSubject deletes a subject together with its enrollments, sections, staff and
+// surveys, unless any of its surveys has responses. Archived surveys count too.
+func DeleteSubject(db *gorm.DB, subject Subject) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		surveys := tx.Unscoped().Model(&Survey{}).Select("id").Where("subject_id = ?", subject.ID)
+
+		var responses int64
+		if err := tx.Unscoped().Model(&Response{}).Where("survey_id IN (?)", surveys).Count(&responses).Error; err != nil {
+			return err
+		}
+		if responses > 0 {
+			return ErrSubjectHasResponses
+		}
+
+		if err := tx.Unscoped().Where("survey_id IN (?)", surveys).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&Survey{}, &StudentEnrollment{}, &Section{}, &StaffAssignment{}} {
+			if err := tx.Unscoped().Where("subject_id = ?", subject.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&subject).Error
+	})
+}
+
+// enrollmentAnswered reports whether the enrolled student answered any survey of the
+// enrollment's subject and semester, including anonymous and archived ones
+func enrollmentAnswered(db *gorm.DB, enrollment StudentEnrollment) (bool, error) {
+	surveys := db.Unscoped().Model(&Survey{}).Select("id").
+		Where("subject_id = ? AND semester_id = ?", enrollment.SubjectID, enrollment.SemesterID)
+
+	var count int64
+	if err := db.Unscoped().Model(&Response{}).
+		Where("student_id = ? AND survey_id IN (?)", enrollment.StudentID, surveys).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Model(&SurveyParticipation{}).
+		Where("student_id = ? AND survey_id IN (?)", enrollment.StudentID, surveys).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ValidateEnrollment checks that an enrollment is for a student, in a section of its
+// offering if any, and doesn't duplicate another enrollment
+func ValidateEnrollment(db *gorm.DB, enrollment StudentEnrollment) error {
+	var student User
+	if err := db.First(&student, enrollment.StudentID).Error; err != nil {
+		return err
+	}
+	if student.Role != RoleStudent {
+		return ErrNotStudent
+	}
+	if err := ValidateEnrollmentSection(db, enrollment); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&StudentEnrollment{}).
+		Where("student_id = ? AND subject_id = ? AND semester_id = ? AND id <> ?", enrollment.StudentID, enrollment.SubjectID, enrollment.SemesterID, enrollment.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAlreadyEnrolled
+	}
+	return nil
+}
+
+// UpdateEnrollment replaces the student, subject, semester and section of an enrollment.
+// Only the section can change once the student answered surveys of the offering.
+func UpdateEnrollment(db *gorm.DB, enrollment *StudentEnrollment, changes StudentEnrollment) error {
+	changes.ID = enrollment.ID
+	if changes.StudentID != enrollment.StudentID || changes.SubjectID != enrollment.SubjectID || changes.SemesterID != enrollment.SemesterID {
+		answered, err := enrollmentAnswered(db, *enrollment)
+		if err != nil {
+			return err
+		}
+		if answered {
+			return ErrEnrollmentHasResponses
+		}
+	}
+	if err := ValidateEnrollment(db, changes); err != nil {
+		return err
+	}
+
+	// A zero section ID is stored as NULL
+	updates := map[string]interface{}{
+		"student_id":  changes.StudentID,
+		"subject_id":  changes.SubjectID,
+		"semester_id": changes.SemesterID,
+		"section_id":  nil,
+	}
+	if changes.SectionID != 0 {
+		updates["section_id"] = changes.SectionID
+	}
+	if err := db.Model(enrollment).Updates(updates).Error; err != nil {
+		return err
+	}
+	enrollment.StudentID, enrollment.SubjectID, enrollment.SemesterID, enrollment.SectionID = changes.StudentID, changes.SubjectID, changes.SemesterID, changes.SectionID
+	return nil
+}
+
+// DeleteEnrollment deletes an enrollment unless the student answered surveys of the offering
+func DeleteEnrollment(db *gorm.DB, enrollment StudentEnrollment) error {
+	answered, err := enrollmentAnswered(db, enrollment)
+	if err != nil {
+		return err
+	}
+	if answered {
+		return ErrEnrollmentHasResponses
+	}
+	return db.Delete(&enrollment).Error
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteRecords(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	student := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	empty := Semester{Name: "2030.1", Year: 2030, Period: 1, StartDate: time.Now().AddDate(6, 0, 0), EndDate: time.Now().AddDate(6, 4, 0)}
+	db.Create(&empty)
+
+	answered := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&answered)
+	unanswered := Subject{Name: "Physics", Code: "FIS101", ProfessorID: professor.ID}
+	db.Create(&unanswered)
+
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: answered.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: student.ID, SubjectID: unanswered.ID, SemesterID: semester.ID})
+	db.Create(&Section{SubjectID: unanswered.ID, SemesterID: semester.ID, Code: "A"})
+
+	survey := Survey{Title: "Calculus feedback", SubjectID: answered.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&survey)
+	question := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+	db.Create(&question)
+	db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: question.ID, Answer: "5"})
+
+	unansweredSurvey := Survey{Title: "Physics feedback", SubjectID: unanswered.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&unansweredSurvey)
+	db.Create(&Question{SurveyID: unansweredSurvey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 1})
+
+	t.Run("Subject With Responses Is Kept", func(t *testing.T) {
+		assert.ErrorIs(t, DeleteSubject(db, answered), ErrSubjectHasResponses)
+		assert.NoError(t, db.First(&Subject{}, answered.ID).Error)
+	})
+
+	t.Run("Subject Without Responses Is Deleted With Its Records", func(t *testing.T) {
+		assert.NoError(t, DeleteSubject(db, unanswered))
+		assert.Error(t, db.First(&Subject{}, unanswered.ID).Error)
+
+		var surveys, questions, enrollments, sections int64
+		db.Model(&Survey{}).Where("subject_id = ?", unanswered.ID).Count(&surveys)
+		db.Model(&Question{}).Where("survey_id = ?", unansweredSurvey.ID).Count(&questions)
+		db.Model(&StudentEnrollment{}).Where("subject_id = ?", unanswered.ID).Count(&enrollments)
+		db.Model(&Section{}).Where("subject_id = ?", unanswered.ID).Count(&sections)
+		assert.Zero(t, surveys+questions+enrollments+sections)
+	})
+
+	t.Run("Only Empty Inactive Semesters Are Deleted", func(t *testing.T) {
+		assert.ErrorIs(t, DeleteSemester(db, semester), ErrSemesterActive)
+		semester.IsActive = false
+		assert.ErrorIs(t, DeleteSemester(db, semester), ErrSemesterInUse)
+		assert.NoError(t, DeleteSemester(db, empty))
+		assert.Error(t, db.First(&Semester{}, empty.ID).Error)
+	})
+
+	t.Run("Enrollment Of A Respondent Is Kept", func(t *testing.T) {
+		var enrollment StudentEnrollment
+		db.Where("student_id = ? AND subject_id = ?", student.ID, answered.ID).First(&enrollment)
+		assert.ErrorIs(t, DeleteEnrollment(db, enrollment), ErrEnrollmentHasResponses)
+	})
+
+	t.Run("Anonymous Participation Counts As An Answer", func(t *testing.T) {
+		other := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleStudent}
+		db.Create(&other)
+		enrollment := StudentEnrollment{StudentID: other.ID, SubjectID: answered.ID, SemesterID: semester.ID}
+		db.Create(&enrollment)
+		db.Create(&SurveyParticipation{SurveyID: survey.ID, StudentID: other.ID})
+		assert.ErrorIs(t, DeleteEnrollment(db, enrollment), ErrEnrollmentHasResponses)
+	})
+}
+
+func TestUpdateEnrollment(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	ana := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&ana)
+	joao := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&joao)
+
+	calculus := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&calculus)
+	physics := Subject{Name: "Physics", Code: "FIS101", ProfessorID: professor.ID}
+	db.Create(&physics)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+	section := Section{SubjectID: physics.ID, SemesterID: semester.ID, Code: "A"}
+	db.Create(&section)
+
+	enrollment := StudentEnrollment{StudentID: ana.ID, SubjectID: calculus.ID, SemesterID: semester.ID}
+	db.Create(&enrollment)
+	db.Create(&StudentEnrollment{StudentID: joao.ID, SubjectID: physics.ID, SemesterID: semester.ID})
+
+	t.Run("Fix The Subject And Section", func(t *testing.T) {
+		changes := StudentEnrollment{StudentID: ana.ID, SubjectID: physics.ID, SemesterID: semester.ID, SectionID: section.ID}
+		assert.NoError(t, UpdateEnrollment(db, &enrollment, changes))
+
+		var stored StudentEnrollment
+		db.First(&stored, enrollment.ID)
+		assert.Equal(t, physics.ID, stored.SubjectID)
+		assert.Equal(t, section.ID, stored.SectionID)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		duplicate := StudentEnrollment{StudentID: joao.ID, SubjectID: physics.ID, SemesterID: semester.ID}
+		assert.ErrorIs(t, UpdateEnrollment(db, &enrollment, duplicate), ErrAlreadyEnrolled)
+
+		notStudent := StudentEnrollment{StudentID: professor.ID, SubjectID: physics.ID, SemesterID: semester.ID}
+		assert.ErrorIs(t, UpdateEnrollment(db, &enrollment, notStudent), ErrNotStudent)
+
+		wrongSection := StudentEnrollment{StudentID: ana.ID, SubjectID: calculus.ID, SemesterID: semester.ID, SectionID: section.ID}
+		assert.ErrorIs(t, UpdateEnrollment(db, &enrollment, wrongSection), ErrSectionNotInOffering)
+	})
+
+	t.Run("Only The Section Changes After Answering", func(t *testing.T) {
+		survey := Survey{Title: "Physics feedback", SubjectID: physics.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+		db.Create(&survey)
+		question := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+		db.Create(&Response{SurveyID: survey.ID, StudentID: ana.ID, QuestionID: question.ID, Answer: "4"})
+
+		moved := StudentEnrollment{StudentID: ana.ID, SubjectID: calculus.ID, SemesterID: semester.ID}
+		assert.ErrorIs(t, UpdateEnrollment(db, &enrollment, moved), ErrEnrollmentHasResponses)
+
+		unsectioned := StudentEnrollment{StudentID: ana.ID, SubjectID: physics.ID, SemesterID: semester.ID}
+		assert.NoError(t, UpdateEnrollment(db, &enrollment, unsectioned))
+		var stored StudentEnrollment
+		db.First(&stored, enrollment.ID)
+		assert.Equal(t, uint(0), stored.SectionID)
+	})
+}
+
+func TestDisableUser(t *testing.T) {
+	db := setupTestDB()
+
+	user := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&user)
+	assert.False(t, user.IsDisabled())
+
+	assert.NoError(t, DisableUser(db, &user, time.Now()))
+	var stored User
+	db.First(&stored, user.ID)
+	assert.True(t, stored.IsDisabled())
+
+	assert.NoError(t, EnableUser(db, &stored))
+	db.First(&stored, user.ID)
+	assert.False(t, stored.IsDisabled())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// ResponseRate compares the enrolled students of a survey's subject/semester
+// with the distinct enrolled students that answered it
+type ResponseRate struct {
+	Enrolled    int64   `json:"enrolled"`
+	Respondents int64   `json:"respondents"`
+	Rate        float64 `json:"rate"` // 0 to 1, 0 when nobody is enrolled
+}
+
+// SurveyResponseRate is the response rate of a single survey
+type SurveyResponseRate struct {
+	SurveyID  uint   `json:"survey_id"`
+	Title     string `json:"title"`
+	SectionID uint   `json:"section_id,omitempty"`
+	ResponseRate
+}
+
+// SubjectResponseRate groups the survey response rates of a subject in a semester
+type SubjectResponseRate struct {
+	Subject  Subject              `json:"subject"`
+	Enrolled int64                `json:"enrolled"`
+	Surveys  []SurveyResponseRate `json:"surveys"`
+}
+
+func newResponseRate(enrolled, respondents int64) ResponseRate {
+	rate := ResponseRate{Enrolled: enrolled, Respondents: respondents}
+	if enrolled > 0 {
+		rate.Rate = float64(respondents) / float64(enrolled)
+	}
+	return rate
+}
+
+type subjectSemesterKey struct {
+	SubjectID  uint
+	SemesterID uint
+}
+
+// ComputeResponseRates returns the response rate of each given survey, keyed by survey ID
+func ComputeResponseRates(db *gorm.DB, surveys []Survey) (map[uint]ResponseRate, error) {
+	rates := make(map[uint]ResponseRate, len(surveys))
+	if len(surveys) == 0 {
+		return rates, nil
+	}
+
+	surveyIDs := make([]uint, len(surveys))
+	subjectIDs := make([]uint, len(surveys))
+	for i, s := range surveys {
+		surveyIDs[i] = s.ID
+		subjectIDs[i] = s.SubjectID
+	}
+
+	// Enrolled students per subject/semester
+	var enrolledRows []struct {
+		SubjectID  uint
+		SemesterID uint
+		Total      int64
+	}
+	if err := db.Model(&StudentEnrollment{}).
+		Select("subject_id, semester_id, COUNT(DISTINCT student_id) AS total").
+		Where("subject_id IN ?", subjectIDs).
+		Group("subject_id, semester_id").
+		Scan(&enrolledRows).Error; err != nil {
+		return nil, err
+	}
+	enrolled := make(map[subjectSemesterKey]int64, len(enrolledRows))
+	for _, row := range enrolledRows {
+		enrolled[subjectSemesterKey{row.SubjectID, row.SemesterID}] = row.Total
+	}
+
+	// Section surveys only count the students of their section
+	var sectionRows []struct {
+		SectionID uint
+		Total     int64
+	}
+	if err := db.Model(&StudentEnrollment{}).
+		Select("section_id, COUNT(DISTINCT student_id) AS total").
+		Where("subject_id IN ? AND section_id IS NOT NULL", subjectIDs).
+		Group("section_id").
+		Scan(&sectionRows).Error; err != nil {
+		return nil, err
+	}
+	enrolledInSection := make(map[uint]int64, len(sectionRows))
+	for _, row := range sectionRows {
+		enrolledInSection[row.SectionID] = row.Total
+	}
+
+	// Distinct enrolled students with at least one response per survey
+	var respondentRows []struct {
+		SurveyID uint
+		Total    int64
+	}
+	if err := db.Model(&Response{}).
+		Select("responses.survey_id, COUNT(DISTINCT responses.student_id) AS total").
+		Joins("JOIN surveys ON responses.survey_id = surveys.id").
+		Joins(surveyEnrollmentJoin+" AND student_enrollments.student_id = responses.student_id").
+		Where("responses.survey_id IN ?", surveyIDs).
+		Group("responses.survey_id").
+		Scan(&respondentRows).Error; err != nil {
+		return nil, err
+	}
+	respondents := make(map[uint]int64, len(respondentRows))
+	for _, row := range respondentRows {
+		respondents[row.SurveyID] = row.Total
+	}
+
+	// Anonymous surveys only record participation, never who gave which answer
+	var participantRows []struct {
+		SurveyID uint
+		Total    int64
+	}
+	if err := db.Model(&SurveyParticipation{}).
+		Select("survey_participations.survey_id, COUNT(*) AS total").
+		Joins("JOIN surveys ON survey_participations.survey_id = surveys.id").
+		Joins(surveyEnrollmentJoin+" AND student_enrollments.student_id = survey_participations.student_id").
+		Where("survey_participations.survey_id IN ?", surveyIDs).
+		Group("survey_participations.survey_id").
+		Scan(&participantRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range participantRows {
+		respondents[row.SurveyID] += row.Total
+	}
+
+	for _, s := range surveys {
+		total := enrolled[subjectSemesterKey{s.SubjectID, s.SemesterID}]
+		if s.SectionID != 0 {
+			total = enrolledInSection[s.SectionID]
+		}
+		rates[s.ID] = newResponseRate(total, respondents[s.ID])
+	}
+	return rates, nil
+}
+
+// SemesterResponseRates reports the response rates of every subject offered in a
+// semester, i.e. every subject with enrollments or surveys in it, ordered by subject code
+func SemesterResponseRates(db *gorm.DB, semesterID uint) ([]SubjectResponseRate, error) {
+	var surveys []Survey
+	if err := db.Where("semester_id = ?", semesterID).Order("id ASC").Find(&surveys).Error; err != nil {
+		return nil, err
+	}
+	rates, err := ComputeResponseRates(db, surveys)
+	if err != nil {
+		return nil, err
+	}
+
+	var enrolledRows []struct {
+		SubjectID uint
+		Total     int64
+	}
+	if err := db.Model(&StudentEnrollment{}).
+		Select("subject_id, COUNT(DISTINCT student_id) AS total").
+		Where("semester_id = ?", semesterID).
+		Group("subject_id").
+		Scan(&enrolledRows).Error; err != nil {
+		return nil, err
+	}
+
+	bySubject := make(map[uint]*SubjectResponseRate)
+	var subjectIDs []uint
+	entry := func(subjectID uint) *SubjectResponseRate {
+		if e, ok := bySubject[subjectID]; ok {
+			return e
+		}
+		e := &SubjectResponseRate{Surveys: []SurveyResponseRate{}}
+		bySubject[subjectID] = e
+		subjectIDs = append(subjectIDs, subjectID)
+		return e
+	}
+	for _, row := range enrolledRows {
+		entry(row.SubjectID).Enrolled = row.Total
+	}
+	for _, s := range surveys {
+		e := entry(s.SubjectID)
+		e.Surveys = append(e.Surveys, SurveyResponseRate{SurveyID: s.ID, Title: s.Title, SectionID: s.SectionID, ResponseRate: rates[s.ID]})
+	}
+
+	var subjects []Subject
+	if len(subjectIDs) > 0 {
+		if err := db.Where("id IN ?", subjectIDs).Find(&subjects).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]SubjectResponseRate, 0, len(subjects))
+	for _, subject := range subjects {
+		e := bySubject[subject.ID]
+		e.Subject = subject
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Subject.Code < result[j].Subject.Code })
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseRates(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	otherSemester := Semester{Name: "2023.2", Year: 2023, Period: 2, StartDate: time.Now().AddDate(0, -6, 0), EndDate: time.Now().AddDate(0, -2, 0)}
+	db.Create(&otherSemester)
+
+	algorithms := Subject{Name: "Algorithms", Code: "COMP001", ProfessorID: professor.ID}
+	db.Create(&algorithms)
+	databases := Subject{Name: "Databases", Code: "COMP002", ProfessorID: professor.ID}
+	db.Create(&databases)
+	compilers := Subject{Name: "Compilers", Code: "COMP003", ProfessorID: professor.ID}
+	db.Create(&compilers)
+
+	var students []User
+	for i := 0; i < 4; i++ {
+		student := User{FirstName: "Student", LastName: fmt.Sprint(i), Email: fmt.Sprintf("student%d@example.com", i), Password: "password123", Role: RoleStudent}
+		db.Create(&student)
+		students = append(students, student)
+	}
+
+	// All 4 students take algorithms, 2 take databases; one also took algorithms last semester
+	for _, s := range students {
+		db.Create(&StudentEnrollment{StudentID: s.ID, SubjectID: algorithms.ID, SemesterID: semester.ID})
+	}
+	db.Create(&StudentEnrollment{StudentID: students[0].ID, SubjectID: databases.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: students[1].ID, SubjectID: databases.ID, SemesterID: semester.ID})
+	db.Create(&StudentEnrollment{StudentID: students[0].ID, SubjectID: algorithms.ID, SemesterID: otherSemester.ID})
+
+	algorithmsSurvey := Survey{Title: "Algorithms Feedback", SubjectID: algorithms.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+	db.Create(&algorithmsSurvey)
+	databasesSurvey := Survey{Title: "Databases Feedback", SubjectID: databases.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+	db.Create(&databasesSurvey)
+
+	q1 := Question{SurveyID: algorithmsSurvey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Order: 1}
+	db.Create(&q1)
+	q2 := Question{SurveyID: algorithmsSurvey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 2}
+	db.Create(&q2)
+
+	// Student 0 answers twice, student 1 once; students 2 and 3 don't answer
+	db.Create(&Response{SurveyID: algorithmsSurvey.ID, StudentID: students[0].ID, QuestionID: q1.ID, Answer: "9"})
+	db.Create(&Response{SurveyID: algorithmsSurvey.ID, StudentID: students[0].ID, QuestionID: q2.ID, Answer: "Nice"})
+	db.Create(&Response{SurveyID: algorithmsSurvey.ID, StudentID: students[1].ID, QuestionID: q1.ID, Answer: "7"})
+
+	t.Run("Per Survey Rates", func(t *testing.T) {
+		rates, err := ComputeResponseRates(db, []Survey{algorithmsSurvey, databasesSurvey})
+		assert.NoError(t, err)
+
+		assert.Equal(t, ResponseRate{Enrolled: 4, Respondents: 2, Rate: 0.5}, rates[algorithmsSurvey.ID])
+		assert.Equal(t, ResponseRate{Enrolled: 2, Respondents: 0, Rate: 0}, rates[databasesSurvey.ID])
+	})
+
+	t.Run("No Surveys", func(t *testing.T) {
+		rates, err := ComputeResponseRates(db, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, rates)
+	})
+
+	t.Run("No Enrollments Yields Zero Rate", func(t *testing.T) {
+		survey := Survey{Title: "Compilers Feedback", SubjectID: compilers.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		db.Create(&survey)
+		defer db.Delete(&survey)
+
+		rates, err := ComputeResponseRates(db, []Survey{survey})
+		assert.NoError(t, err)
+		assert.Equal(t, ResponseRate{}, rates[survey.ID])
+	})
+
+	t.Run("Semester Report", func(t *testing.T) {
+		report, err := SemesterResponseRates(db, semester.ID)
+		assert.NoError(t, err)
+		assert.Len(t, report, 2)
+
+		assert.Equal(t, "COMP001", report[0].Subject.Code)
+		assert.Equal(t, int64(4), report[0].Enrolled)
+		assert.Len(t, report[0].Surveys, 1)
+		assert.Equal(t, algorithmsSurvey.ID, report[0].Surveys[0].SurveyID)
+		assert.InDelta(t, 0.5, report[0].Surveys[0].Rate, 0.001)
+
+		assert.Equal(t, "COMP002", report[1].Subject.Code)
+		assert.Equal(t, int64(2), report[1].Enrolled)
+	})
+
+	t.Run("Semester Report Only Covers Its Semester", func(t *testing.T) {
+		report, err := SemesterResponseRates(db, otherSemester.ID)
+		assert.NoError(t, err)
+		assert.Len(t, report, 1)
+		assert.Equal(t, int64(1), report[0].Enrolled)
+		assert.Empty(t, report[0].Surveys)
+	})
+}
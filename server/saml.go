@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/crewjam/saml"
+)
+
+// SAMLProvider logs users in with a SAML 2.0 identity provider, sending AuthnRequests with
+// the redirect binding and receiving assertions with the POST binding
+type SAMLProvider struct {
+	MetadataURL     string // where the identity provider publishes its metadata
+	RootURL         string // public URL of this API
+	GroupsAttribute string
+	Key             crypto.Signer     // optional, signs requests and decrypts assertions
+	Certificate     *x509.Certificate // public part of Key
+
+	// Metadata is fetched on first use, so the API starts while the provider is down
+	mu sync.Mutex
+	sp *saml.ServiceProvider
+}
+
+// SAML attributes read from assertions, by friendly name or OID
+var (
+	samlEmailAttributes     = []string{"mail", "email", "urn:oid:0.9.2342.19200300.100.1.3"}
+	samlFirstNameAttributes = []string{"givenName", "urn:oid:2.5.4.42"}
+	samlLastNameAttributes  = []string{"sn", "surname", "urn:oid:2.5.4.4"}
+)
+
+// newSAMLProviderFromEnv returns the provider configured by SAML_IDP_METADATA_URL,
+// SAML_GROUPS_ATTRIBUTE and optionally SAML_SP_CERT_FILE and SAML_SP_KEY_FILE, or nil when
+// SAML isn't configured
+func newSAMLProviderFromEnv() (*SAMLProvider, error) {
+	metadataURL := os.Getenv("SAML_IDP_METADATA_URL")
+	if metadataURL == "" {
+		return nil, nil
+	}
+	groupsAttribute := os.Getenv("SAML_GROUPS_ATTRIBUTE")
+	if groupsAttribute == "" {
+		groupsAttribute = "eduPersonAffiliation"
+	}
+	provider := &SAMLProvider{MetadataURL: metadataURL, RootURL: getAPIPublicURL(), GroupsAttribute: groupsAttribute}
+
+	certFile, keyFile := os.Getenv("SAML_SP_CERT_FILE"), os.Getenv("SAML_SP_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load SAML_SP_CERT_FILE and SAML_SP_KEY_FILE: %w", err)
+		}
+		signer, ok := pair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("SAML_SP_KEY_FILE is not a signing key")
+		}
+		provider.Key = signer
+		provider.Certificate, err = x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return provider, nil
+}
+
+// serviceProvider builds the service provider once the identity provider metadata loads
+func (p *SAMLProvider) serviceProvider(ctx context.Context) (*saml.ServiceProvider, error) {
+	if p == nil {
+		return nil, ErrSSONotConfigured
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sp != nil {
+		return p.sp, nil
+	}
+
+	idpMetadata, err := fetchSAMLMetadata(ctx, p.MetadataURL)
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSuffix(p.RootURL, "/")
+	metadataURL, err := url.Parse(root + "/sso/saml/metadata")
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(root + "/sso/saml/acs")
+	if err != nil {
+		return nil, err
+	}
+	p.sp = &saml.ServiceProvider{
+		Key:               p.Key,
+		Certificate:       p.Certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.PersistentNameIDFormat,
+	}
+	return p.sp, nil
+}
+
+// fetchSAMLMetadata loads an identity provider's metadata, which may list it among others
+func fetchSAMLMetadata(ctx context.Context, metadataURL string) (*saml.EntityDescriptor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SAML metadata request failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil && len(entity.IDPSSODescriptors) > 0 {
+		return &entity, nil
+	}
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err != nil {
+		return nil, err
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, errors.New("SAML metadata describes no identity provider")
+}
+
+// Metadata returns this service provider's metadata for registering it with the identity provider
+func (p *SAMLProvider) Metadata(ctx context.Context) ([]byte, error) {
+	sp, err := p.serviceProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return xml.MarshalIndent(sp.Metadata(), "", "  ")
+}
+
+// AuthURL returns the provider's login page for a new login and the state to keep until
+// the assertion comes back
+func (p *SAMLProvider) AuthURL(ctx context.Context) (string, SSOState, error) {
+	sp, err := p.serviceProvider(ctx)
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	request, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	redirect, err := request.Redirect("", sp)
+	if err != nil {
+		return "", SSOState{}, err
+	}
+	return redirect.String(), SSOState{RequestID: request.ID}, nil
+}
+
+// Identity verifies the assertion posted to the assertion consumer service, which must
+// answer the request of this login, and returns the identity it carries
+func (p *SAMLProvider) Identity(r *http.Request, login SSOState) (SSOIdentity, error) {
+	sp, err := p.serviceProvider(r.Context())
+	if err != nil {
+		return SSOIdentity{}, err
+	}
+	if login.RequestID == "" {
+		return SSOIdentity{}, ErrInvalidSSOState
+	}
+	if err := r.ParseForm(); err != nil {
+		return SSOIdentity{}, err
+	}
+	assertion, err := sp.ParseResponse(r, []string{login.RequestID})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return SSOIdentity{}, fmt.Errorf("invalid SAML response: %w", invalid.PrivateErr)
+		}
+		return SSOIdentity{}, err
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return SSOIdentity{}, errors.New("SAML assertion has no subject")
+	}
+
+	return SSOIdentity{
+		Provider:  SSOProviderSAML,
+		Subject:   assertion.Subject.NameID.Value,
+		Email:     firstSAMLValue(assertion, samlEmailAttributes...),
+		FirstName: firstSAMLValue(assertion, samlFirstNameAttributes...),
+		LastName:  firstSAMLValue(assertion, samlLastNameAttributes...),
+		Groups:    samlValues(assertion, p.GroupsAttribute),
+	}, nil
+}
+
+// samlValues returns the values of the attributes with any of the given names or friendly names
+func samlValues(assertion *saml.Assertion, names ...string) []string {
+	var values []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, name := range names {
+				if attribute.Name == name || attribute.FriendlyName == name {
+					for _, value := range attribute.Values {
+						values = append(values, strings.TrimSpace(value.Value))
+					}
+					break
+				}
+			}
+		}
+	}
+	return values
+}
+
+func firstSAMLValue(assertion *saml.Assertion, names ...string) string {
+	for _, name := range names {
+		if values := samlValues(assertion, name); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
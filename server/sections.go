@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrSectionNotInOffering is returned when a section is not one of the sections of the
+// subject in the semester
+var ErrSectionNotInOffering = errors.New("Section does not belong to this subject and semester")
+
+// surveyEnrollmentJoin joins surveys with the enrollments of the students they are meant
+// for: every student of the subject in the semester, or only those of the survey's section
+const surveyEnrollmentJoin = "JOIN student_enrollments ON surveys.subject_id = student_enrollments.subject_id AND surveys.semester_id = student_enrollments.semester_id AND (surveys.section_id = 0 OR surveys.section_id = student_enrollments.section_id)"
+
+// dropLegacyCampaignIndex drops the campaign/subject unique index on surveys, replaced by
+// one that includes the section
+func dropLegacyCampaignIndex(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&Survey{}) || !migrator.HasIndex(&Survey{}, "idx_surveys_campaign_subject") {
+		return nil
+	}
+	return migrator.DropIndex(&Survey{}, "idx_surveys_campaign_subject")
+}
+
+// OfferingSection returns a section of the subject in the semester
+func OfferingSection(db *gorm.DB, subjectID, semesterID, sectionID uint) (Section, error) {
+	var section Section
+	err := db.Where("id = ? AND subject_id = ? AND semester_id = ?", sectionID, subjectID, semesterID).First(&section).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return section, ErrSectionNotInOffering
+	}
+	return section, err
+}
+
+// CanCreateSurvey reports whether the user may create a survey for the subject in the
+// semester, for every section (sectionID 0) or a single one. Professors of a section can
+// only create surveys for their own section.
+func CanCreateSurvey(db *gorm.DB, user User, subjectID, semesterID, sectionID uint) (bool, error) {
+	if sectionID != 0 {
+		section, err := OfferingSection(db, subjectID, semesterID, sectionID)
+		if err != nil {
+			return false, err
+		}
+		if section.ProfessorID == user.ID {
+			return true, nil
+		}
+	}
+
+	var count int64
+	if err := ManageableSubjects(db.Model(&Subject{}), user, semesterID).Where("id = ?", subjectID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ValidateEnrollmentSection checks that the section of an enrollment, if any, is a section
+// of the enrolled subject in the semester
+func ValidateEnrollmentSection(db *gorm.DB, enrollment StudentEnrollment) error {
+	if enrollment.SectionID == 0 {
+		return nil
+	}
+	_, err := OfferingSection(db, enrollment.SubjectID, enrollment.SemesterID, enrollment.SectionID)
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSections(t *testing.T) {
+	db := setupTestDB()
+
+	admin := User{FirstName: "Admin", LastName: "Test", Email: "admin@example.com", Password: "password123", Role: RoleAdmin}
+	db.Create(&admin)
+	lead := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&lead)
+	profA := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&profA)
+	profB := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&profB)
+	studentA := User{FirstName: "Student", LastName: "A", Email: "a@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&studentA)
+	studentB := User{FirstName: "Student", LastName: "B", Email: "b@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&studentB)
+	studentB2 := User{FirstName: "Student", LastName: "B2", Email: "b2@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&studentB2)
+
+	subject := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: lead.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+	other := Semester{Name: "2024.2", Year: 2024, Period: 2, StartDate: time.Now().AddDate(0, 6, 0), EndDate: time.Now().AddDate(0, 10, 0)}
+	db.Create(&other)
+
+	sectionA := Section{SubjectID: subject.ID, SemesterID: semester.ID, Code: "A", ProfessorID: profA.ID, Schedule: "Mon/Wed 08:00"}
+	db.Create(&sectionA)
+	sectionB := Section{SubjectID: subject.ID, SemesterID: semester.ID, Code: "B", ProfessorID: profB.ID, Schedule: "Tue/Thu 10:00"}
+	db.Create(&sectionB)
+
+	db.Create(&StudentEnrollment{StudentID: studentA.ID, SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionA.ID})
+	db.Create(&StudentEnrollment{StudentID: studentB.ID, SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionB.ID})
+	db.Create(&StudentEnrollment{StudentID: studentB2.ID, SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionB.ID})
+
+	t.Run("Unique Code Per Offering", func(t *testing.T) {
+		err := db.Create(&Section{SubjectID: subject.ID, SemesterID: semester.ID, Code: "A"}).Error
+		assert.True(t, isUniqueViolation(err))
+		assert.NoError(t, db.Create(&Section{SubjectID: subject.ID, SemesterID: other.ID, Code: "A"}).Error)
+	})
+
+	t.Run("Enrollment Section Must Match Offering", func(t *testing.T) {
+		assert.NoError(t, ValidateEnrollmentSection(db, StudentEnrollment{SubjectID: subject.ID, SemesterID: semester.ID}))
+		assert.NoError(t, ValidateEnrollmentSection(db, StudentEnrollment{SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionB.ID}))
+		err := ValidateEnrollmentSection(db, StudentEnrollment{SubjectID: subject.ID, SemesterID: other.ID, SectionID: sectionB.ID})
+		assert.ErrorIs(t, err, ErrSectionNotInOffering)
+	})
+
+	t.Run("Who Can Create Section Surveys", func(t *testing.T) {
+		allowed, err := CanCreateSurvey(db, profA, subject.ID, semester.ID, sectionA.ID)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+
+		// Section professors can't survey other sections or the whole subject
+		allowed, _ = CanCreateSurvey(db, profA, subject.ID, semester.ID, sectionB.ID)
+		assert.False(t, allowed)
+		allowed, _ = CanCreateSurvey(db, profA, subject.ID, semester.ID, 0)
+		assert.False(t, allowed)
+
+		allowed, _ = CanCreateSurvey(db, lead, subject.ID, semester.ID, sectionB.ID)
+		assert.True(t, allowed)
+		allowed, _ = CanCreateSurvey(db, lead, subject.ID, semester.ID, 0)
+		assert.True(t, allowed)
+
+		_, err = CanCreateSurvey(db, lead, subject.ID, other.ID, sectionB.ID)
+		assert.ErrorIs(t, err, ErrSectionNotInOffering)
+	})
+
+	surveyA := Survey{Title: "Section A", SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionA.ID, ProfessorID: profA.ID, IsActive: true}
+	db.Create(&surveyA)
+	surveyB := Survey{Title: "Section B", SubjectID: subject.ID, SemesterID: semester.ID, SectionID: sectionB.ID, ProfessorID: profB.ID, IsActive: true}
+	db.Create(&surveyB)
+	whole := Survey{Title: "Whole subject", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: lead.ID, IsActive: true}
+	db.Create(&whole)
+
+	t.Run("Students See Their Section Only", func(t *testing.T) {
+		var surveys []Survey
+		db.Joins(surveyEnrollmentJoin).Where("student_enrollments.student_id = ?", studentA.ID).Order("surveys.id ASC").Find(&surveys)
+		assert.Len(t, surveys, 2)
+		assert.Equal(t, surveyA.ID, surveys[0].ID)
+		assert.Equal(t, whole.ID, surveys[1].ID)
+	})
+
+	t.Run("Section Professors Read Their Section Only", func(t *testing.T) {
+		var surveys []Survey
+		ReadableSurveys(db, profB).Find(&surveys)
+		assert.Len(t, surveys, 1)
+		assert.Equal(t, surveyB.ID, surveys[0].ID)
+
+		var subjects []Subject
+		StaffSubjects(db, profB).Find(&subjects)
+		assert.Len(t, subjects, 1)
+	})
+
+	t.Run("Response Rates Count Section Students", func(t *testing.T) {
+		question := Question{SurveyID: surveyB.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+		db.Create(&Response{SurveyID: surveyB.ID, StudentID: studentB.ID, QuestionID: question.ID, Answer: "4"})
+		// A student of another section doesn't count as a respondent
+		db.Create(&Response{SurveyID: surveyB.ID, StudentID: studentA.ID, QuestionID: question.ID, Answer: "2"})
+
+		rates, err := ComputeResponseRates(db, []Survey{surveyA, surveyB, whole})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), rates[surveyA.ID].Enrolled)
+		assert.Equal(t, int64(2), rates[surveyB.ID].Enrolled)
+		assert.Equal(t, int64(1), rates[surveyB.ID].Respondents)
+		assert.Equal(t, int64(3), rates[whole.ID].Enrolled)
+	})
+
+	t.Run("Campaign Surveys Each Section", func(t *testing.T) {
+		template := SurveyTemplate{Name: "End of term", Questions: []TemplateQuestion{{Type: QuestionTypeNPS, Text: "Recommend?", Order: 1}}}
+		assert.NoError(t, CreateTemplate(db, admin, &template))
+
+		campaign := Campaign{Name: "End of term", SemesterID: semester.ID, TemplateID: template.ID, CreatedByID: admin.ID}
+		assert.NoError(t, CreateCampaign(db, &campaign, template, []Subject{subject}))
+		assert.Len(t, campaign.Surveys, 2)
+		assert.Equal(t, sectionA.ID, campaign.Surveys[0].SectionID)
+		assert.Equal(t, profA.ID, campaign.Surveys[0].ProfessorID)
+		assert.Equal(t, sectionB.ID, campaign.Surveys[1].SectionID)
+		assert.Equal(t, profB.ID, campaign.Surveys[1].ProfessorID)
+	})
+
+	t.Run("Legacy Campaign Index Dropped", func(t *testing.T) {
+		assert.NoError(t, db.Exec("CREATE INDEX idx_surveys_campaign_subject ON surveys (campaign_id, subject_id)").Error)
+		assert.NoError(t, dropLegacyCampaignIndex(db))
+		assert.False(t, db.Migrator().HasIndex(&Survey{}, "idx_surveys_campaign_subject"))
+		assert.True(t, db.Migrator().HasIndex(&Survey{}, "idx_surveys_campaign_offering"))
+	})
+}
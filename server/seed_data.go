@@ -284,6 +284,7 @@ func seedDatabase(db *gorm.DB) {
 			SemesterID:  currentSemester.ID,
 			ProfessorID: createdProfessors[0].ID,
 			IsActive:    true,
+			Status:      SurveyStatusOpen,
 			OpenDate:    now.AddDate(0, 0, -7), // Opened 7 days ago
 			CloseDate:   now.AddDate(0, 0, 14), // Closes in 14 days
 		},
@@ -294,6 +295,7 @@ func seedDatabase(db *gorm.DB) {
 			SemesterID:  currentSemester.ID,
 			ProfessorID: createdProfessors[0].ID,
 			IsActive:    true,
+			Status:      SurveyStatusOpen,
 			OpenDate:    now.AddDate(0, 0, -3), // Opened 3 days ago
 			CloseDate:   now.AddDate(0, 0, 21), // Closes in 21 days
 		},
@@ -304,6 +306,7 @@ func seedDatabase(db *gorm.DB) {
 			SemesterID:  currentSemester.ID,
 			ProfessorID: createdProfessors[1].ID,
 			IsActive:    true,
+			Status:      SurveyStatusOpen,
 			OpenDate:    now.AddDate(0, 0, -1), // Opened yesterday
 			CloseDate:   now.AddDate(0, 0, 30), // Closes in 30 days
 		},
@@ -314,6 +317,7 @@ func seedDatabase(db *gorm.DB) {
 			SemesterID:  currentSemester.ID,
 			ProfessorID: createdProfessors[1].ID,
 			IsActive:    false, // Inactive survey
+			Status:      SurveyStatusClosed,
 			OpenDate:    now.AddDate(0, 0, -30),
 			CloseDate:   now.AddDate(0, 0, -7),
 		},
@@ -324,6 +328,7 @@ func seedDatabase(db *gorm.DB) {
 			SemesterID:  currentSemester.ID,
 			ProfessorID: createdProfessors[2].ID,
 			IsActive:    true,
+			Status:      SurveyStatusPublished,
 			OpenDate:    now.AddDate(0, 0, 2), // Opens in 2 days (upcoming)
 			CloseDate:   now.AddDate(0, 0, 45),
 		},
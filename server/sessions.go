@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Access tokens are short-lived JWTs; refresh tokens are random strings that get a new
+// access token and are replaced by a new refresh token on every use
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("Invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token that was already rotated is
+	// used again, which revokes its session
+	ErrRefreshTokenReused = errors.New("Refresh token was already used, the session has been revoked")
+	// ErrSessionRevoked is returned for access tokens of a revoked or expired session
+	ErrSessionRevoked = errors.New("Session has been revoked")
+)
+
+// hashSecretToken returns the form refresh and password reset tokens are stored in, so a
+// leaked table can't be used to log in
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecretToken returns a random URL-safe token
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsActive reports whether the session can still refresh and authorize requests
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// StartSession opens a session for a user who just logged in and returns its refresh token
+func StartSession(db *gorm.DB, user User, userAgent, ipAddress string, now time.Time) (Session, string, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return Session{}, "", err
+	}
+	session := Session{
+		UserID:     user.ID,
+		TokenHash:  hashSecretToken(token),
+		ExpiresAt:  now.Add(refreshTokenTTL),
+		LastUsedAt: now,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return Session{}, "", err
+	}
+	return session, token, nil
+}
+
+// RefreshSession exchanges a refresh token for a new one, keeping the session. Using a
+// refresh token that was already exchanged revokes the session, since either the client
+// or whoever stole the token is replaying it.
+func RefreshSession(db *gorm.DB, token string, now time.Time) (Session, string, error) {
+	hash := hashSecretToken(token)
+
+	var session Session
+	err := db.Where("token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := db.Where("previous_token_hash = ?", hash).First(&session).Error; err == nil {
+			if err := RevokeSession(db, &session, now); err != nil {
+				return Session{}, "", err
+			}
+			return Session{}, "", ErrRefreshTokenReused
+		}
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Session{}, "", err
+	}
+	if !session.IsActive(now) {
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+
+	next, err := newSecretToken()
+	if err != nil {
+		return Session{}, "", err
+	}
+	// Only the request that still holds the current hash wins a concurrent refresh
+	result := db.Model(&Session{}).Where("id = ? AND token_hash = ?", session.ID, hash).Updates(map[string]interface{}{
+		"token_hash":          hashSecretToken(next),
+		"previous_token_hash": hash,
+		"last_used_at":        now,
+	})
+	if result.Error != nil {
+		return Session{}, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+	session.PreviousTokenHash = hash
+	session.TokenHash = hashSecretToken(next)
+	session.LastUsedAt = now
+	return session, next, nil
+}
+
+// CheckSession returns ErrSessionRevoked unless the session exists, belongs to the user
+// and is still active
+func CheckSession(db *gorm.DB, sessionID, userID uint, now time.Time) error {
+	var session Session
+	if err := db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if !session.IsActive(now) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// RevokeSession ends a single session, as on logout
+func RevokeSession(db *gorm.DB, session *Session, now time.Time) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	if err := db.Model(session).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	session.RevokedAt = &now
+	return nil
+}
+
+// RevokeUserSessions ends every active session of a user, whose access tokens stop
+// working on their next request. It returns how many sessions were revoked.
+func RevokeUserSessions(db *gorm.DB, userID uint, now time.Time) (int64, error) {
+	result := db.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// RevokeOtherSessions ends every active session of a user except one, so changing the
+// password logs out other devices but not the one making the change
+func RevokeOtherSessions(db *gorm.DB, userID, keepID uint, now time.Time) (int64, error) {
+	result := db.Model(&Session{}).Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// sessionToken logs a user in and returns an access token for the new session
+func sessionToken(db *gorm.DB, user User) string {
+	session, _, _ := StartSession(db, user, "test", "127.0.0.1", time.Now())
+	token, _ := GenerateSessionJWT(user.ID, user.Role, session.ID)
+	return token
+}
+
+func TestSessions(t *testing.T) {
+	_, testDB := setupTestRouter()
+
+	user := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	testDB.Create(&user)
+	now := time.Now()
+
+	t.Run("Refresh Tokens Rotate", func(t *testing.T) {
+		session, token, err := StartSession(testDB, user, "test", "127.0.0.1", now)
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, session.TokenHash)
+
+		refreshed, next, err := RefreshSession(testDB, token, now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, session.ID, refreshed.ID)
+		assert.NotEqual(t, token, next)
+
+		_, _, err = RefreshSession(testDB, next, now.Add(2*time.Minute))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reused Refresh Token Revokes The Session", func(t *testing.T) {
+		session, token, _ := StartSession(testDB, user, "test", "127.0.0.1", now)
+		_, next, err := RefreshSession(testDB, token, now)
+		assert.NoError(t, err)
+
+		_, _, err = RefreshSession(testDB, token, now)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		// The legitimate token stops working too
+		_, _, err = RefreshSession(testDB, next, now)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		assert.ErrorIs(t, CheckSession(testDB, session.ID, user.ID, now), ErrSessionRevoked)
+	})
+
+	t.Run("Unknown And Expired Refresh Tokens", func(t *testing.T) {
+		_, _, err := RefreshSession(testDB, "not-a-token", now)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		_, token, _ := StartSession(testDB, user, "test", "127.0.0.1", now)
+		_, _, err = RefreshSession(testDB, token, now.Add(refreshTokenTTL+time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("Revoke Every Session Of A User", func(t *testing.T) {
+		other := User{FirstName: "Bruno", LastName: "Lima", Email: "bruno@example.com", Password: "password123", Role: RoleStudent}
+		testDB.Create(&other)
+		first, _, _ := StartSession(testDB, other, "test", "127.0.0.1", now)
+		second, _, _ := StartSession(testDB, other, "test", "127.0.0.1", now)
+
+		revoked, err := RevokeUserSessions(testDB, other.ID, now)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), revoked)
+		assert.ErrorIs(t, CheckSession(testDB, first.ID, other.ID, now), ErrSessionRevoked)
+		assert.ErrorIs(t, CheckSession(testDB, second.ID, other.ID, now), ErrSessionRevoked)
+		// Sessions of other users are untouched
+		active, _, _ := StartSession(testDB, user, "test", "127.0.0.1", now)
+		assert.NoError(t, CheckSession(testDB, active.ID, user.ID, now))
+	})
+
+	t.Run("Access Tokens Of Revoked Sessions Are Rejected", func(t *testing.T) {
+		session, _, _ := StartSession(testDB, user, "test", "127.0.0.1", now)
+		token, _ := GenerateSessionJWT(user.ID, user.Role, session.ID)
+
+		r := gin.New()
+		r.Use(RequireRole(RoleStudent))
+		r.GET("/test", func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "authorized"})
+		})
+		request := func() int {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, 200, request())
+		assert.NoError(t, RevokeSession(testDB, &session, time.Now()))
+		assert.Equal(t, 401, request())
+	})
+
+	t.Run("Access Tokens Without A Session Are Rejected", func(t *testing.T) {
+		token, _ := GenerateSessionJWT(user.ID, user.Role, 0)
+
+		r := gin.New()
+		r.Use(RequireRole(RoleStudent))
+		r.GET("/test", func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "authorized"})
+		})
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 401, w.Code)
+	})
+
+	t.Run("Role Changes Apply To Existing Tokens", func(t *testing.T) {
+		promoted := User{FirstName: "Carla", LastName: "Dias", Email: "carla@example.com", Password: "password123", Role: RoleStudent}
+		testDB.Create(&promoted)
+		token := sessionToken(testDB, promoted)
+		testDB.Model(&promoted).Update("role", RoleProfessor)
+
+		r := gin.New()
+		r.Use(RequireRole(RoleStudent))
+		r.GET("/test", func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "authorized"})
+		})
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 403, w.Code)
+	})
+}
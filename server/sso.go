@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// SSO providers, stored in UserIdentity.Provider
+	SSOProviderOIDC = "oidc"
+	SSOProviderSAML = "saml"
+
+	// ssoStateTTL bounds the round trip through the identity provider
+	ssoStateTTL = 10 * time.Minute
+	// loginTicketTTL bounds the hand-off from the SSO callback to the client
+	loginTicketTTL = time.Minute
+)
+
+var (
+	// ErrSSONotConfigured is returned for a provider missing from the environment
+	ErrSSONotConfigured = errors.New("Single sign-on is not configured")
+	// ErrInvalidSSOState is returned for callbacks that don't match a login started here
+	ErrInvalidSSOState = errors.New("Invalid or expired single sign-on request")
+	// ErrSSOEmailMissing is returned when the identity provider doesn't share an email
+	ErrSSOEmailMissing = errors.New("The identity provider did not share an email address")
+	// ErrSSOEmailUnverified is returned when the identity provider says the email isn't verified
+	ErrSSOEmailUnverified = errors.New("The identity provider has not verified the email address")
+	// ErrSSONotMember is returned when SSO_STUDENT_GROUPS is set and the user is in none of the groups
+	ErrSSONotMember = errors.New("Your account is not allowed to use this system")
+	// ErrInvalidLoginTicket is returned for unknown, used or expired login tickets
+	ErrInvalidLoginTicket = errors.New("Invalid or expired login ticket")
+)
+
+// SSOIdentity is what an identity provider tells about a user after they log in
+type SSOIdentity struct {
+	Provider  string
+	Subject   string // the provider's stable ID for the user
+	Email     string
+	FirstName string
+	LastName  string
+	Groups    []string
+	// EmailUnverified is set when the provider explicitly says the email isn't verified
+	EmailUnverified bool
+}
+
+// SSORoleMapping maps identity provider groups or affiliations onto roles
+type SSORoleMapping struct {
+	ProfessorGroups []string
+	// StudentGroups, when set, limits SSO to members of these or the professor groups
+	StudentGroups []string
+}
+
+// getSSORoleMapping reads SSO_PROFESSOR_GROUPS and SSO_STUDENT_GROUPS, comma separated
+func getSSORoleMapping() SSORoleMapping {
+	return SSORoleMapping{
+		ProfessorGroups: splitList(os.Getenv("SSO_PROFESSOR_GROUPS")),
+		StudentGroups:   splitList(os.Getenv("SSO_STUDENT_GROUPS")),
+	}
+}
+
+// splitList splits a comma separated setting, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Role returns the role for a user in the given groups: professor if they are in a
+// professor group, student otherwise. Groups compare case-insensitively.
+func (m SSORoleMapping) Role(groups []string) (string, error) {
+	inAny := func(names []string) bool {
+		for _, group := range groups {
+			for _, name := range names {
+				if strings.EqualFold(strings.TrimSpace(group), name) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if inAny(m.ProfessorGroups) {
+		return RoleProfessor, nil
+	}
+	if len(m.StudentGroups) > 0 && !inAny(m.StudentGroups) {
+		return "", ErrSSONotMember
+	}
+	return RoleStudent, nil
+}
+
+// ProvisionSSOUser returns the user an SSO login belongs to. The identity is matched by
+// its provider subject, then linked to the account with the same email, and otherwise a
+// new user is created. Students whose groups map to professor are promoted; other roles
+// set by admins are kept.
+func ProvisionSSOUser(db *gorm.DB, identity SSOIdentity, mapping SSORoleMapping) (User, error) {
+	var user User
+	email := normalizeEmail(identity.Email)
+	if email == "" {
+		return user, ErrSSOEmailMissing
+	}
+	role, err := mapping.Role(identity.Groups)
+	if err != nil {
+		return user, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var link UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// Linking by email trusts the provider to have verified it
+			if identity.EmailUnverified {
+				return ErrSSOEmailUnverified
+			}
+			err := tx.Where("LOWER(email) = ?", email).First(&user).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := createSSOUser(tx, &user, identity, email, role); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+			if err := linkSSOIdentity(tx, user.ID, identity); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		// The provider vouches for the email
+		if user.EmailUnverified {
+			if err := MarkEmailVerified(tx, &user); err != nil {
+				return err
+			}
+		}
+		if user.Role == RoleStudent && role == RoleProfessor {
+			if err := tx.Model(&user).Updates(map[string]interface{}{"role": role, "requested_role": role}).Error; err != nil {
+				return err
+			}
+			user.Role, user.RequestedRole = role, role
+		}
+		return nil
+	})
+	return user, err
+}
+
+// createSSOUser creates the account of a first SSO login. Its random password is never
+// shown, so the user logs in through SSO or sets a password with a reset link.
+func createSSOUser(db *gorm.DB, user *User, identity SSOIdentity, email, role string) error {
+	if err := CheckEmailDomain(db, email); err != nil {
+		return err
+	}
+	secret, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	hashed, err := HashPassword(secret)
+	if err != nil {
+		return err
+	}
+
+	*user = User{
+		FirstName:     identity.FirstName,
+		LastName:      identity.LastName,
+		Email:         email,
+		Password:      hashed,
+		Role:          role,
+		RequestedRole: role,
+	}
+	if user.FirstName == "" {
+		user.FirstName = strings.SplitN(email, "@", 2)[0]
+	}
+	return db.Create(user).Error
+}
+
+// linkSSOIdentity records the provider subject of a user, replacing the one they had with
+// the same provider (SAML providers may send a new subject when NameIDs are transient)
+func linkSSOIdentity(db *gorm.DB, userID uint, identity SSOIdentity) error {
+	var link UserIdentity
+	err := db.Where("user_id = ? AND provider = ?", userID, identity.Provider).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Create(&UserIdentity{UserID: userID, Provider: identity.Provider, Subject: identity.Subject}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return db.Model(&link).Update("subject", identity.Subject).Error
+}
+
+// IssueLoginTicket returns a single-use ticket the client exchanges for tokens through
+// POST /sso/exchange, so tokens never travel in a redirect URL
+func IssueLoginTicket(db *gorm.DB, user User, now time.Time) (string, error) {
+	ticket, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	record := LoginTicket{UserID: user.ID, TokenHash: hashSecretToken(ticket), ExpiresAt: now.Add(loginTicketTTL)}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// RedeemLoginTicket returns the user a ticket was issued to. The ticket can't be used again.
+func RedeemLoginTicket(db *gorm.DB, ticket string, now time.Time) (User, error) {
+	var user User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var record LoginTicket
+		if err := tx.Where("token_hash = ?", hashSecretToken(ticket)).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidLoginTicket
+			}
+			return err
+		}
+		if record.UsedAt != nil || !now.Before(record.ExpiresAt) {
+			return ErrInvalidLoginTicket
+		}
+		result := tx.Model(&LoginTicket{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidLoginTicket
+		}
+		return tx.First(&user, record.UserID).Error
+	})
+	return user, err
+}
+
+// SSOState is carried through the identity provider round trip, signed so it can't be forged
+type SSOState struct {
+	State     string `json:"state,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+	Verifier  string `json:"verifier,omitempty"`   // OIDC PKCE code verifier
+	RequestID string `json:"request_id,omitempty"` // SAML AuthnRequest ID
+	jwt.RegisteredClaims
+}
+
+const ssoStateAudience = "sso-state"
+
+// SignSSOState signs the state of a login that expires after ssoStateTTL
+func SignSSOState(state SSOState, now time.Time) (string, error) {
+	state.RegisteredClaims = jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{ssoStateAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ssoStateTTL)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, state).SignedString(getJWTSecret())
+}
+
+// ParseSSOState returns the state signed by SignSSOState, or ErrInvalidSSOState
+func ParseSSOState(signed string) (SSOState, error) {
+	var state SSOState
+	_, err := jwt.ParseWithClaims(signed, &state, func(token *jwt.Token) (interface{}, error) {
+		return getJWTSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(ssoStateAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return SSOState{}, ErrInvalidSSOState
+	}
+	return state, nil
+}
+
+// getAPIPublicURL returns the URL identity providers send users back to, API_PUBLIC_URL
+// or the local server
+func getAPIPublicURL() string {
+	if publicURL := os.Getenv("API_PUBLIC_URL"); publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+	return "http://localhost:3030"
+}
+
+// getSSOCallbackURL returns the client page SSO logins end on, SSO_CALLBACK_URL or the
+// callback page of the CORS origin
+func getSSOCallbackURL() string {
+	return getClientURL("SSO_CALLBACK_URL", "/sso/callback")
+}
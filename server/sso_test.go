@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSO(t *testing.T) {
+	db := setupTestDB()
+	now := time.Now()
+	mapping := SSORoleMapping{ProfessorGroups: []string{"docente"}}
+
+	t.Run("Role Mapping", func(t *testing.T) {
+		role, err := mapping.Role([]string{"aluno", "Docente"})
+		assert.NoError(t, err)
+		assert.Equal(t, RoleProfessor, role)
+		role, err = mapping.Role(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, RoleStudent, role)
+
+		members := SSORoleMapping{ProfessorGroups: []string{"docente"}, StudentGroups: []string{"aluno"}}
+		role, err = members.Role([]string{"aluno"})
+		assert.NoError(t, err)
+		assert.Equal(t, RoleStudent, role)
+		_, err = members.Role([]string{"visitante"})
+		assert.ErrorIs(t, err, ErrSSONotMember)
+	})
+
+	t.Run("Provision New User", func(t *testing.T) {
+		identity := SSOIdentity{Provider: SSOProviderOIDC, Subject: "sub-ana", Email: "Ana@usp.br", FirstName: "Ana", LastName: "Souza", Groups: []string{"docente"}}
+		user, err := ProvisionSSOUser(db, identity, mapping)
+		assert.NoError(t, err)
+		assert.Equal(t, "ana@usp.br", user.Email)
+		assert.Equal(t, RoleProfessor, user.Role)
+		assert.False(t, user.EmailUnverified)
+
+		// The same subject logs in to the same account
+		again, err := ProvisionSSOUser(db, identity, mapping)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, again.ID)
+		var users int64
+		db.Model(&User{}).Where("email = ?", "ana@usp.br").Count(&users)
+		assert.Equal(t, int64(1), users)
+	})
+
+	t.Run("Link Existing User By Email", func(t *testing.T) {
+		local := User{FirstName: "Bruno", LastName: "Lima", Email: "bruno@usp.br", Password: "password123", Role: RoleStudent, EmailUnverified: true}
+		db.Create(&local)
+
+		user, err := ProvisionSSOUser(db, SSOIdentity{Provider: SSOProviderSAML, Subject: "saml-bruno", Email: "BRUNO@usp.br", Groups: []string{"docente"}}, mapping)
+		assert.NoError(t, err)
+		assert.Equal(t, local.ID, user.ID)
+		// The provider vouches for the email, and the student is promoted
+		assert.False(t, user.EmailUnverified)
+		assert.Equal(t, RoleProfessor, user.Role)
+
+		var link UserIdentity
+		assert.NoError(t, db.Where("user_id = ? AND provider = ?", local.ID, SSOProviderSAML).First(&link).Error)
+		assert.Equal(t, "saml-bruno", link.Subject)
+	})
+
+	t.Run("Admin Roles Are Kept", func(t *testing.T) {
+		admin := User{FirstName: "Carla", LastName: "Dias", Email: "carla@usp.br", Password: "password123", Role: RoleAdmin}
+		db.Create(&admin)
+
+		user, err := ProvisionSSOUser(db, SSOIdentity{Provider: SSOProviderOIDC, Subject: "sub-carla", Email: "carla@usp.br"}, mapping)
+		assert.NoError(t, err)
+		assert.Equal(t, RoleAdmin, user.Role)
+	})
+
+	t.Run("Unverified Or Missing Emails", func(t *testing.T) {
+		_, err := ProvisionSSOUser(db, SSOIdentity{Provider: SSOProviderOIDC, Subject: "sub-x", Email: "carla@usp.br", EmailUnverified: true}, mapping)
+		assert.ErrorIs(t, err, ErrSSOEmailUnverified)
+		_, err = ProvisionSSOUser(db, SSOIdentity{Provider: SSOProviderOIDC, Subject: "sub-y"}, mapping)
+		assert.ErrorIs(t, err, ErrSSOEmailMissing)
+	})
+
+	t.Run("Allowed Domains Apply To New Users", func(t *testing.T) {
+		domainDB := setupTestDB()
+		_, err := AddAllowedDomain(domainDB, "usp.br")
+		assert.NoError(t, err)
+
+		_, err = ProvisionSSOUser(domainDB, SSOIdentity{Provider: SSOProviderOIDC, Subject: "sub-z", Email: "eva@gmail.com"}, mapping)
+		assert.ErrorIs(t, err, ErrEmailDomainNotAllowed)
+	})
+
+	t.Run("Login Tickets", func(t *testing.T) {
+		var user User
+		db.Where("email = ?", "ana@usp.br").First(&user)
+
+		ticket, err := IssueLoginTicket(db, user, now)
+		assert.NoError(t, err)
+		redeemed, err := RedeemLoginTicket(db, ticket, now)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, redeemed.ID)
+		_, err = RedeemLoginTicket(db, ticket, now)
+		assert.ErrorIs(t, err, ErrInvalidLoginTicket)
+
+		expired, _ := IssueLoginTicket(db, user, now)
+		_, err = RedeemLoginTicket(db, expired, now.Add(loginTicketTTL))
+		assert.ErrorIs(t, err, ErrInvalidLoginTicket)
+	})
+
+	t.Run("Signed State", func(t *testing.T) {
+		signed, err := SignSSOState(SSOState{State: "abc", RequestID: "id-1"}, now)
+		assert.NoError(t, err)
+		state, err := ParseSSOState(signed)
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", state.State)
+		assert.Equal(t, "id-1", state.RequestID)
+
+		_, err = ParseSSOState(signed[:len(signed)-2] + "xx")
+		assert.ErrorIs(t, err, ErrInvalidSSOState)
+		old, _ := SignSSOState(SSOState{State: "abc"}, now.Add(-ssoStateTTL))
+		_, err = ParseSSOState(old)
+		assert.ErrorIs(t, err, ErrInvalidSSOState)
+		// Access tokens are signed with the same secret but aren't login states
+		accessToken, _ := GenerateSessionJWT(1, RoleStudent, 1)
+		_, err = ParseSSOState(accessToken)
+		assert.ErrorIs(t, err, ErrInvalidSSOState)
+	})
+
+	t.Run("Unconfigured Providers", func(t *testing.T) {
+		var oidcProvider *OIDCProvider
+		_, _, err := oidcProvider.AuthURL(context.Background())
+		assert.ErrorIs(t, err, ErrSSONotConfigured)
+		var samlProvider *SAMLProvider
+		_, _, err = samlProvider.AuthURL(context.Background())
+		assert.ErrorIs(t, err, ErrSSONotConfigured)
+	})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockOIDCProvider(t, map[string]interface{}{
+		"email":          "ana@usp.br",
+		"email_verified": true,
+		"name":           "Ana Maria Souza",
+		"groups":         []string{"docente"},
+	})
+	provider := &OIDCProvider{
+		Issuer:       idp.URL,
+		ClientID:     "consulta-discente",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3030/sso/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  "groups",
+	}
+	ctx := context.Background()
+
+	t.Run("Authorization Code Flow", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		callback := followRedirect(t, authURL)
+		assert.True(t, strings.HasPrefix(callback.String(), provider.RedirectURL))
+
+		identity, err := provider.Identity(ctx, login, callback.Query().Get("state"), callback.Query().Get("code"))
+		assert.NoError(t, err)
+		assert.Equal(t, SSOProviderOIDC, identity.Provider)
+		assert.Equal(t, "mock-subject", identity.Subject)
+		assert.Equal(t, "ana@usp.br", identity.Email)
+		assert.Equal(t, "Ana", identity.FirstName)
+		assert.Equal(t, "Maria Souza", identity.LastName)
+		assert.Equal(t, []string{"docente"}, identity.Groups)
+		assert.False(t, identity.EmailUnverified)
+	})
+
+	t.Run("State Must Match", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		callback := followRedirect(t, authURL)
+
+		_, err = provider.Identity(ctx, login, "forged", callback.Query().Get("code"))
+		assert.ErrorIs(t, err, ErrInvalidSSOState)
+	})
+
+	t.Run("Nonce Must Match", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		callback := followRedirect(t, authURL)
+
+		login.Nonce = "other"
+		_, err = provider.Identity(ctx, login, callback.Query().Get("state"), callback.Query().Get("code"))
+		assert.ErrorIs(t, err, ErrInvalidSSOState)
+	})
+
+	t.Run("PKCE Verifier Must Match", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		callback := followRedirect(t, authURL)
+
+		login.Verifier = "wrong-verifier-wrong-verifier-wrong-verifier"
+		_, err = provider.Identity(ctx, login, callback.Query().Get("state"), callback.Query().Get("code"))
+		assert.Error(t, err)
+	})
+}
+
+func TestSAMLLogin(t *testing.T) {
+	provider := &SAMLProvider{RootURL: "http://localhost:3030", GroupsAttribute: "eduPersonAffiliation"}
+	idp := newMockSAMLProvider(t, provider, &saml.Session{
+		ID:            "session-1",
+		NameID:        "saml-ana",
+		NameIDFormat:  string(saml.PersistentNameIDFormat),
+		UserEmail:     "ana@usp.br",
+		UserGivenName: "Ana",
+		UserSurname:   "Souza",
+		Groups:        []string{"docente", "member"},
+	})
+	provider.MetadataURL = idp.URL + "/metadata"
+	ctx := context.Background()
+
+	// postAssertion sends the login request to the mock provider and posts its answer to the ACS
+	postAssertion := func(t *testing.T, authURL string) *http.Request {
+		resp, err := http.Get(authURL)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		page, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		match := regexp.MustCompile(`name="SAMLResponse" value="([^"]+)"`).FindStringSubmatch(string(page))
+		if !assert.Len(t, match, 2, string(page)) {
+			t.FailNow()
+		}
+
+		form := url.Values{"SAMLResponse": {html.UnescapeString(match[1])}}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:3030/sso/saml/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	t.Run("Metadata", func(t *testing.T) {
+		metadata, err := provider.Metadata(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, string(metadata), "http://localhost:3030/sso/saml/acs")
+	})
+
+	t.Run("Signed Assertion", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, login.RequestID)
+
+		identity, err := provider.Identity(postAssertion(t, authURL), login)
+		assert.NoError(t, err)
+		assert.Equal(t, SSOProviderSAML, identity.Provider)
+		assert.Equal(t, "saml-ana", identity.Subject)
+		assert.Equal(t, "ana@usp.br", identity.Email)
+		assert.Equal(t, "Ana", identity.FirstName)
+		assert.Equal(t, "Souza", identity.LastName)
+		assert.Equal(t, []string{"docente", "member"}, identity.Groups)
+	})
+
+	t.Run("Assertion Must Answer This Login", func(t *testing.T) {
+		authURL, _, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		_, other, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+
+		_, err = provider.Identity(postAssertion(t, authURL), other)
+		assert.Error(t, err)
+	})
+
+	t.Run("Tampered Assertion", func(t *testing.T) {
+		authURL, login, err := provider.AuthURL(ctx)
+		assert.NoError(t, err)
+		req := postAssertion(t, authURL)
+
+		req.ParseForm()
+		decoded, _ := base64.StdEncoding.DecodeString(req.PostForm.Get("SAMLResponse"))
+		forged := strings.Replace(string(decoded), "ana@usp.br", "eve@usp.br", -1)
+		form := url.Values{"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(forged))}}
+		tampered := httptest.NewRequest(http.MethodPost, "http://localhost:3030/sso/saml/acs", strings.NewReader(form.Encode()))
+		tampered.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		_, err = provider.Identity(tampered, login)
+		assert.Error(t, err)
+	})
+}
+
+// followRedirect requests url and returns where it redirects to
+func followRedirect(t *testing.T, target string) *url.URL {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(target)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return location
+}
+
+// newMockOIDCProvider starts an OpenID Connect provider that logs everyone in as
+// "mock-subject" with the given claims
+func newMockOIDCProvider(t *testing.T, claims map[string]interface{}) *httptest.Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type grant struct{ clientID, nonce, challenge string }
+	grants := map[string]grant{}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	writeJSON := func(w http.ResponseWriter, value interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(value)
+	}
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                server.URL,
+			"authorization_endpoint":                server.URL + "/authorize",
+			"token_endpoint":                        server.URL + "/token",
+			"jwks_uri":                              server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code := "code-" + query.Get("state")
+		grants[code] = grant{clientID: query.Get("client_id"), nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		granted, ok := grants[r.PostForm.Get("code")]
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != granted.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(grants, r.PostForm.Get("code"))
+
+		idClaims := jwt.MapClaims{
+			"iss":   server.URL,
+			"sub":   "mock-subject",
+			"aud":   granted.clientID,
+			"nonce": granted.nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+		for name, value := range claims {
+			idClaims[name] = value
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idClaims)
+		token.Header["kid"] = "mock"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"access_token": "mock-access-token", "token_type": "Bearer", "id_token": idToken, "expires_in": 60})
+	})
+	return server
+}
+
+// mockSAMLServiceProviders hands the mock identity provider the metadata of the provider under test
+type mockSAMLServiceProviders struct{ provider *SAMLProvider }
+
+func (m mockSAMLServiceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	sp, err := m.provider.serviceProvider(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return sp.Metadata(), nil
+}
+
+// mockSAMLSession logs everyone in with the same session
+type mockSAMLSession struct{ session *saml.Session }
+
+func (m mockSAMLSession) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	return m.session
+}
+
+// newMockSAMLProvider starts a SAML identity provider that signs assertions for the given session
+func newMockSAMLProvider(t *testing.T, provider *SAMLProvider, session *saml.Session) *httptest.Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mock-idp"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             cert,
+		ServiceProviderProvider: mockSAMLServiceProviders{provider},
+		SessionProvider:         mockSAMLSession{session},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	metadataURL, _ := url.Parse(server.URL + "/metadata")
+	ssoURL, _ := url.Parse(server.URL + "/sso")
+	idp.MetadataURL, idp.SSOURL = *metadataURL, *ssoURL
+	return server
+}
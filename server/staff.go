@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Staff roles in a subject offering (a subject taught in a semester)
+const (
+	StaffRoleLead        = "lead"
+	StaffRoleCoProfessor = "co_professor"
+	StaffRoleTA          = "ta"
+)
+
+// Survey access levels of a staff member
+const (
+	SurveyAccessManage = "manage"
+	SurveyAccessRead   = "read"
+)
+
+var (
+	// ErrInvalidStaffRole is returned for a staff role other than lead, co_professor or ta
+	ErrInvalidStaffRole = errors.New("Staff role must be lead, co_professor or ta")
+	// ErrLeadAlreadyAssigned is returned when an offering would get a second lead professor
+	ErrLeadAlreadyAssigned = errors.New("This offering already has a lead professor")
+	// ErrNotOfferingStaff is returned when a question evaluates someone outside the offering's staff
+	ErrNotOfferingStaff = errors.New("Staff member is not part of this offering")
+)
+
+// managingStaffRoles can edit the surveys of their offering; TAs can only read them
+var managingStaffRoles = []string{StaffRoleLead, StaffRoleCoProfessor}
+
+var allStaffRoles = []string{StaffRoleLead, StaffRoleCoProfessor, StaffRoleTA}
+
+func isStaffRole(role string) bool {
+	return role == StaffRoleLead || role == StaffRoleCoProfessor || role == StaffRoleTA
+}
+
+// ValidateStaffAssignment checks the role of an assignment and that an offering has at
+// most one assigned lead professor
+func ValidateStaffAssignment(db *gorm.DB, assignment StaffAssignment) error {
+	if !isStaffRole(assignment.Role) {
+		return ErrInvalidStaffRole
+	}
+	if assignment.Role != StaffRoleLead {
+		return nil
+	}
+
+	var leads int64
+	if err := db.Model(&StaffAssignment{}).
+		Where("subject_id = ? AND semester_id = ? AND role = ? AND id <> ?", assignment.SubjectID, assignment.SemesterID, StaffRoleLead, assignment.ID).
+		Count(&leads).Error; err != nil {
+		return err
+	}
+	if leads > 0 {
+		return ErrLeadAlreadyAssigned
+	}
+	return nil
+}
+
+// ReadableSurveys limits a survey query to the surveys the user may read: the ones they
+// created, those of subjects they are the professor of, those of offerings they are staff
+// of and those of sections they teach
+func ReadableSurveys(query *gorm.DB, user User) *gorm.DB {
+	return staffSurveys(query, user, allStaffRoles)
+}
+
+// ManageableSurveys limits a survey query to the surveys the user may change, which
+// leaves out the surveys they can only read as a TA
+func ManageableSurveys(query *gorm.DB, user User) *gorm.DB {
+	return staffSurveys(query, user, managingStaffRoles)
+}
+
+func staffSurveys(query *gorm.DB, user User, roles []string) *gorm.DB {
+	return query.Where(`(surveys.professor_id = ? OR surveys.subject_id IN (SELECT id FROM subjects WHERE professor_id = ?)
+		OR surveys.section_id IN (SELECT id FROM sections WHERE professor_id = ?)
+		OR EXISTS (SELECT 1 FROM staff_assignments WHERE staff_assignments.subject_id = surveys.subject_id
+			AND staff_assignments.semester_id = surveys.semester_id AND staff_assignments.user_id = ? AND staff_assignments.role IN ?))`,
+		user.ID, user.ID, user.ID, user.ID, roles)
+}
+
+// ManageableSurveyIDs returns the IDs of the surveys the user may change
+func ManageableSurveyIDs(db *gorm.DB, user User) (map[uint]bool, error) {
+	var ids []uint
+	if err := ManageableSurveys(db.Model(&Survey{}), user).Pluck("surveys.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	manageable := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		manageable[id] = true
+	}
+	return manageable, nil
+}
+
+// StaffSubjects limits a subject query to the subjects the user is the professor of, staff
+// of or teaches a section of, in any semester
+func StaffSubjects(query *gorm.DB, user User) *gorm.DB {
+	return query.Where(`(subjects.professor_id = ? OR subjects.id IN (SELECT subject_id FROM staff_assignments WHERE user_id = ?)
+		OR subjects.id IN (SELECT subject_id FROM sections WHERE professor_id = ?))`, user.ID, user.ID, user.ID)
+}
+
+// ManageableSubjects limits a subject query to the subjects the user may create surveys
+// for in a semester: as their professor or as lead or co-professor of the offering
+func ManageableSubjects(query *gorm.DB, user User, semesterID uint) *gorm.DB {
+	return query.Where(`(subjects.professor_id = ? OR subjects.id IN (SELECT subject_id FROM staff_assignments
+		WHERE user_id = ? AND semester_id = ? AND role IN ?))`, user.ID, user.ID, semesterID, managingStaffRoles)
+}
+
+// publicUser limits a preloaded user to the columns safe to show other users, leaving out
+// the password hash
+func publicUser(tx *gorm.DB) *gorm.DB {
+	return tx.Select("id", "first_name", "last_name", "email", "role")
+}
+
+// OfferingStaff returns the staff of a subject in a semester, lead first. The subject's
+// professor is listed as the lead unless the offering has an assigned lead or already lists them.
+func OfferingStaff(db *gorm.DB, subjectID, semesterID uint) ([]StaffAssignment, error) {
+	var subject Subject
+	if err := db.Preload("Professor", publicUser).First(&subject, subjectID).Error; err != nil {
+		return nil, err
+	}
+
+	var assigned []StaffAssignment
+	if err := db.Preload("User", publicUser).
+		Where("subject_id = ? AND semester_id = ?", subjectID, semesterID).
+		Order("id ASC").Find(&assigned).Error; err != nil {
+		return nil, err
+	}
+
+	hasLead, listsProfessor := false, false
+	for _, a := range assigned {
+		hasLead = hasLead || a.Role == StaffRoleLead
+		listsProfessor = listsProfessor || a.UserID == subject.ProfessorID
+	}
+
+	staff := make([]StaffAssignment, 0, len(assigned)+1)
+	if !hasLead && !listsProfessor {
+		staff = append(staff, StaffAssignment{SubjectID: subjectID, SemesterID: semesterID, UserID: subject.ProfessorID, User: subject.Professor, Role: StaffRoleLead})
+	}
+	for _, role := range allStaffRoles {
+		for _, a := range assigned {
+			if a.Role == role {
+				staff = append(staff, a)
+			}
+		}
+	}
+	return staff, nil
+}
+
+// IsOfferingStaff reports whether the user is one of the given staff members
+func IsOfferingStaff(staff []StaffAssignment, userID uint) bool {
+	for _, s := range staff {
+		if s.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// StaffQuestions returns one copy of the question for each staff member, numbered from firstOrder
+func StaffQuestions(question Question, staff []StaffAssignment, firstOrder int) []Question {
+	questions := make([]Question, len(staff))
+	for i, s := range staff {
+		questions[i] = Question{
+			SurveyID: question.SurveyID,
+			Type:     question.Type,
+			Text:     question.Text,
+			Required: question.Required,
+			Options:  question.Options,
+			Order:    firstOrder + i,
+			StaffID:  s.UserID,
+		}
+	}
+	return questions
+}
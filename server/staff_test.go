@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaffAccess(t *testing.T) {
+	db := setupTestDB()
+
+	lead := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&lead)
+	coProfessor := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&coProfessor)
+	ta := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&ta)
+	outsider := User{FirstName: "Other", LastName: "Prof", Email: "other@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&outsider)
+
+	subject := Subject{Name: "Algorithms", Code: "MAC201", ProfessorID: lead.ID}
+	db.Create(&subject)
+	current := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&current)
+	next := Semester{Name: "2024.2", Year: 2024, Period: 2, StartDate: time.Now().AddDate(0, 6, 0), EndDate: time.Now().AddDate(0, 10, 0)}
+	db.Create(&next)
+
+	db.Create(&StaffAssignment{SubjectID: subject.ID, SemesterID: current.ID, UserID: coProfessor.ID, Role: StaffRoleCoProfessor})
+	db.Create(&StaffAssignment{SubjectID: subject.ID, SemesterID: current.ID, UserID: ta.ID, Role: StaffRoleTA})
+
+	// One survey created by the co-professor this semester, one by the lead next semester
+	coSurvey := Survey{Title: "Midterm", SubjectID: subject.ID, SemesterID: current.ID, ProfessorID: coProfessor.ID}
+	db.Create(&coSurvey)
+	leadSurvey := Survey{Title: "Next term", SubjectID: subject.ID, SemesterID: next.ID, ProfessorID: lead.ID}
+	db.Create(&leadSurvey)
+
+	surveyIDs := func(query func() []Survey) []uint {
+		ids := []uint{}
+		for _, s := range query() {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	readable := func(user User) []uint {
+		return surveyIDs(func() []Survey {
+			var surveys []Survey
+			ReadableSurveys(db, user).Order("id ASC").Find(&surveys)
+			return surveys
+		})
+	}
+	manageable := func(user User) []uint {
+		return surveyIDs(func() []Survey {
+			var surveys []Survey
+			ManageableSurveys(db, user).Order("id ASC").Find(&surveys)
+			return surveys
+		})
+	}
+
+	t.Run("Lead Professor", func(t *testing.T) {
+		// The subject's professor keeps access to every survey of the subject
+		assert.Equal(t, []uint{coSurvey.ID, leadSurvey.ID}, readable(lead))
+		assert.Equal(t, []uint{coSurvey.ID, leadSurvey.ID}, manageable(lead))
+	})
+
+	t.Run("Co-Professor", func(t *testing.T) {
+		assert.Equal(t, []uint{coSurvey.ID}, readable(coProfessor))
+		assert.Equal(t, []uint{coSurvey.ID}, manageable(coProfessor))
+
+		var subject Subject
+		assert.NoError(t, ManageableSubjects(db, coProfessor, current.ID).First(&subject).Error)
+		assert.Error(t, ManageableSubjects(db, coProfessor, next.ID).First(&subject).Error)
+	})
+
+	t.Run("TA Is Read Only", func(t *testing.T) {
+		assert.Equal(t, []uint{coSurvey.ID}, readable(ta))
+		assert.Empty(t, manageable(ta))
+
+		ids, err := ManageableSurveyIDs(db, ta)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		var subjects []Subject
+		StaffSubjects(db, ta).Find(&subjects)
+		assert.Len(t, subjects, 1)
+		assert.Error(t, ManageableSubjects(db, ta, current.ID).First(&Subject{}).Error)
+	})
+
+	t.Run("Outsider", func(t *testing.T) {
+		assert.Empty(t, readable(outsider))
+		assert.Empty(t, manageable(outsider))
+
+		var subjects []Subject
+		StaffSubjects(db, outsider).Find(&subjects)
+		assert.Empty(t, subjects)
+	})
+
+	t.Run("Responses Of Readable Surveys", func(t *testing.T) {
+		student := User{FirstName: "Student", LastName: "Test", Email: "student@example.com", Password: "password123", Role: RoleStudent}
+		db.Create(&student)
+		question := Question{SurveyID: coSurvey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+		db.Create(&Response{SurveyID: coSurvey.ID, StudentID: student.ID, QuestionID: question.ID, Answer: "5"})
+
+		var responses []Response
+		db.Where("survey_id IN (?)", ReadableSurveys(db.Model(&Survey{}), ta).Select("surveys.id")).Find(&responses)
+		assert.Len(t, responses, 1)
+		db.Where("survey_id IN (?)", ReadableSurveys(db.Model(&Survey{}), outsider).Select("surveys.id")).Find(&responses)
+		assert.Empty(t, responses)
+	})
+}
+
+func TestOfferingStaff(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	other := User{FirstName: "Joao", LastName: "Santos", Email: "joao@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&other)
+	ta := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&ta)
+
+	subject := Subject{Name: "Algorithms", Code: "MAC201", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+
+	t.Run("Validate Assignment", func(t *testing.T) {
+		assert.ErrorIs(t, ValidateStaffAssignment(db, StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: ta.ID, Role: "grader"}), ErrInvalidStaffRole)
+		assert.NoError(t, ValidateStaffAssignment(db, StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: other.ID, Role: StaffRoleLead}))
+	})
+
+	t.Run("Subject Professor Is The Default Lead", func(t *testing.T) {
+		db.Create(&StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: ta.ID, Role: StaffRoleTA})
+
+		staff, err := OfferingStaff(db, subject.ID, semester.ID)
+		assert.NoError(t, err)
+		assert.Len(t, staff, 2)
+		assert.Equal(t, professor.ID, staff[0].UserID)
+		assert.Equal(t, StaffRoleLead, staff[0].Role)
+		assert.Equal(t, "Maria", staff[0].User.FirstName)
+		assert.Empty(t, staff[0].User.Password)
+		assert.Equal(t, ta.ID, staff[1].UserID)
+		assert.True(t, IsOfferingStaff(staff, ta.ID))
+		assert.False(t, IsOfferingStaff(staff, other.ID))
+	})
+
+	t.Run("Assigned Lead Replaces The Default", func(t *testing.T) {
+		lead := StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: other.ID, Role: StaffRoleLead}
+		assert.NoError(t, ValidateStaffAssignment(db, lead))
+		db.Create(&lead)
+
+		staff, err := OfferingStaff(db, subject.ID, semester.ID)
+		assert.NoError(t, err)
+		assert.Len(t, staff, 2)
+		assert.Equal(t, other.ID, staff[0].UserID)
+		assert.Equal(t, ta.ID, staff[1].UserID)
+
+		// Only one lead per offering, but the lead can be saved again
+		second := StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: professor.ID, Role: StaffRoleLead}
+		assert.ErrorIs(t, ValidateStaffAssignment(db, second), ErrLeadAlreadyAssigned)
+		assert.NoError(t, ValidateStaffAssignment(db, lead))
+	})
+
+	t.Run("One Assignment Per Offering And User", func(t *testing.T) {
+		err := db.Create(&StaffAssignment{SubjectID: subject.ID, SemesterID: semester.ID, UserID: ta.ID, Role: StaffRoleCoProfessor}).Error
+		assert.True(t, isUniqueViolation(err))
+	})
+
+	t.Run("Staff Questions", func(t *testing.T) {
+		survey := Survey{Title: "Evaluation", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		db.Create(&survey)
+		staff, _ := OfferingStaff(db, subject.ID, semester.ID)
+
+		questions := StaffQuestions(Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Teaching", Required: true}, staff, 4)
+		assert.Len(t, questions, 2)
+		assert.NoError(t, db.Create(&questions).Error)
+		assert.Equal(t, other.ID, questions[0].StaffID)
+		assert.Equal(t, 4, questions[0].Order)
+		assert.Equal(t, ta.ID, questions[1].StaffID)
+		assert.Equal(t, 5, questions[1].Order)
+		assert.NotEqual(t, questions[0].Key, questions[1].Key)
+
+		var stored Question
+		db.Preload("Staff").First(&stored, questions[1].ID)
+		assert.Equal(t, "Teaching (Ana Souza)", stored.Label())
+		analytics := BuildQuestionAnalytics(stored, []string{"4", "5"})
+		assert.Equal(t, ta.ID, analytics.StaffID)
+		assert.Equal(t, "Ana Souza", analytics.StaffName)
+	})
+}
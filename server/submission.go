@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCodeAlreadySubmitted is returned when a student submits a survey twice
+const ErrCodeAlreadySubmitted = "already_submitted"
+
+// ErrNoSubmission is returned when editing the answers of a survey the student never submitted
+var ErrNoSubmission = errors.New("no submission to edit")
+
+// SubmittedAnswer is a single answer within a whole-survey submission
+type SubmittedAnswer struct {
+	QuestionID uint   `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// SubmissionRequest is the body of POST /student/surveys/:id/submission
+type SubmissionRequest struct {
+	Answers []SubmittedAnswer `json:"answers"`
+}
+
+// AnswerError describes why the answer to a question was rejected
+type AnswerError struct {
+	QuestionID uint   `json:"question_id"`
+	Error      string `json:"error"`
+}
+
+// ValidateAnswer checks that a non-empty answer is acceptable for the question type
+func ValidateAnswer(question Question, answer string) error {
+	switch question.Type {
+	case QuestionTypeNPS:
+		return validateScore(answer, NPSMin, NPSMax)
+	case QuestionTypeRating:
+		return validateScore(answer, RatingMin, RatingMax)
+	case QuestionTypeChoice:
+		for _, opt := range question.OptionList() {
+			if answer == opt {
+				return nil
+			}
+		}
+		return errors.New("answer is not one of the question options")
+	}
+	return nil
+}
+
+func validateScore(answer string, min, max int) error {
+	v, err := strconv.Atoi(answer)
+	if err != nil || v < min || v > max {
+		return fmt.Errorf("answer must be an integer from %d to %d", min, max)
+	}
+	return nil
+}
+
+// ValidateSubmission checks a whole-survey submission against the survey questions:
+// every answer must belong to the survey, appear once and match its question type,
+// and every required question must be answered. Blank answers count as unanswered.
+func ValidateSubmission(questions []Question, answers []SubmittedAnswer) []AnswerError {
+	byID := make(map[uint]Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	var errs []AnswerError
+	answered := make(map[uint]bool, len(answers))
+	for _, a := range answers {
+		question, ok := byID[a.QuestionID]
+		if !ok {
+			errs = append(errs, AnswerError{QuestionID: a.QuestionID, Error: "question does not belong to this survey"})
+			continue
+		}
+		if answered[a.QuestionID] {
+			errs = append(errs, AnswerError{QuestionID: a.QuestionID, Error: "question answered more than once"})
+			continue
+		}
+
+		answer := strings.TrimSpace(a.Answer)
+		if answer == "" {
+			continue
+		}
+		answered[a.QuestionID] = true
+		if err := ValidateAnswer(question, answer); err != nil {
+			errs = append(errs, AnswerError{QuestionID: a.QuestionID, Error: err.Error()})
+		}
+	}
+
+	for _, q := range questions {
+		if q.Required && !answered[q.ID] {
+			errs = append(errs, AnswerError{QuestionID: q.ID, Error: "required question not answered"})
+		}
+	}
+	return errs
+}
+
+// SubmitSurvey stores every non-blank answer of a validated submission in a single
+// transaction, so either the whole submission is saved or none of it is.
+// For anonymous surveys the student is only recorded as a participant, while the
+// answers carry random IDs, a random submission token and a day-precision timestamp instead.
+func SubmitSurvey(db *gorm.DB, survey Survey, studentID uint, answers []SubmittedAnswer) ([]Response, error) {
+	var token string
+	var storedAt time.Time
+	if survey.Anonymous {
+		var err error
+		if token, err = newSubmissionToken(); err != nil {
+			return nil, err
+		}
+		storedAt = anonymousTimestamp(time.Now())
+	}
+
+	responses := make([]Response, 0, len(answers))
+	for _, a := range answers {
+		answer := strings.TrimSpace(a.Answer)
+		if answer == "" {
+			continue
+		}
+		response := Response{
+			SurveyID:   survey.ID,
+			StudentID:  studentID,
+			QuestionID: a.QuestionID,
+			Answer:     answer,
+		}
+		if survey.Anonymous {
+			id, err := newAnonymousResponseID()
+			if err != nil {
+				return nil, err
+			}
+			response.ID = id
+			response.StudentID = 0
+			response.SubmissionToken = token
+			response.SubmittedAt = storedAt
+			response.CreatedAt = storedAt
+			response.UpdatedAt = storedAt
+		}
+		responses = append(responses, response)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if survey.Anonymous {
+			if err := tx.Create(&SurveyParticipation{SurveyID: survey.ID, StudentID: studentID}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range responses {
+			if err := tx.Create(&responses[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// ResubmitSurvey replaces a student's previous answers to a survey with a validated
+// submission in a single transaction. Answers to the same question are updated in
+// place, new answers are added and questions left blank lose their previous answer.
+func ResubmitSurvey(db *gorm.DB, survey Survey, studentID uint, answers []SubmittedAnswer) ([]Response, error) {
+	if survey.Anonymous {
+		return nil, ErrAnonymousEdit
+	}
+
+	var responses []Response
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var previous []Response
+		if err := tx.Where("survey_id = ? AND student_id = ?", survey.ID, studentID).Find(&previous).Error; err != nil {
+			return err
+		}
+		if len(previous) == 0 {
+			return ErrNoSubmission
+		}
+		byQuestion := make(map[uint]Response, len(previous))
+		for _, r := range previous {
+			byQuestion[r.QuestionID] = r
+		}
+
+		for _, a := range answers {
+			answer := strings.TrimSpace(a.Answer)
+			if answer == "" {
+				continue
+			}
+			response, ok := byQuestion[a.QuestionID]
+			if ok {
+				delete(byQuestion, a.QuestionID)
+				response.Answer = answer
+				if err := tx.Save(&response).Error; err != nil {
+					return err
+				}
+			} else {
+				response = Response{SurveyID: survey.ID, StudentID: studentID, QuestionID: a.QuestionID, Answer: answer}
+				if err := tx.Create(&response).Error; err != nil {
+					return err
+				}
+			}
+			responses = append(responses, response)
+		}
+
+		// Whatever is left was cleared in this submission, for good so the question can be answered again
+		for _, r := range byQuestion {
+			if err := tx.Unscoped().Delete(&r).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// removeDuplicateResponses keeps only the latest answer of each student to each
+// question, so the unique response index can be created on existing databases.
+// Anonymous answers have no student and are left alone.
+func removeDuplicateResponses(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Response{}) {
+		return nil
+	}
+	return db.Exec("DELETE FROM responses WHERE student_id IS NOT NULL AND id NOT IN (SELECT MAX(id) FROM responses WHERE student_id IS NOT NULL GROUP BY survey_id, student_id, question_id)").Error
+}
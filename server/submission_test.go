@@ -0,0 +1,208 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestValidateAnswer(t *testing.T) {
+	nps := Question{Type: QuestionTypeNPS}
+	rating := Question{Type: QuestionTypeRating}
+	choice := Question{Type: QuestionTypeChoice, Options: `["Java", "Python"]`}
+	freeText := Question{Type: QuestionTypeFreeText}
+
+	tests := []struct {
+		name     string
+		question Question
+		answer   string
+		valid    bool
+	}{
+		{"NPS Lower Bound", nps, "0", true},
+		{"NPS Upper Bound", nps, "10", true},
+		{"NPS Out Of Range", nps, "11", false},
+		{"NPS Not A Number", nps, "banana", false},
+		{"Rating In Range", rating, "3", true},
+		{"Rating Below Range", rating, "0", false},
+		{"Rating Above Range", rating, "6", false},
+		{"Rating Decimal", rating, "4.5", false},
+		{"Choice Known Option", choice, "Python", true},
+		{"Choice Unknown Option", choice, "Rust", false},
+		{"Free Text Anything", freeText, "banana", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAnswer(tt.question, tt.answer)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSubmission(t *testing.T) {
+	questions := []Question{
+		{ID: 1, Type: QuestionTypeNPS, Required: true, Order: 1},
+		{ID: 2, Type: QuestionTypeRating, Required: true, Order: 2},
+		{ID: 3, Type: QuestionTypeFreeText, Required: false, Order: 3},
+	}
+
+	t.Run("Valid Submission", func(t *testing.T) {
+		errs := ValidateSubmission(questions, []SubmittedAnswer{
+			{QuestionID: 1, Answer: "9"},
+			{QuestionID: 2, Answer: " 4 "},
+		})
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Missing Required Question", func(t *testing.T) {
+		errs := ValidateSubmission(questions, []SubmittedAnswer{
+			{QuestionID: 1, Answer: "9"},
+			{QuestionID: 2, Answer: "   "},
+			{QuestionID: 3, Answer: "Nice course"},
+		})
+		assert.Equal(t, []AnswerError{{QuestionID: 2, Error: "required question not answered"}}, errs)
+	})
+
+	t.Run("Invalid Answer Type", func(t *testing.T) {
+		errs := ValidateSubmission(questions, []SubmittedAnswer{
+			{QuestionID: 1, Answer: "banana"},
+			{QuestionID: 2, Answer: "4"},
+		})
+		assert.Len(t, errs, 1)
+		assert.Equal(t, uint(1), errs[0].QuestionID)
+	})
+
+	t.Run("Question From Another Survey", func(t *testing.T) {
+		errs := ValidateSubmission(questions, []SubmittedAnswer{
+			{QuestionID: 1, Answer: "9"},
+			{QuestionID: 2, Answer: "4"},
+			{QuestionID: 99, Answer: "hello"},
+		})
+		assert.Equal(t, []AnswerError{{QuestionID: 99, Error: "question does not belong to this survey"}}, errs)
+	})
+
+	t.Run("Question Answered Twice", func(t *testing.T) {
+		errs := ValidateSubmission(questions, []SubmittedAnswer{
+			{QuestionID: 1, Answer: "9"},
+			{QuestionID: 1, Answer: "3"},
+			{QuestionID: 2, Answer: "4"},
+		})
+		assert.Equal(t, []AnswerError{{QuestionID: 1, Error: "question answered more than once"}}, errs)
+	})
+}
+
+func TestSubmitSurvey(t *testing.T) {
+	db := setupTestDB()
+
+	student := User{FirstName: "Student", LastName: "Test", Email: "student@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	survey := Survey{Title: "Test Survey", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&survey)
+	q1 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true, Order: 1}
+	db.Create(&q1)
+	q2 := Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 2}
+	db.Create(&q2)
+
+	responses, err := SubmitSurvey(db, survey, student.ID, []SubmittedAnswer{
+		{QuestionID: q1.ID, Answer: " 8 "},
+		{QuestionID: q2.ID, Answer: ""},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.NotZero(t, responses[0].ID)
+	assert.Equal(t, "8", responses[0].Answer)
+	assert.Equal(t, student.ID, responses[0].StudentID)
+
+	var count int64
+	db.Model(&Response{}).Where("survey_id = ? AND student_id = ?", survey.ID, student.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestResubmitSurvey(t *testing.T) {
+	db := setupTestDB()
+
+	student := User{FirstName: "Student", LastName: "Test", Email: "student@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0), IsActive: true}
+	db.Create(&semester)
+	survey := Survey{Title: "Test Survey", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true}
+	db.Create(&survey)
+	q1 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Required: true, Order: 1}
+	db.Create(&q1)
+	q2 := Question{SurveyID: survey.ID, Type: QuestionTypeFreeText, Text: "Comments", Order: 2}
+	db.Create(&q2)
+	q3 := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Rate", Order: 3}
+	db.Create(&q3)
+
+	t.Run("No Previous Submission", func(t *testing.T) {
+		_, err := ResubmitSurvey(db, survey, student.ID, []SubmittedAnswer{{QuestionID: q1.ID, Answer: "5"}})
+		assert.ErrorIs(t, err, ErrNoSubmission)
+	})
+
+	original, err := SubmitSurvey(db, survey, student.ID, []SubmittedAnswer{
+		{QuestionID: q1.ID, Answer: "5"},
+		{QuestionID: q2.ID, Answer: "Too fast"},
+	})
+	assert.NoError(t, err)
+
+	t.Run("Duplicate Answers Rejected By Database", func(t *testing.T) {
+		_, err := SubmitSurvey(db, survey, student.ID, []SubmittedAnswer{{QuestionID: q1.ID, Answer: "6"}})
+		assert.Error(t, err)
+		assert.True(t, isUniqueViolation(err))
+	})
+
+	t.Run("Answers Are Updated Added And Cleared", func(t *testing.T) {
+		responses, err := ResubmitSurvey(db, survey, student.ID, []SubmittedAnswer{
+			{QuestionID: q1.ID, Answer: "9"},
+			{QuestionID: q2.ID, Answer: ""},
+			{QuestionID: q3.ID, Answer: "4"},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, responses, 2)
+		assert.Equal(t, original[0].ID, responses[0].ID)
+
+		var stored []Response
+		db.Where("survey_id = ? AND student_id = ?", survey.ID, student.ID).Order("question_id").Find(&stored)
+		assert.Len(t, stored, 2)
+		assert.Equal(t, q1.ID, stored[0].QuestionID)
+		assert.Equal(t, "9", stored[0].Answer)
+		assert.Equal(t, q3.ID, stored[1].QuestionID)
+		assert.Equal(t, "4", stored[1].Answer)
+	})
+}
+
+func TestRemoveDuplicateResponses(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	// Table created before the unique index existed
+	assert.NoError(t, db.Exec("CREATE TABLE responses (id integer PRIMARY KEY, survey_id integer, student_id integer, question_id integer, answer text)").Error)
+	db.Exec("INSERT INTO responses (id, survey_id, student_id, question_id, answer) VALUES (1, 1, 1, 1, '3'), (2, 1, 1, 1, '8'), (3, 1, 2, 1, '7')")
+
+	assert.NoError(t, removeDuplicateResponses(db))
+
+	var answers []string
+	db.Raw("SELECT answer FROM responses ORDER BY id").Scan(&answers)
+	assert.Equal(t, []string{"8", "7"}, answers)
+
+	// Nothing to do on a fresh database
+	fresh, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, removeDuplicateResponses(fresh))
+}
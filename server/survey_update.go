@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSurveyTitleRequired is returned when a survey update clears the title
+	ErrSurveyTitleRequired = errors.New("Title is required")
+	// ErrAnonymityLocked is returned when turning anonymity on or off after the survey got responses
+	ErrAnonymityLocked = errors.New("Anonymity cannot change once the survey has responses")
+	// ErrEmbargoLocked is returned when changing the results embargo after the survey got responses
+	ErrEmbargoLocked = errors.New("The results embargo cannot change once the survey has responses")
+	// ErrCampaignSurveySettings is returned when changing the window or anonymity of a campaign survey
+	ErrCampaignSurveySettings = errors.New("The dates and anonymity of campaign surveys are set by the campaign")
+	// ErrCampaignSurveyDelete is returned when a professor deletes a survey generated by a campaign
+	ErrCampaignSurveyDelete = errors.New("Campaign surveys cannot be deleted")
+	// ErrSurveyHasResponses is returned when deleting a survey that was answered
+	ErrSurveyHasResponses = errors.New("Surveys with responses cannot be deleted, archive them instead")
+)
+
+// SurveyChanges are the survey settings a professor can update. Fields left out of the
+// request are nil and keep their value.
+type SurveyChanges struct {
+	Title          *string    `json:"title"`
+	Description    *string    `json:"description"`
+	IsActive       *bool      `json:"is_active"`
+	Anonymous      *bool      `json:"anonymous"`
+	ResultsEmbargo *string    `json:"results_embargo"`
+	OpenDate       *time.Time `json:"open_date"`
+	CloseDate      *time.Time `json:"close_date"`
+}
+
+// Apply copies the changes onto a survey and validates the result
+func (changes SurveyChanges) Apply(survey *Survey) error {
+	if changes.Title != nil {
+		survey.Title = *changes.Title
+	}
+	if changes.Description != nil {
+		survey.Description = *changes.Description
+	}
+	if changes.IsActive != nil {
+		survey.IsActive = *changes.IsActive
+	}
+	if changes.Anonymous != nil {
+		survey.Anonymous = *changes.Anonymous
+	}
+	if changes.ResultsEmbargo != nil {
+		survey.ResultsEmbargo = *changes.ResultsEmbargo
+	}
+	if changes.OpenDate != nil {
+		survey.OpenDate = *changes.OpenDate
+	}
+	if changes.CloseDate != nil {
+		survey.CloseDate = *changes.CloseDate
+	}
+
+	if survey.Title == "" {
+		return ErrSurveyTitleRequired
+	}
+	if err := ValidateSurveyWindow(*survey); err != nil {
+		return err
+	}
+	return ValidateEmbargoPolicy(survey.ResultsEmbargo)
+}
+
+// CheckSurveyChange returns an error when an update touches what the survey's campaign
+// decides, or switches anonymity or the results embargo after students answered
+func CheckSurveyChange(db *gorm.DB, original, updated Survey) error {
+	anonymityChanged := original.Anonymous != updated.Anonymous
+	embargoChanged := original.ResultsEmbargo != updated.ResultsEmbargo
+	if original.CampaignID != 0 && (anonymityChanged || !original.OpenDate.Equal(updated.OpenDate) || !original.CloseDate.Equal(updated.CloseDate)) {
+		return ErrCampaignSurveySettings
+	}
+	if !anonymityChanged && !embargoChanged {
+		return nil
+	}
+	answered, err := SurveyHasResponses(db, original.ID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case answered && anonymityChanged:
+		return ErrAnonymityLocked
+	case answered && embargoChanged:
+		return ErrEmbargoLocked
+	}
+	return nil
+}
+
+// SaveSurveyChanges stores the settings covered by SurveyChanges
+func SaveSurveyChanges(db *gorm.DB, survey *Survey) error {
+	return db.Model(survey).
+		Select("title", "description", "is_active", "anonymous", "results_embargo", "open_date", "close_date").
+		Updates(survey).Error
+}
+
+// DeleteSurvey permanently deletes a survey nobody answered, with its questions. Surveys
+// with responses are archived instead, and campaign surveys belong to the campaign.
+func DeleteSurvey(db *gorm.DB, survey Survey) error {
+	if survey.CampaignID != 0 {
+		return ErrCampaignSurveyDelete
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		answered, err := SurveyHasResponses(tx, survey.ID)
+		if err != nil {
+			return err
+		}
+		if answered {
+			return ErrSurveyHasResponses
+		}
+		if err := tx.Unscoped().Where("survey_id = ?", survey.ID).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&survey).Error
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyUpdate(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Maria", LastName: "Silva", Email: "maria@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	student := User{FirstName: "Ana", LastName: "Souza", Email: "ana@example.com", Password: "password123", Role: RoleStudent}
+	db.Create(&student)
+	subject := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+
+	openDate := time.Now().Truncate(time.Second)
+	closeDate := openDate.AddDate(0, 0, 14)
+	newSurvey := func(title string) Survey {
+		survey := Survey{Title: title, SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true, OpenDate: openDate, CloseDate: closeDate}
+		db.Create(&survey)
+		return survey
+	}
+	stringPtr := func(s string) *string { return &s }
+	boolPtr := func(b bool) *bool { return &b }
+
+	t.Run("Apply Changes", func(t *testing.T) {
+		survey := newSurvey("Feedback")
+		later := closeDate.AddDate(0, 0, 7)
+		changes := SurveyChanges{Title: stringPtr("Final feedback"), IsActive: boolPtr(false), CloseDate: &later}
+		assert.NoError(t, changes.Apply(&survey))
+		assert.NoError(t, SaveSurveyChanges(db, &survey))
+
+		var loaded Survey
+		db.First(&loaded, survey.ID)
+		assert.Equal(t, "Final feedback", loaded.Title)
+		assert.False(t, loaded.IsActive)
+		assert.True(t, later.Equal(loaded.CloseDate))
+		// Fields left out keep their value
+		assert.True(t, openDate.Equal(loaded.OpenDate))
+	})
+
+	t.Run("Invalid Changes", func(t *testing.T) {
+		survey := newSurvey("Invalid")
+		assert.ErrorIs(t, SurveyChanges{Title: stringPtr("")}.Apply(&survey), ErrSurveyTitleRequired)
+
+		survey = newSurvey("Invalid")
+		earlier := openDate.AddDate(0, 0, -1)
+		assert.Error(t, SurveyChanges{CloseDate: &earlier}.Apply(&survey))
+
+		survey = newSurvey("Invalid")
+		assert.Error(t, SurveyChanges{ResultsEmbargo: stringPtr("forever")}.Apply(&survey))
+	})
+
+	t.Run("Anonymity Locks After Responses", func(t *testing.T) {
+		survey := newSurvey("Anonymity")
+		question := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+
+		updated := survey
+		assert.NoError(t, SurveyChanges{Anonymous: boolPtr(true)}.Apply(&updated))
+		assert.NoError(t, CheckSurveyChange(db, survey, updated))
+
+		db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: question.ID, Answer: "4"})
+		assert.ErrorIs(t, CheckSurveyChange(db, survey, updated), ErrAnonymityLocked)
+
+		// Other settings can still change
+		retitled := survey
+		assert.NoError(t, SurveyChanges{Title: stringPtr("Renamed")}.Apply(&retitled))
+		assert.NoError(t, CheckSurveyChange(db, survey, retitled))
+	})
+
+	t.Run("Embargo Locks After Responses", func(t *testing.T) {
+		survey := newSurvey("Embargo")
+		survey.ResultsEmbargo = EmbargoUntilClose
+		db.Save(&survey)
+		question := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+
+		updated := survey
+		assert.NoError(t, SurveyChanges{ResultsEmbargo: stringPtr(EmbargoNone)}.Apply(&updated))
+		assert.NoError(t, CheckSurveyChange(db, survey, updated))
+
+		db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: question.ID, Answer: "4"})
+		assert.ErrorIs(t, CheckSurveyChange(db, survey, updated), ErrEmbargoLocked)
+
+		// Sending the current policy again is not a change
+		same := survey
+		assert.NoError(t, SurveyChanges{ResultsEmbargo: stringPtr(EmbargoUntilClose)}.Apply(&same))
+		assert.NoError(t, CheckSurveyChange(db, survey, same))
+	})
+
+	t.Run("Campaign Surveys Keep The Campaign Window", func(t *testing.T) {
+		survey := newSurvey("Campaign")
+		survey.CampaignID = 1
+
+		moved := survey
+		later := closeDate.AddDate(0, 0, 7)
+		assert.NoError(t, SurveyChanges{CloseDate: &later}.Apply(&moved))
+		assert.ErrorIs(t, CheckSurveyChange(db, survey, moved), ErrCampaignSurveySettings)
+
+		retitled := survey
+		assert.NoError(t, SurveyChanges{Title: stringPtr("Renamed")}.Apply(&retitled))
+		assert.NoError(t, CheckSurveyChange(db, survey, retitled))
+
+		assert.ErrorIs(t, DeleteSurvey(db, survey), ErrCampaignSurveyDelete)
+	})
+
+	t.Run("Delete An Unused Survey", func(t *testing.T) {
+		survey := newSurvey("Unused")
+		db.Create(&Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1})
+		assert.NoError(t, DeleteSurvey(db, survey))
+
+		var surveys, questions int64
+		db.Unscoped().Model(&Survey{}).Where("id = ?", survey.ID).Count(&surveys)
+		db.Unscoped().Model(&Question{}).Where("survey_id = ?", survey.ID).Count(&questions)
+		assert.Zero(t, surveys+questions)
+	})
+
+	t.Run("Answered Surveys Are Not Deleted", func(t *testing.T) {
+		survey := newSurvey("Answered")
+		question := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 1}
+		db.Create(&question)
+		db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: question.ID, Answer: "4"})
+		assert.ErrorIs(t, DeleteSurvey(db, survey), ErrSurveyHasResponses)
+
+		anonymous := newSurvey("Anonymous")
+		db.Create(&SurveyParticipation{SurveyID: anonymous.ID, StudentID: student.ID})
+		assert.ErrorIs(t, DeleteSurvey(db, anonymous), ErrSurveyHasResponses)
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Survey window statuses, computed from OpenDate, CloseDate and IsActive
+const (
+	SurveyWindowScheduled = "scheduled"
+	SurveyWindowOpen      = "open"
+	SurveyWindowClosed    = "closed"
+)
+
+// Error codes returned when a submission falls outside the survey window
+const (
+	ErrCodeSurveyInactive = "survey_inactive"
+	ErrCodeSurveyNotOpen  = "survey_not_open"
+	ErrCodeSurveyClosed   = "survey_closed"
+)
+
+// SurveyWindowError is returned when a survey cannot receive submissions.
+// Code is stable and meant for clients, Message is human readable.
+type SurveyWindowError struct {
+	Code    string
+	Message string
+}
+
+func (e *SurveyWindowError) Error() string {
+	return e.Message
+}
+
+// WindowStatusAt computes whether the survey is scheduled, open or closed at the given time.
+// An inactive survey is always closed.
+func (s *Survey) WindowStatusAt(now time.Time) string {
+	switch {
+	case !s.IsActive:
+		return SurveyWindowClosed
+	case now.Before(s.OpenDate):
+		return SurveyWindowScheduled
+	case now.After(s.CloseDate):
+		return SurveyWindowClosed
+	default:
+		return SurveyWindowOpen
+	}
+}
+
+// CheckSubmissionWindow returns an error when the survey does not accept
+// submissions at the given time, nil otherwise
+func CheckSubmissionWindow(survey Survey, now time.Time) *SurveyWindowError {
+	if !survey.IsActive {
+		return &SurveyWindowError{Code: ErrCodeSurveyInactive, Message: "Survey is not active"}
+	}
+	switch survey.WindowStatusAt(now) {
+	case SurveyWindowScheduled:
+		return &SurveyWindowError{Code: ErrCodeSurveyNotOpen, Message: "Survey is not open yet"}
+	case SurveyWindowClosed:
+		return &SurveyWindowError{Code: ErrCodeSurveyClosed, Message: "Survey is closed"}
+	}
+	return nil
+}
+
+// ValidateSurveyWindow checks the open/close dates of a survey being created or updated
+func ValidateSurveyWindow(survey Survey) error {
+	if survey.OpenDate.IsZero() || survey.CloseDate.IsZero() {
+		return errors.New("Open and close dates are required")
+	}
+	if !survey.CloseDate.After(survey.OpenDate) {
+		return errors.New("Close date must be after open date")
+	}
+	return nil
+}
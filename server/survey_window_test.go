@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyWindowStatus(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	survey := Survey{
+		IsActive:  true,
+		OpenDate:  now.AddDate(0, 0, -1),
+		CloseDate: now.AddDate(0, 0, 1),
+	}
+
+	t.Run("Open Within Window", func(t *testing.T) {
+		assert.Equal(t, SurveyWindowOpen, survey.WindowStatusAt(now))
+		assert.Nil(t, CheckSubmissionWindow(survey, now))
+	})
+
+	t.Run("Open On Boundaries", func(t *testing.T) {
+		assert.Equal(t, SurveyWindowOpen, survey.WindowStatusAt(survey.OpenDate))
+		assert.Equal(t, SurveyWindowOpen, survey.WindowStatusAt(survey.CloseDate))
+	})
+
+	t.Run("Scheduled Before Open Date", func(t *testing.T) {
+		before := now.AddDate(0, 0, -2)
+		assert.Equal(t, SurveyWindowScheduled, survey.WindowStatusAt(before))
+
+		err := CheckSubmissionWindow(survey, before)
+		assert.NotNil(t, err)
+		assert.Equal(t, ErrCodeSurveyNotOpen, err.Code)
+	})
+
+	t.Run("Closed After Close Date", func(t *testing.T) {
+		after := now.AddDate(0, 0, 2)
+		assert.Equal(t, SurveyWindowClosed, survey.WindowStatusAt(after))
+
+		err := CheckSubmissionWindow(survey, after)
+		assert.NotNil(t, err)
+		assert.Equal(t, ErrCodeSurveyClosed, err.Code)
+	})
+
+	t.Run("Inactive Survey", func(t *testing.T) {
+		inactive := survey
+		inactive.IsActive = false
+		assert.Equal(t, SurveyWindowClosed, inactive.WindowStatusAt(now))
+
+		err := CheckSubmissionWindow(inactive, now)
+		assert.NotNil(t, err)
+		assert.Equal(t, ErrCodeSurveyInactive, err.Code)
+		assert.Equal(t, "Survey is not active", err.Error())
+	})
+}
+
+func TestValidateSurveyWindow(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, ValidateSurveyWindow(Survey{OpenDate: now, CloseDate: now.Add(time.Hour)}))
+	assert.EqualError(t, ValidateSurveyWindow(Survey{OpenDate: now, CloseDate: now}), "Close date must be after open date")
+	assert.EqualError(t, ValidateSurveyWindow(Survey{OpenDate: now, CloseDate: now.Add(-time.Hour)}), "Close date must be after open date")
+	assert.EqualError(t, ValidateSurveyWindow(Survey{OpenDate: now}), "Open and close dates are required")
+}
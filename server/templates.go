@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// isQuestionType reports whether t is one of the supported question types
+func isQuestionType(t string) bool {
+	switch t {
+	case QuestionTypeNPS, QuestionTypeFreeText, QuestionTypeRating, QuestionTypeChoice:
+		return true
+	}
+	return false
+}
+
+// ValidateTemplate checks a template and its questions before it is stored
+func ValidateTemplate(template SurveyTemplate) error {
+	if strings.TrimSpace(template.Name) == "" {
+		return errors.New("Template name is required")
+	}
+	for i, q := range template.Questions {
+		if strings.TrimSpace(q.Text) == "" {
+			return fmt.Errorf("Question %d: text is required", i+1)
+		}
+		if !isQuestionType(q.Type) {
+			return fmt.Errorf("Question %d: invalid question type %q", i+1, q.Type)
+		}
+		if q.Type == QuestionTypeChoice && len((&Question{Options: q.Options}).OptionList()) == 0 {
+			return fmt.Errorf("Question %d: multiple choice questions need options", i+1)
+		}
+	}
+	return nil
+}
+
+// normalizeTemplateQuestions clears client-sent IDs and numbers unordered questions by position
+func normalizeTemplateQuestions(questions []TemplateQuestion) []TemplateQuestion {
+	normalized := make([]TemplateQuestion, len(questions))
+	for i, q := range questions {
+		q.ID = 0
+		q.TemplateID = 0
+		if q.Order <= 0 {
+			q.Order = i + 1
+		}
+		normalized[i] = q
+	}
+	return normalized
+}
+
+// VisibleTemplates scopes a query to the templates the user can use: admins see
+// every template, professors their own plus the shared ones
+func VisibleTemplates(db *gorm.DB, user User) *gorm.DB {
+	if user.Role == RoleAdmin {
+		return db
+	}
+	return db.Where("owner_id = ? OR shared = ?", user.ID, true)
+}
+
+// CanEditTemplate reports whether the user may change or delete the template
+func CanEditTemplate(user User, template SurveyTemplate) bool {
+	return user.Role == RoleAdmin || template.OwnerID == user.ID
+}
+
+// CreateTemplate stores a new template with its questions. Templates created by
+// admins are shared with every professor.
+func CreateTemplate(db *gorm.DB, owner User, template *SurveyTemplate) error {
+	template.ID = 0
+	template.OwnerID = owner.ID
+	template.Shared = owner.Role == RoleAdmin
+	template.Questions = normalizeTemplateQuestions(template.Questions)
+	return db.Create(template).Error
+}
+
+// UpdateTemplate replaces the name, description and questions of a template. Questions
+// sent back with their ID keep their key, so trend reports still match them.
+func UpdateTemplate(db *gorm.DB, template *SurveyTemplate, changes SurveyTemplate) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []TemplateQuestion
+		if err := tx.Where("template_id = ?", template.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		keys := make(map[uint]string, len(existing))
+		for _, q := range existing {
+			keys[q.ID] = q.Key
+		}
+		for i, q := range changes.Questions {
+			if q.Key == "" {
+				changes.Questions[i].Key = keys[q.ID]
+			}
+		}
+
+		if err := tx.Where("template_id = ?", template.ID).Delete(&TemplateQuestion{}).Error; err != nil {
+			return err
+		}
+
+		template.Name = changes.Name
+		template.Description = changes.Description
+		template.Questions = normalizeTemplateQuestions(changes.Questions)
+		for i := range template.Questions {
+			template.Questions[i].TemplateID = template.ID
+		}
+		if err := tx.Omit("Questions").Save(template).Error; err != nil {
+			return err
+		}
+		if len(template.Questions) == 0 {
+			return nil
+		}
+		return tx.Create(&template.Questions).Error
+	})
+}
+
+// ErrTemplateInUse is returned when deleting a template a campaign was generated from
+var ErrTemplateInUse = errors.New("Templates used by a campaign cannot be deleted")
+
+// DeleteTemplate removes a template and its questions. Surveys created from it are kept,
+// but templates referenced by a campaign are refused.
+func DeleteTemplate(db *gorm.DB, template SurveyTemplate) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var campaigns int64
+		if err := tx.Model(&Campaign{}).Where("template_id = ?", template.ID).Count(&campaigns).Error; err != nil {
+			return err
+		}
+		if campaigns > 0 {
+			return ErrTemplateInUse
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&TemplateQuestion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+}
+
+// SurveyInstanceRequest describes a survey created from a template or cloned from
+// another survey. Empty fields keep the value of the template or source survey. The
+// results embargo can't be set here: clones keep the source's and template surveys
+// follow RESULTS_EMBARGO until changed with PUT /professor/surveys/:id.
+type SurveyInstanceRequest struct {
+	TemplateID  uint      `json:"template_id"`
+	SubjectID   uint      `json:"subject_id" binding:"required"`
+	SemesterID  uint      `json:"semester_id" binding:"required"`
+	SectionID   uint      `json:"section_id"` // 0 targets every section
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	OpenDate    time.Time `json:"open_date"`
+	CloseDate   time.Time `json:"close_date"`
+	IsActive    *bool     `json:"is_active"`
+	Anonymous   *bool     `json:"anonymous"`
+}
+
+// Apply returns the survey described by the request, starting from base. New surveys
+// start as drafts whatever the status of base.
+func (req SurveyInstanceRequest) Apply(base Survey) Survey {
+	survey := Survey{
+		Status:         SurveyStatusDraft,
+		Title:          base.Title,
+		Description:    base.Description,
+		SubjectID:      req.SubjectID,
+		SemesterID:     req.SemesterID,
+		SectionID:      req.SectionID,
+		IsActive:       base.IsActive,
+		Anonymous:      base.Anonymous,
+		ResultsEmbargo: base.ResultsEmbargo,
+		OpenDate:       req.OpenDate,
+		CloseDate:      req.CloseDate,
+	}
+	if req.Title != "" {
+		survey.Title = req.Title
+	}
+	if req.Description != "" {
+		survey.Description = req.Description
+	}
+	if req.IsActive != nil {
+		survey.IsActive = *req.IsActive
+	}
+	if req.Anonymous != nil {
+		survey.Anonymous = *req.Anonymous
+	}
+	return survey
+}
+
+// TemplateSurvey is the survey a template starts from before the request is applied
+func TemplateSurvey(template SurveyTemplate) Survey {
+	return Survey{Title: template.Name, Description: template.Description, IsActive: true}
+}
+
+// QuestionsFromTemplate copies the questions of a template into new survey questions
+func QuestionsFromTemplate(template SurveyTemplate) []Question {
+	questions := make([]Question, len(template.Questions))
+	for i, q := range template.Questions {
+		questions[i] = Question{Type: q.Type, Text: q.Text, Required: q.Required, Order: q.Order, Options: q.Options, Key: q.Key}
+	}
+	return questions
+}
+
+// CloneQuestions copies survey questions so they can be attached to another survey.
+// Copies keep the key of the original question and the staff member it evaluates.
+func CloneQuestions(source []Question) []Question {
+	questions := make([]Question, len(source))
+	for i, q := range source {
+		questions[i] = Question{Type: q.Type, Text: q.Text, Required: q.Required, Order: q.Order, Options: q.Options, Key: q.Key, StaffID: q.StaffID}
+	}
+	return questions
+}
+
+// CreateSurveyWithQuestions stores a survey and its questions in one transaction
+func CreateSurveyWithQuestions(db *gorm.DB, survey *Survey, questions []Question) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		survey.Questions = nil
+		// GORM skips zero values on insert, so the is_active column default would win
+		isActive := survey.IsActive
+		if err := tx.Create(survey).Error; err != nil {
+			return err
+		}
+		if !isActive {
+			if err := tx.Model(survey).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		for i := range questions {
+			questions[i].SurveyID = survey.ID
+		}
+		if len(questions) > 0 {
+			if err := tx.Create(&questions).Error; err != nil {
+				return err
+			}
+		}
+		survey.Questions = questions
+		return nil
+	})
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	valid := SurveyTemplate{
+		Name: "End of term",
+		Questions: []TemplateQuestion{
+			{Type: QuestionTypeNPS, Text: "Recommend?"},
+			{Type: QuestionTypeChoice, Text: "Pace", Options: `["Slow","Fine","Fast"]`},
+		},
+	}
+	assert.NoError(t, ValidateTemplate(valid))
+
+	t.Run("Name Required", func(t *testing.T) {
+		template := valid
+		template.Name = "  "
+		assert.Error(t, ValidateTemplate(template))
+	})
+
+	t.Run("Invalid Questions", func(t *testing.T) {
+		for _, q := range []TemplateQuestion{
+			{Type: QuestionTypeRating, Text: ""},
+			{Type: "essay", Text: "Essay"},
+			{Type: QuestionTypeChoice, Text: "No options"},
+		} {
+			template := SurveyTemplate{Name: "Broken", Questions: []TemplateQuestion{q}}
+			assert.Error(t, ValidateTemplate(template), q.Text)
+		}
+	})
+}
+
+func TestSurveyTemplates(t *testing.T) {
+	db := setupTestDB()
+
+	admin := User{FirstName: "Admin", LastName: "Test", Email: "admin@example.com", Password: "password123", Role: RoleAdmin}
+	db.Create(&admin)
+	professor := User{FirstName: "Prof", LastName: "One", Email: "prof1@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	colleague := User{FirstName: "Prof", LastName: "Two", Email: "prof2@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&colleague)
+
+	shared := SurveyTemplate{Name: "Institutional", Questions: []TemplateQuestion{
+		{Type: QuestionTypeNPS, Text: "Recommend?", Required: true},
+		{Type: QuestionTypeFreeText, Text: "Comments"},
+	}}
+	assert.NoError(t, CreateTemplate(db, admin, &shared))
+	private := SurveyTemplate{Name: "Lab feedback", Questions: []TemplateQuestion{{Type: QuestionTypeRating, Text: "Lab quality", Order: 1}}}
+	assert.NoError(t, CreateTemplate(db, professor, &private))
+
+	t.Run("Create", func(t *testing.T) {
+		assert.True(t, shared.Shared)
+		assert.False(t, private.Shared)
+		assert.Equal(t, professor.ID, private.OwnerID)
+
+		var questions []TemplateQuestion
+		db.Where("template_id = ?", shared.ID).Order("\"order\" ASC").Find(&questions)
+		assert.Len(t, questions, 2)
+		assert.Equal(t, 1, questions[0].Order)
+		assert.Equal(t, 2, questions[1].Order)
+	})
+
+	t.Run("Visibility", func(t *testing.T) {
+		var templates []SurveyTemplate
+		VisibleTemplates(db, admin).Find(&templates)
+		assert.Len(t, templates, 2)
+
+		templates = nil
+		VisibleTemplates(db, professor).Find(&templates)
+		assert.Len(t, templates, 2)
+
+		templates = nil
+		VisibleTemplates(db, colleague).Find(&templates)
+		assert.Len(t, templates, 1)
+		assert.Equal(t, shared.ID, templates[0].ID)
+	})
+
+	t.Run("Edit Permissions", func(t *testing.T) {
+		assert.True(t, CanEditTemplate(admin, private))
+		assert.True(t, CanEditTemplate(professor, private))
+		assert.False(t, CanEditTemplate(colleague, private))
+		assert.False(t, CanEditTemplate(professor, shared))
+	})
+
+	t.Run("Update Replaces Questions", func(t *testing.T) {
+		changes := SurveyTemplate{Name: "Lab feedback v2", Questions: []TemplateQuestion{
+			{ID: 999, Type: QuestionTypeNPS, Text: "Recommend the lab?"},
+			{Type: QuestionTypeFreeText, Text: "What to improve?"},
+		}}
+		assert.NoError(t, UpdateTemplate(db, &private, changes))
+
+		var stored SurveyTemplate
+		db.Preload("Questions").First(&stored, private.ID)
+		assert.Equal(t, "Lab feedback v2", stored.Name)
+		assert.Equal(t, professor.ID, stored.OwnerID)
+		assert.Len(t, stored.Questions, 2)
+		for _, q := range stored.Questions {
+			assert.NotEqual(t, uint(999), q.ID)
+		}
+	})
+
+	t.Run("Templates Used By A Campaign Are Kept", func(t *testing.T) {
+		semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+		db.Create(&semester)
+		db.Create(&Campaign{Name: "End of term", SemesterID: semester.ID, TemplateID: shared.ID, CreatedByID: admin.ID})
+
+		assert.ErrorIs(t, DeleteTemplate(db, shared), ErrTemplateInUse)
+		var count int64
+		db.Model(&TemplateQuestion{}).Where("template_id = ?", shared.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, DeleteTemplate(db, private))
+
+		var count int64
+		db.Model(&SurveyTemplate{}).Where("id = ?", private.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+		db.Model(&TemplateQuestion{}).Where("template_id = ?", private.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestSurveyInstances(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	fall := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&fall)
+	spring := Semester{Name: "2024.2", Year: 2024, Period: 2, StartDate: time.Now().AddDate(0, 6, 0), EndDate: time.Now().AddDate(0, 10, 0)}
+	db.Create(&spring)
+
+	openDate := time.Now().AddDate(0, 6, 0)
+	closeDate := openDate.AddDate(0, 0, 14)
+
+	t.Run("From Template", func(t *testing.T) {
+		template := SurveyTemplate{Name: "End of term", Description: "Standard questionnaire", Questions: []TemplateQuestion{
+			{Type: QuestionTypeNPS, Text: "Recommend?", Required: true, Order: 1},
+			{Type: QuestionTypeChoice, Text: "Pace", Options: `["Slow","Fine","Fast"]`, Order: 2},
+		}}
+		anonymous := true
+		req := SurveyInstanceRequest{SubjectID: subject.ID, SemesterID: fall.ID, OpenDate: openDate, CloseDate: closeDate, Anonymous: &anonymous}
+
+		survey := req.Apply(TemplateSurvey(template))
+		assert.Equal(t, SurveyStatusDraft, survey.Status)
+		survey.ProfessorID = professor.ID
+		assert.NoError(t, CreateSurveyWithQuestions(db, &survey, QuestionsFromTemplate(template)))
+
+		var stored Survey
+		db.Preload("Questions").First(&stored, survey.ID)
+		assert.Equal(t, "End of term", stored.Title)
+		assert.Equal(t, "Standard questionnaire", stored.Description)
+		assert.True(t, stored.IsActive)
+		assert.True(t, stored.Anonymous)
+		assert.Len(t, stored.Questions, 2)
+		assert.Equal(t, `["Slow","Fine","Fast"]`, stored.Questions[1].Options)
+		assert.True(t, stored.Questions[0].Required)
+	})
+
+	t.Run("Clone Into New Semester", func(t *testing.T) {
+		source := Survey{Title: "Midterm", Description: "Checkpoint", SubjectID: subject.ID, SemesterID: fall.ID, ProfessorID: professor.ID, IsActive: false, ResultsEmbargo: EmbargoUntilClose, Status: SurveyStatusClosed}
+		questions := []Question{{Type: QuestionTypeRating, Text: "Clarity", Order: 1}, {Type: QuestionTypeFreeText, Text: "Comments", Order: 2}}
+		assert.NoError(t, CreateSurveyWithQuestions(db, &source, questions))
+
+		req := SurveyInstanceRequest{SubjectID: subject.ID, SemesterID: spring.ID, Title: "Midterm 2024.2", OpenDate: openDate, CloseDate: closeDate}
+		clone := req.Apply(source)
+		assert.Equal(t, SurveyStatusDraft, clone.Status)
+		clone.ProfessorID = professor.ID
+		assert.NoError(t, CreateSurveyWithQuestions(db, &clone, CloneQuestions(source.Questions)))
+
+		var stored Survey
+		db.Preload("Questions").First(&stored, clone.ID)
+		assert.NotEqual(t, source.ID, stored.ID)
+		assert.Equal(t, "Midterm 2024.2", stored.Title)
+		assert.Equal(t, "Checkpoint", stored.Description)
+		assert.Equal(t, spring.ID, stored.SemesterID)
+		assert.False(t, stored.IsActive)
+		assert.Equal(t, SurveyStatusDraft, stored.Status)
+		assert.Equal(t, EmbargoUntilClose, stored.ResultsEmbargo)
+		assert.Len(t, stored.Questions, 2)
+
+		// The source keeps its own questions
+		var sourceCount int64
+		db.Model(&Question{}).Where("survey_id = ?", source.ID).Count(&sourceCount)
+		assert.Equal(t, int64(2), sourceCount)
+	})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newQuestionKey returns a random key identifying a question across templates and clones
+func newQuestionKey() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// legacyQuestionKey derives a key for questions created before keys existed, so that
+// questions with the same type and text keep matching across semesters
+func legacyQuestionKey(questionType, text string) string {
+	sum := sha1.Sum([]byte(questionType + "|" + strings.ToLower(strings.TrimSpace(text))))
+	return "legacy-" + hex.EncodeToString(sum[:8])
+}
+
+// BeforeCreate gives new questions a key unless they were copied from a template or survey
+func (q *Question) BeforeCreate(tx *gorm.DB) error {
+	var err error
+	if q.Key == "" {
+		q.Key, err = newQuestionKey()
+	}
+	return err
+}
+
+// BeforeCreate gives new template questions a key, which surveys created from the template inherit
+func (q *TemplateQuestion) BeforeCreate(tx *gorm.DB) error {
+	var err error
+	if q.Key == "" {
+		q.Key, err = newQuestionKey()
+	}
+	return err
+}
+
+// backfillQuestionKeys sets the legacy key on questions stored before keys were introduced
+func backfillQuestionKeys(db *gorm.DB) error {
+	var questions []Question
+	if err := db.Where("key = ? OR key IS NULL", "").Find(&questions).Error; err != nil {
+		return err
+	}
+	for _, q := range questions {
+		if err := db.Model(&Question{}).Where("id = ?", q.ID).Update("key", legacyQuestionKey(q.Type, q.Text)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrendPoint aggregates the answers to one question in one semester
+type TrendPoint struct {
+	SemesterID    uint     `json:"semester_id"`
+	Semester      string   `json:"semester"`
+	SurveyCount   int      `json:"survey_count"`
+	ResponseCount int      `json:"response_count"`
+	Average       float64  `json:"average"`
+	NPSScore      *float64 `json:"nps_score,omitempty"` // NPS questions only
+}
+
+// QuestionTrend follows a question, identified by its key, across semesters
+type QuestionTrend struct {
+	Key    string       `json:"key"`
+	Text   string       `json:"text"` // wording used in the latest semester
+	Type   string       `json:"type"`
+	Points []TrendPoint `json:"points"` // ordered by semester
+}
+
+// SubjectTrends lines up the NPS and rating questions of a subject's surveys over time
+type SubjectTrends struct {
+	Subject         Subject         `json:"subject"`
+	Semesters       []Semester      `json:"semesters"`
+	Questions       []QuestionTrend `json:"questions"`
+	WithheldSurveys int             `json:"withheld_surveys"` // embargoed or below the minimum cohort
+}
+
+// TrendOptions controls which surveys may contribute to a trend report
+type TrendOptions struct {
+	MinCohort     int
+	ApplyEmbargo  bool // professors and coordinators are subject to results embargoes, admins are not
+	EmbargoPolicy string
+	Now           time.Time
+}
+
+func isTrendQuestion(questionType string) bool {
+	return questionType == QuestionTypeNPS || questionType == QuestionTypeRating
+}
+
+// LoadSubjectTrends loads the surveys of a subject whose results may be reported and
+// builds its trend report
+func LoadSubjectTrends(db *gorm.DB, subject Subject, opts TrendOptions) (SubjectTrends, error) {
+	var surveys []Survey
+	if err := db.Preload("Semester").Preload("Questions").Where("subject_id = ?", subject.ID).Find(&surveys).Error; err != nil {
+		return SubjectTrends{}, err
+	}
+
+	surveyIDs := make([]uint, len(surveys))
+	for i, s := range surveys {
+		surveyIDs[i] = s.ID
+	}
+	cohorts, err := SurveyCohorts(db, surveyIDs, opts.MinCohort)
+	if err != nil {
+		return SubjectTrends{}, err
+	}
+
+	reportable := make([]Survey, 0, len(surveys))
+	var reportableIDs []uint
+	withheld := 0
+	for _, s := range surveys {
+		if !cohorts[s.ID].Met() || (opts.ApplyEmbargo && SurveyEmbargo(s, opts.EmbargoPolicy, opts.Now).Active) {
+			withheld++
+			continue
+		}
+		reportable = append(reportable, s)
+		reportableIDs = append(reportableIDs, s.ID)
+	}
+
+	var responses []Response
+	if len(reportableIDs) > 0 {
+		if err := db.Where("survey_id IN ?", reportableIDs).Find(&responses).Error; err != nil {
+			return SubjectTrends{}, err
+		}
+	}
+
+	trends := BuildSubjectTrends(subject, reportable, responses)
+	trends.WithheldSurveys = withheld
+	return trends, nil
+}
+
+// BuildSubjectTrends groups the answers to NPS and rating questions by question key and
+// semester. Surveys must have their Semester and Questions loaded.
+func BuildSubjectTrends(subject Subject, surveys []Survey, responses []Response) SubjectTrends {
+	semesters := make(map[uint]Semester)
+	questions := make(map[uint]Question)
+	for _, s := range surveys {
+		semesters[s.SemesterID] = s.Semester
+		for _, q := range s.Questions {
+			if isTrendQuestion(q.Type) {
+				questions[q.ID] = q
+			}
+		}
+	}
+
+	orderedSemesters := make([]Semester, 0, len(semesters))
+	for _, s := range semesters {
+		orderedSemesters = append(orderedSemesters, s)
+	}
+	sort.Slice(orderedSemesters, func(i, j int) bool {
+		a, b := orderedSemesters[i], orderedSemesters[j]
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+		return a.Period < b.Period
+	})
+	position := make(map[uint]int, len(orderedSemesters))
+	for i, s := range orderedSemesters {
+		position[s.ID] = i
+	}
+
+	// The same key with another type is a different question
+	type trendKey struct {
+		key, questionType string
+	}
+	type bucket struct {
+		answers []string
+		surveys map[uint]bool
+	}
+	buckets := make(map[trendKey]map[uint]*bucket) // by semester
+	text := make(map[trendKey]string)
+	textSemester := make(map[trendKey]int)
+	var keys []trendKey
+
+	surveySemester := make(map[uint]uint, len(surveys))
+	for _, s := range surveys {
+		surveySemester[s.ID] = s.SemesterID
+		for _, q := range s.Questions {
+			if !isTrendQuestion(q.Type) {
+				continue
+			}
+			k := trendKey{q.Key, q.Type}
+			if _, ok := buckets[k]; !ok {
+				buckets[k] = make(map[uint]*bucket)
+				keys = append(keys, k)
+				textSemester[k] = -1
+			}
+			b, ok := buckets[k][s.SemesterID]
+			if !ok {
+				b = &bucket{surveys: make(map[uint]bool)}
+				buckets[k][s.SemesterID] = b
+			}
+			b.surveys[s.ID] = true
+			if pos := position[s.SemesterID]; pos >= textSemester[k] {
+				text[k] = q.Text
+				textSemester[k] = pos
+			}
+		}
+	}
+
+	for _, r := range responses {
+		q, ok := questions[r.QuestionID]
+		if !ok {
+			continue
+		}
+		if b, ok := buckets[trendKey{q.Key, q.Type}][surveySemester[r.SurveyID]]; ok {
+			b.answers = append(b.answers, r.Answer)
+		}
+	}
+
+	trends := make([]QuestionTrend, 0, len(keys))
+	for _, k := range keys {
+		trend := QuestionTrend{Key: k.key, Text: text[k], Type: k.questionType, Points: []TrendPoint{}}
+		for _, semester := range orderedSemesters {
+			b, ok := buckets[k][semester.ID]
+			if !ok {
+				continue
+			}
+			point := TrendPoint{SemesterID: semester.ID, Semester: semester.Name, SurveyCount: len(b.surveys)}
+			if k.questionType == QuestionTypeNPS {
+				scores := parseScores(b.answers, NPSMin, NPSMax)
+				summary := summarizeNPS(scores)
+				point.ResponseCount = len(scores)
+				point.Average = summary.Average
+				if len(scores) > 0 {
+					point.NPSScore = &summary.Score
+				}
+			} else {
+				scores := parseScores(b.answers, RatingMin, RatingMax)
+				point.ResponseCount = len(scores)
+				point.Average = summarizeRating(scores).Mean
+			}
+			trend.Points = append(trend.Points, point)
+		}
+		trends = append(trends, trend)
+	}
+	// Questions asked in more semesters first, then alphabetically
+	sort.SliceStable(trends, func(i, j int) bool {
+		if len(trends[i].Points) != len(trends[j].Points) {
+			return len(trends[i].Points) > len(trends[j].Points)
+		}
+		return trends[i].Text < trends[j].Text
+	})
+
+	return SubjectTrends{Subject: subject, Semesters: orderedSemesters, Questions: trends}
+}
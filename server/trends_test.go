@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuestionKeys(t *testing.T) {
+	db := setupTestDB()
+
+	admin := User{FirstName: "Admin", LastName: "Test", Email: "admin@example.com", Password: "password123", Role: RoleAdmin}
+	db.Create(&admin)
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	semester := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&semester)
+
+	t.Run("Assigned On Create", func(t *testing.T) {
+		survey := Survey{Title: "Survey", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		db.Create(&survey)
+		q1 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Order: 1}
+		db.Create(&q1)
+		q2 := Question{SurveyID: survey.ID, Type: QuestionTypeNPS, Text: "Recommend?", Order: 2}
+		db.Create(&q2)
+		custom := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Clarity", Order: 3, Key: "clarity"}
+		db.Create(&custom)
+
+		assert.Len(t, q1.Key, 16)
+		assert.NotEqual(t, q1.Key, q2.Key)
+		assert.Equal(t, "clarity", custom.Key)
+	})
+
+	t.Run("Kept Across Templates And Clones", func(t *testing.T) {
+		template := SurveyTemplate{Name: "End of term", Questions: []TemplateQuestion{{Type: QuestionTypeNPS, Text: "Recommend?"}}}
+		assert.NoError(t, CreateTemplate(db, admin, &template))
+		key := template.Questions[0].Key
+		assert.NotEmpty(t, key)
+
+		survey := Survey{Title: "From template", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		assert.NoError(t, CreateSurveyWithQuestions(db, &survey, QuestionsFromTemplate(template)))
+		assert.Equal(t, key, survey.Questions[0].Key)
+
+		clone := Survey{Title: "Clone", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		assert.NoError(t, CreateSurveyWithQuestions(db, &clone, CloneQuestions(survey.Questions)))
+		assert.Equal(t, key, clone.Questions[0].Key)
+
+		// Editing the template keeps the key of questions sent back with their ID
+		changes := SurveyTemplate{Name: "End of term", Questions: []TemplateQuestion{
+			{ID: template.Questions[0].ID, Type: QuestionTypeNPS, Text: "Would you recommend this course?"},
+			{Type: QuestionTypeRating, Text: "Workload"},
+		}}
+		assert.NoError(t, UpdateTemplate(db, &template, changes))
+		assert.Equal(t, key, template.Questions[0].Key)
+		assert.NotEmpty(t, template.Questions[1].Key)
+		assert.NotEqual(t, key, template.Questions[1].Key)
+	})
+
+	t.Run("Legacy Backfill", func(t *testing.T) {
+		survey := Survey{Title: "Legacy", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID}
+		db.Create(&survey)
+		legacy := Question{SurveyID: survey.ID, Type: QuestionTypeRating, Text: "Teaching quality", Order: 1}
+		db.Create(&legacy)
+		db.Model(&Question{}).Where("id = ?", legacy.ID).Update("key", "")
+
+		assert.NoError(t, backfillQuestionKeys(db))
+		var stored Question
+		db.First(&stored, legacy.ID)
+		assert.Equal(t, legacyQuestionKey(QuestionTypeRating, " teaching QUALITY "), stored.Key)
+	})
+}
+
+func TestBuildSubjectTrends(t *testing.T) {
+	subject := Subject{ID: 1, Name: "Calculus", Code: "MAT101"}
+	s2023 := Semester{ID: 1, Name: "2023.2", Year: 2023, Period: 2}
+	s2024 := Semester{ID: 2, Name: "2024.1", Year: 2024, Period: 1}
+
+	older := Survey{ID: 10, SemesterID: s2023.ID, Semester: s2023, Questions: []Question{
+		{ID: 100, Type: QuestionTypeNPS, Text: "Recommend?", Key: "nps"},
+		{ID: 101, Type: QuestionTypeRating, Text: "Clarity", Key: "clarity"},
+		{ID: 102, Type: QuestionTypeFreeText, Text: "Comments", Key: "comments"},
+	}}
+	newer := Survey{ID: 20, SemesterID: s2024.ID, Semester: s2024, Questions: []Question{
+		{ID: 200, Type: QuestionTypeNPS, Text: "Would you recommend it?", Key: "nps"},
+		{ID: 201, Type: QuestionTypeRating, Text: "Workload", Key: "workload"},
+	}}
+	responses := []Response{
+		{SurveyID: 10, QuestionID: 100, Answer: "10"},
+		{SurveyID: 10, QuestionID: 100, Answer: "5"},
+		{SurveyID: 10, QuestionID: 101, Answer: "3"},
+		{SurveyID: 10, QuestionID: 102, Answer: "Great"},
+		{SurveyID: 20, QuestionID: 200, Answer: "9"},
+		{SurveyID: 20, QuestionID: 200, Answer: "10"},
+		{SurveyID: 20, QuestionID: 201, Answer: "4"},
+	}
+
+	// Surveys are given newest first to check semester ordering
+	trends := BuildSubjectTrends(subject, []Survey{newer, older}, responses)
+	assert.Equal(t, []string{"2023.2", "2024.1"}, []string{trends.Semesters[0].Name, trends.Semesters[1].Name})
+	assert.Len(t, trends.Questions, 3)
+
+	nps := trends.Questions[0]
+	assert.Equal(t, "nps", nps.Key)
+	assert.Equal(t, "Would you recommend it?", nps.Text)
+	assert.Len(t, nps.Points, 2)
+	assert.Equal(t, "2023.2", nps.Points[0].Semester)
+	assert.Equal(t, 2, nps.Points[0].ResponseCount)
+	assert.InDelta(t, 7.5, nps.Points[0].Average, 0.001)
+	assert.InDelta(t, 0, *nps.Points[0].NPSScore, 0.001)
+	assert.InDelta(t, 9.5, nps.Points[1].Average, 0.001)
+	assert.InDelta(t, 100, *nps.Points[1].NPSScore, 0.001)
+
+	// Questions asked in a single semester follow, alphabetically
+	assert.Equal(t, "Clarity", trends.Questions[1].Text)
+	assert.Len(t, trends.Questions[1].Points, 1)
+	assert.Nil(t, trends.Questions[1].Points[0].NPSScore)
+	assert.Equal(t, "Workload", trends.Questions[2].Text)
+	assert.InDelta(t, 4, trends.Questions[2].Points[0].Average, 0.001)
+}
+
+func TestLoadSubjectTrends(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+	subject := Subject{Name: "Test Subject", Code: "TEST101", ProfessorID: professor.ID}
+	db.Create(&subject)
+	past := Semester{Name: "2023.2", Year: 2023, Period: 2, StartDate: time.Now().AddDate(-1, 0, 0), EndDate: time.Now().AddDate(0, -8, 0)}
+	db.Create(&past)
+	current := Semester{Name: "2024.1", Year: 2024, Period: 1, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 4, 0)}
+	db.Create(&current)
+
+	var students []User
+	for i := 0; i < 3; i++ {
+		student := User{FirstName: "Student", LastName: fmt.Sprint(i), Email: fmt.Sprintf("s%d@example.com", i), Password: "password123", Role: RoleStudent}
+		db.Create(&student)
+		students = append(students, student)
+	}
+
+	addSurvey := func(semester Semester, closeDate time.Time, respondents int) Survey {
+		survey := Survey{Title: "Evaluation", SubjectID: subject.ID, SemesterID: semester.ID, ProfessorID: professor.ID, IsActive: true,
+			CloseDate: closeDate, ResultsEmbargo: EmbargoUntilClose}
+		questions := []Question{{Type: QuestionTypeRating, Text: "Clarity", Key: "clarity", Order: 1}}
+		assert.NoError(t, CreateSurveyWithQuestions(db, &survey, questions))
+		for _, student := range students[:respondents] {
+			db.Create(&Response{SurveyID: survey.ID, StudentID: student.ID, QuestionID: survey.Questions[0].ID, Answer: "4"})
+		}
+		return survey
+	}
+	addSurvey(past, time.Now().AddDate(0, -9, 0), 3)
+	addSurvey(current, time.Now().AddDate(0, 0, 7), 3)
+	addSurvey(current, time.Now().AddDate(0, 0, -1), 1)
+
+	t.Run("Professor", func(t *testing.T) {
+		trends, err := LoadSubjectTrends(db, subject, TrendOptions{MinCohort: 2, ApplyEmbargo: true, EmbargoPolicy: EmbargoNone, Now: time.Now()})
+		assert.NoError(t, err)
+		// The open survey is embargoed and the closed one is below the cohort
+		assert.Equal(t, 2, trends.WithheldSurveys)
+		assert.Len(t, trends.Semesters, 1)
+		assert.Len(t, trends.Questions, 1)
+		assert.Equal(t, 3, trends.Questions[0].Points[0].ResponseCount)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		trends, err := LoadSubjectTrends(db, subject, TrendOptions{MinCohort: 2, Now: time.Now()})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, trends.WithheldSurveys)
+		assert.Len(t, trends.Semesters, 2)
+		assert.Len(t, trends.Questions[0].Points, 2)
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCoordinatorUnit is returned when a coordinator assignment doesn't name
+// exactly one department or program
+var ErrInvalidCoordinatorUnit = errors.New("Assign either a department or a program")
+
+// refreshUserRoleCheck drops the check constraint on users.role so AutoMigrate recreates
+// it with the current list of roles, since GORM never alters an existing constraint
+func refreshUserRoleCheck(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&User{}) || !migrator.HasConstraint(&User{}, "chk_users_role") {
+		return nil
+	}
+	return migrator.DropConstraint(&User{}, "chk_users_role")
+}
+
+// ValidateCoordinatorAssignment checks that an assignment names exactly one unit
+func ValidateCoordinatorAssignment(assignment CoordinatorAssignment) error {
+	if (assignment.DepartmentID == 0) == (assignment.ProgramID == 0) {
+		return ErrInvalidCoordinatorUnit
+	}
+	return nil
+}
+
+// CoordinatedSubjectIDs returns the subjects in the units coordinated by the user: subjects
+// of a coordinated program, of a coordinated department, or of any program in a coordinated department
+func CoordinatedSubjectIDs(db *gorm.DB, user User) ([]uint, error) {
+	var assignments []CoordinatorAssignment
+	if err := db.Where("user_id = ?", user.ID).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	var departmentIDs, programIDs []uint
+	for _, a := range assignments {
+		if a.DepartmentID != 0 {
+			departmentIDs = append(departmentIDs, a.DepartmentID)
+		}
+		if a.ProgramID != 0 {
+			programIDs = append(programIDs, a.ProgramID)
+		}
+	}
+	if len(departmentIDs) > 0 {
+		var departmentPrograms []uint
+		if err := db.Model(&Program{}).Where("department_id IN ?", departmentIDs).Pluck("id", &departmentPrograms).Error; err != nil {
+			return nil, err
+		}
+		programIDs = append(programIDs, departmentPrograms...)
+	}
+	if len(departmentIDs) == 0 && len(programIDs) == 0 {
+		return []uint{}, nil
+	}
+
+	query := db.Model(&Subject{})
+	switch {
+	case len(departmentIDs) > 0 && len(programIDs) > 0:
+		query = query.Where("department_id IN ? OR program_id IN ?", departmentIDs, programIDs)
+	case len(departmentIDs) > 0:
+		query = query.Where("department_id IN ?", departmentIDs)
+	default:
+		query = query.Where("program_id IN ?", programIDs)
+	}
+
+	subjectIDs := []uint{}
+	if err := query.Order("code ASC").Pluck("id", &subjectIDs).Error; err != nil {
+		return nil, err
+	}
+	return subjectIDs, nil
+}
+
+// CoordinatesSubject reports whether the subject is in a unit coordinated by the user
+func CoordinatesSubject(db *gorm.DB, user User, subjectID uint) (bool, error) {
+	subjectIDs, err := CoordinatedSubjectIDs(db, user)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range subjectIDs {
+		if id == subjectID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FilterSubjectResponseRates keeps the response rates of the given subjects only
+func FilterSubjectResponseRates(rates []SubjectResponseRate, subjectIDs []uint) []SubjectResponseRate {
+	keep := make(map[uint]bool, len(subjectIDs))
+	for _, id := range subjectIDs {
+		keep[id] = true
+	}
+	filtered := make([]SubjectResponseRate, 0, len(rates))
+	for _, rate := range rates {
+		if keep[rate.Subject.ID] {
+			filtered = append(filtered, rate)
+		}
+	}
+	return filtered
+}
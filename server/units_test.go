@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCoordinatorRole(t *testing.T) {
+	t.Run("Valid Assignment", func(t *testing.T) {
+		assert.NoError(t, ValidateCoordinatorAssignment(CoordinatorAssignment{UserID: 1, DepartmentID: 1}))
+		assert.NoError(t, ValidateCoordinatorAssignment(CoordinatorAssignment{UserID: 1, ProgramID: 1}))
+		assert.ErrorIs(t, ValidateCoordinatorAssignment(CoordinatorAssignment{UserID: 1}), ErrInvalidCoordinatorUnit)
+		assert.ErrorIs(t, ValidateCoordinatorAssignment(CoordinatorAssignment{UserID: 1, DepartmentID: 1, ProgramID: 1}), ErrInvalidCoordinatorUnit)
+	})
+
+	t.Run("Role Check Refreshed On Existing Tables", func(t *testing.T) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		assert.NoError(t, err)
+		// A users table created before the coordinator role existed
+		assert.NoError(t, db.Exec(`CREATE TABLE users (id integer PRIMARY KEY AUTOINCREMENT, first_name text NOT NULL,
+			last_name text NOT NULL, email text NOT NULL, password text NOT NULL, role text NOT NULL,
+			created_at datetime, updated_at datetime,
+			CONSTRAINT chk_users_role CHECK (role IN ('student','professor','admin')))`).Error)
+
+		assert.NoError(t, refreshUserRoleCheck(db))
+		assert.NoError(t, db.AutoMigrate(&User{}))
+
+		coordinator := User{FirstName: "Coord", LastName: "Test", Email: "coord@example.com", Password: "password123", Role: RoleCoordinator}
+		assert.NoError(t, db.Create(&coordinator).Error)
+		invalid := User{FirstName: "Bad", LastName: "Role", Email: "bad@example.com", Password: "password123", Role: "dean"}
+		assert.Error(t, db.Create(&invalid).Error)
+	})
+}
+
+func TestCoordinatedSubjects(t *testing.T) {
+	db := setupTestDB()
+
+	professor := User{FirstName: "Prof", LastName: "Test", Email: "prof@example.com", Password: "password123", Role: RoleProfessor}
+	db.Create(&professor)
+
+	computing := Department{Name: "Computer Science", Code: "DCC"}
+	db.Create(&computing)
+	math := Department{Name: "Mathematics", Code: "DMAT"}
+	db.Create(&math)
+	bcc := Program{Name: "Computer Science BSc", Code: "BCC", DepartmentID: computing.ID}
+	db.Create(&bcc)
+
+	algorithms := Subject{Name: "Algorithms", Code: "MAC201", ProfessorID: professor.ID, ProgramID: bcc.ID}
+	db.Create(&algorithms)
+	compilers := Subject{Name: "Compilers", Code: "MAC411", ProfessorID: professor.ID, DepartmentID: computing.ID}
+	db.Create(&compilers)
+	calculus := Subject{Name: "Calculus", Code: "MAT101", ProfessorID: professor.ID, DepartmentID: math.ID}
+	db.Create(&calculus)
+	orphan := Subject{Name: "Seminar", Code: "SEM001", ProfessorID: professor.ID}
+	db.Create(&orphan)
+
+	newCoordinator := func(email string, assignments ...CoordinatorAssignment) User {
+		user := User{FirstName: "Coord", LastName: "Test", Email: email, Password: "password123", Role: RoleCoordinator}
+		db.Create(&user)
+		for _, a := range assignments {
+			a.UserID = user.ID
+			db.Create(&a)
+		}
+		return user
+	}
+
+	t.Run("Program Coordinator", func(t *testing.T) {
+		user := newCoordinator("program@example.com", CoordinatorAssignment{ProgramID: bcc.ID})
+		ids, err := CoordinatedSubjectIDs(db, user)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{algorithms.ID}, ids)
+	})
+
+	t.Run("Department Coordinator Includes Its Programs", func(t *testing.T) {
+		user := newCoordinator("department@example.com", CoordinatorAssignment{DepartmentID: computing.ID})
+		ids, err := CoordinatedSubjectIDs(db, user)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{algorithms.ID, compilers.ID}, ids)
+
+		ok, err := CoordinatesSubject(db, user, compilers.ID)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, _ = CoordinatesSubject(db, user, calculus.ID)
+		assert.False(t, ok)
+		ok, _ = CoordinatesSubject(db, user, orphan.ID)
+		assert.False(t, ok)
+	})
+
+	t.Run("Several Units", func(t *testing.T) {
+		user := newCoordinator("both@example.com", CoordinatorAssignment{DepartmentID: math.ID}, CoordinatorAssignment{ProgramID: bcc.ID})
+		ids, err := CoordinatedSubjectIDs(db, user)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{algorithms.ID, calculus.ID}, ids)
+	})
+
+	t.Run("No Assignments", func(t *testing.T) {
+		user := newCoordinator("none@example.com")
+		ids, err := CoordinatedSubjectIDs(db, user)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("Filter Response Rates", func(t *testing.T) {
+		rates := []SubjectResponseRate{{Subject: algorithms}, {Subject: compilers}, {Subject: calculus}}
+		filtered := FilterSubjectResponseRates(rates, []uint{calculus.ID, algorithms.ID})
+		assert.Len(t, filtered, 2)
+		assert.Equal(t, algorithms.ID, filtered[0].Subject.ID)
+		assert.Equal(t, calculus.ID, filtered[1].Subject.ID)
+		assert.Empty(t, FilterSubjectResponseRates(rates, nil))
+	})
+}
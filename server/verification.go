@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// ErrCodeEmailUnverified is returned when a self-registered user hasn't confirmed their email yet
+const ErrCodeEmailUnverified = "email_unverified"
+
+// ErrCodeEmailDomainNotAllowed is returned when registering with an email outside the allowed domains
+const ErrCodeEmailDomainNotAllowed = "email_domain_not_allowed"
+
+var (
+	// ErrEmailUnverified blocks login and API access until the email is confirmed
+	ErrEmailUnverified = errors.New("Email address is not verified")
+	// ErrEmailDomainNotAllowed is returned for emails outside every allowed domain
+	ErrEmailDomainNotAllowed = errors.New("Email domain is not allowed")
+	// ErrInvalidVerificationToken is returned for unknown, used or expired verification tokens
+	ErrInvalidVerificationToken = errors.New("Invalid or expired verification link")
+	// ErrInvalidDomain is returned when adding an allowed domain that isn't a domain name
+	ErrInvalidDomain = errors.New("Invalid domain")
+)
+
+// normalizeDomain lower-cases a domain and drops a leading "@" or "." so "@USP.br" and "usp.br" match
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimLeft(domain, "@.")
+	return strings.TrimSuffix(domain, ".")
+}
+
+// emailDomain returns the normalized domain of an email, or "" when it has none
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return normalizeDomain(email[at+1:])
+}
+
+// domainMatches reports whether domain is allowed or one of its subdomains, so "usp.br"
+// also allows "ime.usp.br"
+func domainMatches(domain, allowed string) bool {
+	return domain == allowed || strings.HasSuffix(domain, "."+allowed)
+}
+
+// CheckEmailDomain returns ErrEmailDomainNotAllowed unless the email belongs to an allowed
+// domain. Registration is open to every domain while no domain is configured.
+func CheckEmailDomain(db *gorm.DB, email string) error {
+	var allowed []AllowedDomain
+	if err := db.Find(&allowed).Error; err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	domain := emailDomain(email)
+	if domain == "" {
+		return ErrEmailDomainNotAllowed
+	}
+	for _, a := range allowed {
+		if domainMatches(domain, a.Domain) {
+			return nil
+		}
+	}
+	return ErrEmailDomainNotAllowed
+}
+
+// AddAllowedDomain restricts registration to the given domain (and the others already allowed)
+func AddAllowedDomain(db *gorm.DB, domain string) (AllowedDomain, error) {
+	allowed := AllowedDomain{Domain: normalizeDomain(domain)}
+	if allowed.Domain == "" || !strings.Contains(allowed.Domain, ".") || strings.ContainsAny(allowed.Domain, "@/ ") {
+		return allowed, ErrInvalidDomain
+	}
+	err := db.Create(&allowed).Error
+	return allowed, err
+}
+
+// getEmailVerificationURL returns the client page verification links point to,
+// EMAIL_VERIFICATION_URL or the verification page of the CORS origin
+func getEmailVerificationURL() string {
+	return getClientURL("EMAIL_VERIFICATION_URL", "/verify-email")
+}
+
+// StartEmailVerification emails an unverified user a link that confirms their email
+func StartEmailVerification(db *gorm.DB, mailer Mailer, user *User, verifyURL string, now time.Time) error {
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	verification := EmailVerification{UserID: user.ID, TokenHash: hashSecretToken(token), ExpiresAt: now.Add(emailVerificationTTL)}
+	if err := db.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	link, err := url.Parse(verifyURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Send(Mail{
+		To:      user.Email,
+		Subject: "Confirme o seu email",
+		Body: fmt.Sprintf("Olá, %s.\n\nPara ativar a sua conta, confirme o seu email acessando:\n\n%s\n\n"+
+			"O link vale por %d horas. Se você não criou uma conta, ignore este email.\n",
+			user.FirstName, link.String(), int(emailVerificationTTL.Hours())),
+	})
+}
+
+// ResendEmailVerification emails a new verification link to the unverified user with the
+// given email. Other emails are skipped without an error, so the response doesn't tell who
+// has an account.
+func ResendEmailVerification(db *gorm.DB, mailer Mailer, email, verifyURL string, now time.Time) error {
+	var user User
+	err := db.Where("LOWER(email) = ?", normalizeEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.EmailUnverified || user.IsDisabled() {
+		return nil
+	}
+	return StartEmailVerification(db, mailer, &user, verifyURL, now)
+}
+
+// VerifyEmail confirms the email of the user a verification token was sent to. The token
+// can't be used again.
+func VerifyEmail(db *gorm.DB, token string, now time.Time) (User, error) {
+	var user User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var verification EmailVerification
+		if err := tx.Where("token_hash = ?", hashSecretToken(token)).First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+		if verification.UsedAt != nil || !now.Before(verification.ExpiresAt) {
+			return ErrInvalidVerificationToken
+		}
+
+		// Only one request can use the token
+		result := tx.Model(&EmailVerification{}).Where("id = ? AND used_at IS NULL", verification.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidVerificationToken
+		}
+
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+		if user.IsDisabled() {
+			return ErrInvalidVerificationToken
+		}
+		// Domains may have been restricted since the user registered
+		if err := CheckEmailDomain(tx, user.Email); err != nil {
+			return err
+		}
+		return MarkEmailVerified(tx, &user)
+	})
+	return user, err
+}
+
+// MarkEmailVerified lifts the unverified state, e.g. when an admin vouches for a user
+func MarkEmailVerified(db *gorm.DB, user *User) error {
+	if err := db.Model(user).Update("email_unverified", false).Error; err != nil {
+		return err
+	}
+	user.EmailUnverified = false
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification(t *testing.T) {
+	db := setupTestDB()
+	now := time.Now()
+
+	var outbox bytes.Buffer
+	mailer := &WriterMailer{From: "no-reply@example.com", out: &outbox}
+	// emailedToken returns the token from the last emailed verification link
+	emailedToken := func(t *testing.T) string {
+		link := regexp.MustCompile(`http://\S+`).FindString(outbox.String())
+		parsed, err := url.Parse(link)
+		assert.NoError(t, err)
+		return parsed.Query().Get("token")
+	}
+	register := func(email string) User {
+		user := User{FirstName: "Ana", LastName: "Souza", Email: email, Password: "password123", Role: RoleStudent, EmailUnverified: true}
+		db.Create(&user)
+		return user
+	}
+
+	t.Run("Any Domain Without Allowed Domains", func(t *testing.T) {
+		assert.NoError(t, CheckEmailDomain(db, "ana@gmail.com"))
+	})
+
+	t.Run("Allowed Domains", func(t *testing.T) {
+		domain, err := AddAllowedDomain(db, " @USP.br ")
+		assert.NoError(t, err)
+		assert.Equal(t, "usp.br", domain.Domain)
+
+		assert.NoError(t, CheckEmailDomain(db, "ana@usp.br"))
+		assert.NoError(t, CheckEmailDomain(db, "ana@ime.usp.br"))
+		assert.ErrorIs(t, CheckEmailDomain(db, "ana@gmail.com"), ErrEmailDomainNotAllowed)
+		assert.ErrorIs(t, CheckEmailDomain(db, "ana@fakeusp.br"), ErrEmailDomainNotAllowed)
+		assert.ErrorIs(t, CheckEmailDomain(db, "ana"), ErrEmailDomainNotAllowed)
+	})
+
+	t.Run("Invalid Domains", func(t *testing.T) {
+		for _, domain := range []string{"", "localhost", "ana@usp.br", "usp.br/x"} {
+			_, err := AddAllowedDomain(db, domain)
+			assert.ErrorIs(t, err, ErrInvalidDomain, domain)
+		}
+		_, err := AddAllowedDomain(db, "usp.br")
+		assert.True(t, isUniqueViolation(err))
+	})
+
+	t.Run("Verify Email", func(t *testing.T) {
+		user := register("bruno@usp.br")
+		outbox.Reset()
+		assert.NoError(t, StartEmailVerification(db, mailer, &user, "http://localhost:5173/verify-email", now))
+		assert.Contains(t, outbox.String(), "To: bruno@usp.br")
+		token := emailedToken(t)
+
+		verified, err := VerifyEmail(db, token, now)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, verified.ID)
+		assert.False(t, verified.EmailUnverified)
+
+		var loaded User
+		db.First(&loaded, user.ID)
+		assert.False(t, loaded.EmailUnverified)
+		// The token can't be used twice
+		_, err = VerifyEmail(db, token, now)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("Invalid Verification Tokens", func(t *testing.T) {
+		_, err := VerifyEmail(db, "unknown", now)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+
+		user := register("carla@usp.br")
+		outbox.Reset()
+		assert.NoError(t, StartEmailVerification(db, mailer, &user, "http://localhost:5173/verify-email", now))
+		_, err = VerifyEmail(db, emailedToken(t), now.Add(emailVerificationTTL))
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("Resend Only To Unverified Users", func(t *testing.T) {
+		register("diego@usp.br")
+		outbox.Reset()
+		assert.NoError(t, ResendEmailVerification(db, mailer, " DIEGO@usp.br", "http://localhost:5173/verify-email", now))
+		assert.Contains(t, outbox.String(), "To: diego@usp.br")
+
+		outbox.Reset()
+		assert.NoError(t, ResendEmailVerification(db, mailer, "bruno@usp.br", "http://localhost:5173/verify-email", now))
+		assert.NoError(t, ResendEmailVerification(db, mailer, "nobody@usp.br", "http://localhost:5173/verify-email", now))
+		assert.Empty(t, outbox.String())
+	})
+
+	t.Run("Domains Restricted After Registration", func(t *testing.T) {
+		user := register("eva@example.com")
+		outbox.Reset()
+		assert.NoError(t, StartEmailVerification(db, mailer, &user, "http://localhost:5173/verify-email", now))
+		_, err := VerifyEmail(db, emailedToken(t), now)
+		assert.ErrorIs(t, err, ErrEmailDomainNotAllowed)
+	})
+
+	t.Run("Admin Verification", func(t *testing.T) {
+		user := register("fabio@example.com")
+		assert.NoError(t, MarkEmailVerified(db, &user))
+
+		var loaded User
+		db.First(&loaded, user.ID)
+		assert.False(t, loaded.EmailUnverified)
+	})
+}